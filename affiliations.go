@@ -0,0 +1,268 @@
+package devstats
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AffData - holds single affiliation data: GitHub login works for company in [From, To) date range
+type AffData struct {
+	Login   string
+	Company string
+	From    time.Time
+	To      time.Time
+}
+
+// AffsDiff - affiliations added and removed since the previous import
+type AffsDiff struct {
+	Added   []AffData
+	Removed []AffData
+}
+
+// AffsCompanyChange - number of affiliations added and removed for a single company
+type AffsCompanyChange struct {
+	Company string
+	Added   int
+	Removed int
+}
+
+// AffsDiffSummary - summary of affiliations changes
+// NewLogins - logins that only have affiliations added (new in the source file)
+// GoneLogins - logins that only have affiliations removed (no longer in the source file)
+// ChangedLogins - logins that have affiliations both added and removed
+// Companies - per company changes, sorted by the number of changes descending
+type AffsDiffSummary struct {
+	Added         int
+	Removed       int
+	NewLogins     int
+	GoneLogins    int
+	ChangedLogins int
+	Companies     []AffsCompanyChange
+}
+
+// Affiliation date ranges conflict kinds
+const (
+	AffsConflictZeroLength = "zero-length"
+	AffsConflictInverted   = "inverted"
+	AffsConflictOutOfOrder = "out-of-order"
+	AffsConflictOverlap    = "overlap"
+)
+
+// AffsConflict - problem with affiliation(s) date ranges of a single login
+// Other is the second affiliation for out-of-order and overlap conflicts
+type AffsConflict struct {
+	Kind  string
+	Aff   AffData
+	Other *AffData
+}
+
+// affKey - returns key uniquely identifying affiliation
+func affKey(aff *AffData) string {
+	return aff.Login + "\t" + aff.Company + "\t" + ToYMDHMSDate(aff.From) + "\t" + ToYMDHMSDate(aff.To)
+}
+
+// DiffAffiliations - returns affiliations present in curr but not in prev (added) and present in prev but not in curr (removed)
+// Results are sorted by login, from date and company
+func DiffAffiliations(prev, curr []AffData) (diff AffsDiff) {
+	prevM := make(map[string]struct{})
+	for i := range prev {
+		prevM[affKey(&prev[i])] = struct{}{}
+	}
+	currM := make(map[string]struct{})
+	for i := range curr {
+		key := affKey(&curr[i])
+		_, dup := currM[key]
+		if dup {
+			continue
+		}
+		currM[key] = struct{}{}
+		_, ok := prevM[key]
+		if !ok {
+			diff.Added = append(diff.Added, curr[i])
+		}
+	}
+	for i := range prev {
+		key := affKey(&prev[i])
+		_, ok := currM[key]
+		if !ok {
+			diff.Removed = append(diff.Removed, prev[i])
+			// Protect from duplicates on the prev list
+			currM[key] = struct{}{}
+		}
+	}
+	SortAffiliations(diff.Added)
+	SortAffiliations(diff.Removed)
+	return
+}
+
+// SortAffiliations - sorts affiliations by login, from date and company
+func SortAffiliations(affs []AffData) {
+	sort.Slice(affs, func(i, j int) bool {
+		if affs[i].Login != affs[j].Login {
+			return affs[i].Login < affs[j].Login
+		}
+		if !affs[i].From.Equal(affs[j].From) {
+			return affs[i].From.Before(affs[j].From)
+		}
+		return affs[i].Company < affs[j].Company
+	})
+}
+
+// Summary - returns affiliations changes summary
+func (diff *AffsDiff) Summary() (summary AffsDiffSummary) {
+	summary.Added = len(diff.Added)
+	summary.Removed = len(diff.Removed)
+	added := make(map[string]struct{})
+	removed := make(map[string]struct{})
+	companies := make(map[string]*AffsCompanyChange)
+	company := func(name string) *AffsCompanyChange {
+		change, ok := companies[name]
+		if !ok {
+			change = &AffsCompanyChange{Company: name}
+			companies[name] = change
+		}
+		return change
+	}
+	for _, aff := range diff.Added {
+		added[aff.Login] = struct{}{}
+		company(aff.Company).Added++
+	}
+	for _, aff := range diff.Removed {
+		removed[aff.Login] = struct{}{}
+		company(aff.Company).Removed++
+	}
+	for login := range added {
+		_, ok := removed[login]
+		if ok {
+			summary.ChangedLogins++
+		} else {
+			summary.NewLogins++
+		}
+	}
+	summary.GoneLogins = len(removed) - summary.ChangedLogins
+	for _, change := range companies {
+		summary.Companies = append(summary.Companies, *change)
+	}
+	sort.Slice(summary.Companies, func(i, j int) bool {
+		ci := summary.Companies[i].Added + summary.Companies[i].Removed
+		cj := summary.Companies[j].Added + summary.Companies[j].Removed
+		if ci != cj {
+			return ci > cj
+		}
+		return summary.Companies[i].Company < summary.Companies[j].Company
+	})
+	return
+}
+
+// Report - returns human readable affiliations changes summary
+func (summary *AffsDiffSummary) Report() string {
+	lines := []string{
+		fmt.Sprintf("Affiliations added: %d, removed: %d", summary.Added, summary.Removed),
+		fmt.Sprintf(
+			"Logins new: %d, gone: %d, changed: %d",
+			summary.NewLogins, summary.GoneLogins, summary.ChangedLogins,
+		),
+	}
+	if len(summary.Companies) > 0 {
+		lines = append(lines, "Company changes (company: +added -removed):")
+	}
+	for _, change := range summary.Companies {
+		lines = append(lines, fmt.Sprintf("%s: +%d -%d", change.Company, change.Added, change.Removed))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// AffsSourceHash - returns hash of affiliations source file contents, used to identify import in the affiliations history
+func AffsSourceHash(data []byte) string {
+	hash := sha1.New()
+	_, err := hash.Write(data)
+	FatalOnError(err)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// CheckAffiliations - returns date ranges conflicts in affiliations, affiliations are checked per login in the given order
+// zero-length: from = to, inverted: from > to, out-of-order: range starts before the previous valid range of that login
+// overlap: two different ranges of that login overlap, identical duplicates are not reported
+// Results are sorted by login, order within a login is kept
+func CheckAffiliations(affs []AffData) (conflicts []AffsConflict) {
+	logins := []string{}
+	loginAffs := make(map[string][]AffData)
+	for _, aff := range affs {
+		_, ok := loginAffs[aff.Login]
+		if !ok {
+			logins = append(logins, aff.Login)
+		}
+		loginAffs[aff.Login] = append(loginAffs[aff.Login], aff)
+	}
+	sort.Strings(logins)
+	for _, login := range logins {
+		list := loginAffs[login]
+		var valid []AffData
+		for _, aff := range list {
+			if aff.From.Equal(aff.To) {
+				conflicts = append(conflicts, AffsConflict{Kind: AffsConflictZeroLength, Aff: aff})
+				continue
+			}
+			if aff.From.After(aff.To) {
+				conflicts = append(conflicts, AffsConflict{Kind: AffsConflictInverted, Aff: aff})
+				continue
+			}
+			// Compare with the previous valid affiliation, invalid ones are already reported
+			if len(valid) > 0 && aff.From.Before(valid[len(valid)-1].From) {
+				prev := valid[len(valid)-1]
+				conflicts = append(conflicts, AffsConflict{Kind: AffsConflictOutOfOrder, Aff: aff, Other: &prev})
+			}
+			valid = append(valid, aff)
+		}
+		SortAffiliations(valid)
+		for i := range valid {
+			for j := i + 1; j < len(valid) && valid[j].From.Before(valid[i].To); j++ {
+				if affKey(&valid[i]) == affKey(&valid[j]) {
+					continue
+				}
+				other := valid[i]
+				conflicts = append(conflicts, AffsConflict{Kind: AffsConflictOverlap, Aff: valid[j], Other: &other})
+			}
+		}
+	}
+	return
+}
+
+// String - returns human readable affiliation conflict
+func (conflict *AffsConflict) String() string {
+	aff := &conflict.Aff
+	str := fmt.Sprintf(
+		"%s: %s: %s [%s - %s]",
+		aff.Login, conflict.Kind, aff.Company, ToYMDDate(aff.From), ToYMDDate(aff.To),
+	)
+	if conflict.Other != nil {
+		other := conflict.Other
+		str += fmt.Sprintf(" and %s [%s - %s]", other.Company, ToYMDDate(other.From), ToYMDDate(other.To))
+	}
+	return str
+}
+
+// AffsConflictsReport - returns human readable affiliations conflicts report: one conflict per line and a summary
+func AffsConflictsReport(conflicts []AffsConflict) string {
+	lines := []string{}
+	kinds := make(map[string]int)
+	logins := make(map[string]struct{})
+	for i := range conflicts {
+		lines = append(lines, conflicts[i].String())
+		kinds[conflicts[i].Kind]++
+		logins[conflicts[i].Aff.Login] = struct{}{}
+	}
+	lines = append(
+		lines,
+		fmt.Sprintf(
+			"Conflicts: %d, logins: %d, overlap: %d, zero-length: %d, inverted: %d, out-of-order: %d",
+			len(conflicts), len(logins), kinds[AffsConflictOverlap], kinds[AffsConflictZeroLength],
+			kinds[AffsConflictInverted], kinds[AffsConflictOutOfOrder],
+		),
+	)
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,188 @@
+package devstats
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// Affiliation sources, stored in gha_actors_affiliations.source
+const (
+	AffsSourceFile        = "file"
+	AffsSourceEmailDomain = "email_domain"
+)
+
+// AffsSourcesSQL - returns list of affiliation sources metrics should use, to replace {{affs_sources}} in SQLs
+// Inferred affiliations are only used when a metric explicitly asks for them
+func AffsSourcesSQL(inferred bool) string {
+	if inferred {
+		return "'" + AffsSourceFile + "', '" + AffsSourceEmailDomain + "'"
+	}
+	return "'" + AffsSourceFile + "'"
+}
+
+// EmailDomain - returns lowercase email domain, emails can use ! instead of @ (gitdm convention)
+// Returns empty string for invalid emails and GitHub noreply emails
+func EmailDomain(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	i := strings.LastIndexAny(email, "@!")
+	if i < 1 || i == len(email)-1 {
+		return ""
+	}
+	domain := email[i+1:]
+	if !strings.Contains(domain, ".") || domain == "users.noreply.github.com" {
+		return ""
+	}
+	return domain
+}
+
+// DomainsMap - returns map from email domain to canonical company name
+func (ca *CompanyAliases) DomainsMap() map[string]string {
+	domains := make(map[string]string)
+	for _, company := range ca.Companies {
+		for _, domain := range company.Domains {
+			domains[strings.ToLower(domain)] = company.Name
+		}
+	}
+	return domains
+}
+
+// DomainCompany - returns company for email domain, subdomains are also matched: "corp.google.com" matches "google.com"
+func DomainCompany(domains map[string]string, domain string) (string, bool) {
+	for domain != "" {
+		company, ok := domains[domain]
+		if ok {
+			return company, true
+		}
+		i := strings.Index(domain, ".")
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+	return "", false
+}
+
+// InferCompany - infers company from emails usage counts (email -> number of commits or 1 for known emails)
+// Returns company with the most email usages in its domain(s) and confidence: its fraction of all emails usages
+func InferCompany(domains map[string]string, emails map[string]int) (company string, confidence float64) {
+	all := 0
+	companies := make(map[string]int)
+	for email, n := range emails {
+		domain := EmailDomain(email)
+		if domain == "" {
+			continue
+		}
+		all += n
+		comp, ok := DomainCompany(domains, domain)
+		if ok {
+			companies[comp] += n
+		}
+	}
+	max := 0
+	for comp, n := range companies {
+		if n > max || (n == max && comp < company) {
+			company = comp
+			max = n
+		}
+	}
+	if company != "" {
+		confidence = float64(max) / float64(all)
+	}
+	return
+}
+
+// TrustedCommitEmails - returns commit emails that can be attributed to the pusher
+// commitEmails is pusher actor ID -> commit author email -> number of commits pushed
+// gha_commits.dup_actor_id is the push actor, not the commit author: maintainers and merge robots push commits
+// authored by others, so the same safeguards as for identities are used, see IdentityCommitLinks
+func TrustedCommitEmails(commitEmails map[int64]map[string]int) map[int64]map[string]int {
+	byKey := make(map[string]map[string]int)
+	for aid, emails := range commitEmails {
+		byKey[strconv.FormatInt(aid, 10)] = emails
+	}
+	trusted := make(map[int64]map[string]int)
+	for _, link := range IdentityCommitLinks(byKey) {
+		aid, err := strconv.ParseInt(link[0], 10, 64)
+		FatalOnError(err)
+		_, ok := trusted[aid]
+		if !ok {
+			trusted[aid] = make(map[string]int)
+		}
+		trusted[aid][link[1]] = commitEmails[aid][link[1]]
+	}
+	return trusted
+}
+
+// actorsEmailsCounts - returns actor ID -> email -> count from a query returning actor_id, email, count rows
+func actorsEmailsCounts(con *sql.DB, ctx *Ctx, query string) map[int64]map[string]int {
+	rows := QuerySQLWithErr(con, ctx, query)
+	defer func() { FatalOnError(rows.Close()) }()
+	actorsEmails := make(map[int64]map[string]int)
+	var (
+		aid   int64
+		email string
+		n     int
+	)
+	for rows.Next() {
+		FatalOnError(rows.Scan(&aid, &email, &n))
+		_, ok := actorsEmails[aid]
+		if !ok {
+			actorsEmails[aid] = make(map[string]int)
+		}
+		actorsEmails[aid][email] += n
+	}
+	FatalOnError(rows.Err())
+	return actorsEmails
+}
+
+// InferAffiliations - infers affiliations for actors without affiliations from files using email domains
+// Emails come from gha_actors_emails and gha_commits.author_email (number of commits), commit emails are only used
+// when they can be attributed to the pusher (see TrustedCommitEmails)
+// All previously inferred affiliations are replaced, returns number of inferred affiliations
+func InferAffiliations(con *sql.DB, ctx *Ctx, domains map[string]string, maybeHide func(string) string) (inferred int) {
+	ExecSQLWithErr(con, ctx, "delete from gha_actors_affiliations where source = "+NValue(1), AffsSourceEmailDomain)
+	if len(domains) == 0 {
+		return
+	}
+	actorsEmails := actorsEmailsCounts(
+		con,
+		ctx,
+		"select actor_id, email, 1 from gha_actors_emails "+
+			"where actor_id not in (select actor_id from gha_actors_affiliations)",
+	)
+	commitEmails := actorsEmailsCounts(
+		con,
+		ctx,
+		"select dup_actor_id, author_email, count(*) from gha_commits where author_email != '' "+
+			"and dup_actor_id not in (select actor_id from gha_actors_affiliations) group by dup_actor_id, author_email",
+	)
+	for aid, emails := range TrustedCommitEmails(commitEmails) {
+		_, ok := actorsEmails[aid]
+		if !ok {
+			actorsEmails[aid] = make(map[string]int)
+		}
+		for email, n := range emails {
+			actorsEmails[aid][email] += n
+		}
+	}
+	for aid, emails := range actorsEmails {
+		company, confidence := InferCompany(domains, emails)
+		if company == "" {
+			continue
+		}
+		hcompany := maybeHide(company)
+		ExecSQLWithErr(con, ctx, InsertIgnore("into gha_companies(name) "+NValues(1)), hcompany)
+		ExecSQLWithErr(
+			con,
+			ctx,
+			InsertIgnore("into gha_actors_affiliations(actor_id, company_name, dt_from, dt_to, source, confidence) "+NValues(6)),
+			AnyArray{aid, hcompany, AffsDefaultFrom, AffsDefaultTo, AffsSourceEmailDomain, confidence}...,
+		)
+		if ctx.Debug > 0 {
+			Printf("Inferred: actor %d: %s (%.2f)\n", aid, company, confidence)
+		}
+		inferred++
+	}
+	return
+}
@@ -0,0 +1,322 @@
+package devstats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Default affiliation date range, used when affiliation has no start or end date
+var (
+	AffsDefaultFrom = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	AffsDefaultTo   = time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// AffsUser - single developer defined in affiliations source: GitHub login, name, emails and affiliations
+// Emails can use ! instead of @ (gitdm convention)
+type AffsUser struct {
+	Login        string
+	Name         string
+	Emails       []string
+	Affiliations []AffData
+}
+
+// AffsSource - affiliations source format, returns developers defined in a given source file contents
+// The same login can be returned multiple times (for example with different emails)
+type AffsSource interface {
+	Users(data []byte) ([]AffsUser, error)
+}
+
+// AffsSources - supported affiliations sources by file extension
+var AffsSources = map[string]AffsSource{
+	".json": GitHubUsersAffsSource{},
+	".txt":  GitdmAffsSource{},
+	".csv":  CSVAffsSource{},
+	".yaml": YAMLAffsSource{},
+	".yml":  YAMLAffsSource{},
+}
+
+// GetAffsSource - returns affiliations source for a given file name (based on its extension)
+func GetAffsSource(fileName string) (AffsSource, error) {
+	source, ok := AffsSources[strings.ToLower(filepath.Ext(fileName))]
+	if !ok {
+		return nil, fmt.Errorf("unsupported affiliations file format: %s", fileName)
+	}
+	return source, nil
+}
+
+// affsChain - creates affiliations from a list of companies with optional end dates
+// Each company starts when the previous one ends, company without end date lasts forever
+func affsChain(login string, companies, untils []string) ([]AffData, error) {
+	var affs []AffData
+	prevDate := AffsDefaultFrom
+	for i, company := range companies {
+		dtTo := AffsDefaultTo
+		if untils[i] != "" {
+			dt, err := TimeParseAnyWithErr(untils[i])
+			if err != nil {
+				return nil, fmt.Errorf("login %s, company %s: %v", login, company, err)
+			}
+			dtTo = dt
+		}
+		affs = append(affs, AffData{Login: login, Company: company, From: prevDate, To: dtTo})
+		prevDate = dtTo
+	}
+	return affs, nil
+}
+
+// ParseAffiliations - parses cncf/gitdm affiliation string: "com1 < dt1, com2 < dt2, ..., com(N-1) < dt(N-1), comN"
+// Returns no affiliations for "NotFound", "(Unknown)" and "?" values
+func ParseAffiliations(login, aff string) ([]AffData, error) {
+	if aff == "" || aff == "NotFound" || aff == "(Unknown)" || aff == "?" {
+		return nil, nil
+	}
+	var companies, untils []string
+	for _, item := range strings.Split(aff, ", ") {
+		// "company name" or "company name < date"
+		ary := strings.Split(item, " < ")
+		until := ""
+		if len(ary) > 1 {
+			until = strings.TrimSpace(ary[1])
+		}
+		companies = append(companies, strings.TrimSpace(ary[0]))
+		untils = append(untils, until)
+	}
+	return affsChain(login, companies, untils)
+}
+
+// GitHubUsersAffsSource - cncf/gitdm `github_users.json` format
+type GitHubUsersAffsSource struct{}
+
+// gitHubUser - single GitHub user entry from cncf/gitdm `github_users.json` JSON.
+type gitHubUser struct {
+	Login       string `json:"login"`
+	Email       string `json:"email"`
+	Affiliation string `json:"affiliation"`
+	Name        string `json:"name"`
+}
+
+// Users - returns developers defined in `github_users.json`
+func (GitHubUsersAffsSource) Users(data []byte) ([]AffsUser, error) {
+	var ghUsers []gitHubUser
+	err := json.Unmarshal(data, &ghUsers)
+	if err != nil {
+		return nil, err
+	}
+	users := []AffsUser{}
+	for _, ghUser := range ghUsers {
+		affs, err := ParseAffiliations(ghUser.Login, ghUser.Affiliation)
+		if err != nil {
+			return nil, err
+		}
+		user := AffsUser{Login: ghUser.Login, Name: ghUser.Name, Affiliations: affs}
+		if ghUser.Email != "" {
+			user.Emails = []string{ghUser.Email}
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// GitdmAffsSource - cncf/gitdm `developers_affiliations*.txt` format:
+// login: email1!domain1, email2!domain2
+// <tab>Company1 until YYYY-MM-DD
+// <tab>Company2
+type GitdmAffsSource struct{}
+
+// Users - returns developers defined in `developers_affiliations*.txt`
+func (GitdmAffsSource) Users(data []byte) ([]AffsUser, error) {
+	users := []AffsUser{}
+	var (
+		user              *AffsUser
+		companies, untils []string
+	)
+	flush := func() error {
+		if user == nil {
+			return nil
+		}
+		affs, err := affsChain(user.Login, companies, untils)
+		if err != nil {
+			return err
+		}
+		user.Affiliations = affs
+		users = append(users, *user)
+		user, companies, untils = nil, nil, nil
+		return nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			// Company line: "Company" or "Company until YYYY-MM-DD"
+			if user == nil {
+				return nil, fmt.Errorf("line %d: company without developer: %s", lineNo, trimmed)
+			}
+			ary := strings.Split(trimmed, " until ")
+			until := ""
+			if len(ary) > 1 {
+				until = strings.TrimSpace(ary[1])
+			}
+			companies = append(companies, strings.TrimSpace(ary[0]))
+			untils = append(untils, until)
+			continue
+		}
+		// Developer line: "login: emails"
+		err := flush()
+		if err != nil {
+			return nil, err
+		}
+		ary := strings.SplitN(trimmed, ":", 2)
+		login := strings.TrimSpace(ary[0])
+		if len(ary) < 2 || login == "" {
+			return nil, fmt.Errorf("line %d: expected 'login: emails', got: %s", lineNo, trimmed)
+		}
+		user = &AffsUser{Login: login}
+		for _, email := range strings.Split(ary[1], ",") {
+			email = strings.TrimSpace(email)
+			if email != "" {
+				user.Emails = append(user.Emails, email)
+			}
+		}
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	err = flush()
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// CSVAffsSource - CSV format: login, company, from, to (from and to are optional)
+// Header row (starting with "login") is optional, lines starting with # are comments
+type CSVAffsSource struct{}
+
+// Users - returns developers defined in CSV
+func (CSVAffsSource) Users(data []byte) ([]AffsUser, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	users := []AffsUser{}
+	index := make(map[string]int)
+	lineNo := 0
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		lineNo++
+		if lineNo == 1 && len(row) > 0 && strings.ToLower(strings.TrimSpace(row[0])) == "login" {
+			continue
+		}
+		if len(row) < 2 || len(row) > 4 {
+			return nil, fmt.Errorf("row %d: expected login, company, from, to: %v", lineNo, row)
+		}
+		aff := AffData{
+			Login:   strings.TrimSpace(row[0]),
+			Company: strings.TrimSpace(row[1]),
+			From:    AffsDefaultFrom,
+			To:      AffsDefaultTo,
+		}
+		if aff.Login == "" || aff.Company == "" {
+			return nil, fmt.Errorf("row %d: login and company are required: %v", lineNo, row)
+		}
+		for i, dt := range []*time.Time{&aff.From, &aff.To} {
+			if len(row) < i+3 || strings.TrimSpace(row[i+2]) == "" {
+				continue
+			}
+			*dt, err = TimeParseAnyWithErr(strings.TrimSpace(row[i+2]))
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %v", lineNo, err)
+			}
+		}
+		i, ok := index[aff.Login]
+		if !ok {
+			i = len(users)
+			index[aff.Login] = i
+			users = append(users, AffsUser{Login: aff.Login})
+		}
+		users[i].Affiliations = append(users[i].Affiliations, aff)
+	}
+	return users, nil
+}
+
+// YAMLAffsSource - YAML format, see AffsYAML
+type YAMLAffsSource struct{}
+
+// AffsYAML - YAML affiliations file
+type AffsYAML struct {
+	Users []AffsYAMLUser `yaml:"users"`
+}
+
+// AffsYAMLUser - single developer in YAML affiliations file
+type AffsYAMLUser struct {
+	Login        string                `yaml:"login"`
+	Name         string                `yaml:"name"`
+	Emails       []string              `yaml:"emails"`
+	Affiliations []AffsYAMLAffiliation `yaml:"affiliations"`
+}
+
+// AffsYAMLAffiliation - single affiliation in YAML affiliations file, from and to are optional
+type AffsYAMLAffiliation struct {
+	Company string `yaml:"company"`
+	From    string `yaml:"from"`
+	To      string `yaml:"to"`
+}
+
+// Users - returns developers defined in YAML
+func (YAMLAffsSource) Users(data []byte) ([]AffsUser, error) {
+	var affsYAML AffsYAML
+	err := yaml.Unmarshal(data, &affsYAML)
+	if err != nil {
+		return nil, err
+	}
+	users := []AffsUser{}
+	for _, yUser := range affsYAML.Users {
+		if yUser.Login == "" {
+			return nil, fmt.Errorf("user without login: %+v", yUser)
+		}
+		user := AffsUser{Login: yUser.Login, Name: yUser.Name, Emails: yUser.Emails}
+		for _, yAff := range yUser.Affiliations {
+			if yAff.Company == "" {
+				return nil, fmt.Errorf("login %s: affiliation without company: %+v", yUser.Login, yAff)
+			}
+			aff := AffData{Login: yUser.Login, Company: yAff.Company, From: AffsDefaultFrom, To: AffsDefaultTo}
+			if yAff.From != "" {
+				aff.From, err = TimeParseAnyWithErr(yAff.From)
+				if err != nil {
+					return nil, fmt.Errorf("login %s: %v", yUser.Login, err)
+				}
+			}
+			if yAff.To != "" {
+				aff.To, err = TimeParseAnyWithErr(yAff.To)
+				if err != nil {
+					return nil, fmt.Errorf("login %s: %v", yUser.Login, err)
+				}
+			}
+			user.Affiliations = append(user.Affiliations, aff)
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
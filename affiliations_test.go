@@ -0,0 +1,419 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestDiffAffiliations(t *testing.T) {
+	// Dates
+	start := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	dt := testlib.YMDHMS(2017, 1, 1)
+
+	// Test cases
+	var testCases = []struct {
+		prev, curr       []lib.AffData
+		expectedAdded    []lib.AffData
+		expectedRemoved  []lib.AffData
+		expectedSummary  lib.AffsDiffSummary
+		expectedReport   string
+		expectedNoChange bool
+	}{
+		{
+			prev:             []lib.AffData{{Login: "a", Company: "A", From: start, To: end}},
+			curr:             []lib.AffData{{Login: "a", Company: "A", From: start, To: end}},
+			expectedSummary:  lib.AffsDiffSummary{},
+			expectedReport:   "Affiliations added: 0, removed: 0\nLogins new: 0, gone: 0, changed: 0\n",
+			expectedNoChange: true,
+		},
+		{
+			prev: []lib.AffData{
+				{Login: "a", Company: "A", From: start, To: end},
+				{Login: "b", Company: "B", From: start, To: end},
+			},
+			curr: []lib.AffData{
+				{Login: "c", Company: "A", From: start, To: end},
+				{Login: "a", Company: "B", From: dt, To: end},
+				{Login: "a", Company: "A", From: start, To: dt},
+				{Login: "c", Company: "A", From: start, To: end},
+			},
+			expectedAdded: []lib.AffData{
+				{Login: "a", Company: "A", From: start, To: dt},
+				{Login: "a", Company: "B", From: dt, To: end},
+				{Login: "c", Company: "A", From: start, To: end},
+			},
+			expectedRemoved: []lib.AffData{
+				{Login: "a", Company: "A", From: start, To: end},
+				{Login: "b", Company: "B", From: start, To: end},
+			},
+			expectedSummary: lib.AffsDiffSummary{
+				Added:         3,
+				Removed:       2,
+				NewLogins:     1,
+				GoneLogins:    1,
+				ChangedLogins: 1,
+				Companies: []lib.AffsCompanyChange{
+					{Company: "A", Added: 2, Removed: 1},
+					{Company: "B", Added: 1, Removed: 1},
+				},
+			},
+			expectedReport: "Affiliations added: 3, removed: 2\nLogins new: 1, gone: 1, changed: 1\n" +
+				"Company changes (company: +added -removed):\nA: +2 -1\nB: +1 -1\n",
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		diff := lib.DiffAffiliations(test.prev, test.curr)
+		if !reflect.DeepEqual(diff.Added, test.expectedAdded) {
+			t.Errorf("test number %d, expected added %+v, got %+v", index+1, test.expectedAdded, diff.Added)
+		}
+		if !reflect.DeepEqual(diff.Removed, test.expectedRemoved) {
+			t.Errorf("test number %d, expected removed %+v, got %+v", index+1, test.expectedRemoved, diff.Removed)
+		}
+		summary := diff.Summary()
+		if !reflect.DeepEqual(summary, test.expectedSummary) {
+			t.Errorf("test number %d, expected summary %+v, got %+v", index+1, test.expectedSummary, summary)
+		}
+		report := summary.Report()
+		if report != test.expectedReport {
+			t.Errorf("test number %d, expected report:\n%s\ngot:\n%s", index+1, test.expectedReport, report)
+		}
+		noChange := len(diff.Added) == 0 && len(diff.Removed) == 0
+		if noChange != test.expectedNoChange {
+			t.Errorf("test number %d, expected no change %v, got %v", index+1, test.expectedNoChange, noChange)
+		}
+	}
+}
+
+func TestAffsSourceHash(t *testing.T) {
+	got := lib.AffsSourceHash([]byte("[]"))
+	expected := "97d170e1550eee4afc0af065b78cda302a97674c"
+	if got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestAffsSources(t *testing.T) {
+	// Dates
+	start := lib.AffsDefaultFrom
+	end := lib.AffsDefaultTo
+	dt1 := testlib.YMDHMS(2016, 1, 1)
+	dt2 := testlib.YMDHMS(2017, 7)
+
+	// Test cases
+	var testCases = []struct {
+		fileName string
+		data     string
+		expected []lib.AffsUser
+		err      bool
+	}{
+		{
+			fileName: "github_users.json",
+			data: `[{"login":"a","email":"a!x.com","affiliation":"A < 2016-01-01, B < 2017-07, C","name":"Al"},` +
+				`{"login":"b","email":"","affiliation":"NotFound","name":""}]`,
+			expected: []lib.AffsUser{
+				{
+					Login:  "a",
+					Name:   "Al",
+					Emails: []string{"a!x.com"},
+					Affiliations: []lib.AffData{
+						{Login: "a", Company: "A", From: start, To: dt1},
+						{Login: "a", Company: "B", From: dt1, To: dt2},
+						{Login: "a", Company: "C", From: dt2, To: end},
+					},
+				},
+				{Login: "b"},
+			},
+		},
+		{
+			fileName: "developers_affiliations2.txt",
+			data:     "# comment\na: a!x.com, a!y.com\n\tA until 2016-01-01\n\tB\nb: b!x.com\n\nc: \n\tC until 2017-07\n",
+			expected: []lib.AffsUser{
+				{
+					Login:  "a",
+					Emails: []string{"a!x.com", "a!y.com"},
+					Affiliations: []lib.AffData{
+						{Login: "a", Company: "A", From: start, To: dt1},
+						{Login: "a", Company: "B", From: dt1, To: end},
+					},
+				},
+				{Login: "b", Emails: []string{"b!x.com"}},
+				{Login: "c", Affiliations: []lib.AffData{{Login: "c", Company: "C", From: start, To: dt2}}},
+			},
+		},
+		{
+			fileName: "affs.csv",
+			data:     "login,company,from,to\na,A,,2016-01-01\nb,B\n# comment\na,\"B, Inc.\",2016-01-01,2017-07\n",
+			expected: []lib.AffsUser{
+				{
+					Login: "a",
+					Affiliations: []lib.AffData{
+						{Login: "a", Company: "A", From: start, To: dt1},
+						{Login: "a", Company: "B, Inc.", From: dt1, To: dt2},
+					},
+				},
+				{Login: "b", Affiliations: []lib.AffData{{Login: "b", Company: "B", From: start, To: end}}},
+			},
+		},
+		{
+			fileName: "affs.yaml",
+			data: "users:\n  - login: a\n    name: Al\n    emails: [a@x.com]\n    affiliations:\n" +
+				"      - company: A\n        to: 2016-01-01\n      - company: B\n        from: '2016-01-01'\n",
+			expected: []lib.AffsUser{
+				{
+					Login:  "a",
+					Name:   "Al",
+					Emails: []string{"a@x.com"},
+					Affiliations: []lib.AffData{
+						{Login: "a", Company: "A", From: start, To: dt1},
+						{Login: "a", Company: "B", From: dt1, To: end},
+					},
+				},
+			},
+		},
+		{fileName: "developers_affiliations.txt", data: "\tA\n", err: true},
+		{fileName: "affs.csv", data: "a,A,2016-13-45\n", err: true},
+		{fileName: "affs.yml", data: "users:\n  - name: no login\n", err: true},
+		{fileName: "affs.xml", data: "", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		source, err := lib.GetAffsSource(test.fileName)
+		var got []lib.AffsUser
+		if err == nil {
+			got, err = source.Users([]byte(test.data))
+		}
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got %+v", index+1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected:\n%+v\ngot:\n%+v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestEmailDomain(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		email    string
+		expected string
+	}{
+		{email: "john@Google.com", expected: "google.com"},
+		{email: " john!redhat.com ", expected: "redhat.com"},
+		{email: "a@b@corp.ibm.com", expected: "corp.ibm.com"},
+		{email: "john@users.noreply.github.com", expected: ""},
+		{email: "john@localhost", expected: ""},
+		{email: "@google.com", expected: ""},
+		{email: "john@", expected: ""},
+		{email: "", expected: ""},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.EmailDomain(test.email)
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%v', got '%v', test case: %+v", index+1, test.expected, got, test)
+		}
+	}
+}
+
+func TestInferCompany(t *testing.T) {
+	// Domains
+	aliases := lib.CompanyAliases{
+		Companies: []lib.CompanyAlias{
+			{Name: "Google", Domains: []string{"google.com", "Golang.org"}},
+			{Name: "Red Hat", Domains: []string{"redhat.com"}},
+		},
+	}
+	domains := aliases.DomainsMap()
+
+	// Test cases
+	var testCases = []struct {
+		emails             map[string]int
+		expectedCompany    string
+		expectedConfidence float64
+	}{
+		{emails: map[string]int{}},
+		{emails: map[string]int{"john@gmail.com": 10}},
+		{emails: map[string]int{"john@users.noreply.github.com": 10}},
+		{
+			emails:             map[string]int{"john@google.com": 3},
+			expectedCompany:    "Google",
+			expectedConfidence: 1.0,
+		},
+		{
+			emails:             map[string]int{"john@corp.google.com": 1, "john@golang.org": 2, "john@gmail.com": 1},
+			expectedCompany:    "Google",
+			expectedConfidence: 0.75,
+		},
+		{
+			emails:             map[string]int{"john@google.com": 1, "john@redhat.com": 3},
+			expectedCompany:    "Red Hat",
+			expectedConfidence: 0.75,
+		},
+		{
+			emails:             map[string]int{"john@redhat.com": 2, "john!google.com": 2},
+			expectedCompany:    "Google",
+			expectedConfidence: 0.5,
+		},
+		{
+			emails:             map[string]int{"john@notgoogle.com": 2, "john@redhat.com": 1, "john@users.noreply.github.com": 5},
+			expectedCompany:    "Red Hat",
+			expectedConfidence: 1.0 / 3.0,
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		company, confidence := lib.InferCompany(domains, test.emails)
+		if company != test.expectedCompany || confidence != test.expectedConfidence {
+			t.Errorf(
+				"test number %d, expected (%v, %v), got (%v, %v), test case: %+v",
+				index+1, test.expectedCompany, test.expectedConfidence, company, confidence, test,
+			)
+		}
+	}
+}
+
+func TestTrustedCommitEmails(t *testing.T) {
+	commitEmails := map[int64]map[string]int{
+		// Merge robot pushing commits of many authors
+		1: {
+			"a@google.com": 5, "b@google.com": 5, "c@google.com": 5, "d@google.com": 5, "e@google.com": 5,
+			"f@redhat.com": 5, "g@redhat.com": 5,
+		},
+		// Maintainer pushing own commits and few commits of others
+		2: {"john@redhat.com": 10, "a@google.com": 3},
+		// Too few commits
+		3: {"jane@google.com": 2},
+	}
+	expected := map[int64]map[string]int{2: {"john@redhat.com": 10}}
+	got := lib.TrustedCommitEmails(commitEmails)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	// Robot must not be affiliated with its authors' company
+	aliases := lib.CompanyAliases{Companies: []lib.CompanyAlias{{Name: "Google", Domains: []string{"google.com"}}}}
+	company, _ := lib.InferCompany(aliases.DomainsMap(), got[1])
+	if company != "" {
+		t.Errorf("expected no company for pusher of many authors' commits, got %s", company)
+	}
+}
+
+func TestAffsSourcesSQL(t *testing.T) {
+	expected := "'file'"
+	got := lib.AffsSourcesSQL(false)
+	if got != expected {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	expected = "'file', 'email_domain'"
+	got = lib.AffsSourcesSQL(true)
+	if got != expected {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestCheckAffiliations(t *testing.T) {
+	// Dates
+	start := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	dt0 := testlib.YMDHMS(2015, 1, 1)
+	dt1 := testlib.YMDHMS(2016, 1, 1)
+	dt2 := testlib.YMDHMS(2017, 1, 1)
+
+	// Test cases
+	var testCases = []struct {
+		affs           []lib.AffData
+		expected       []lib.AffsConflict
+		expectedReport string
+	}{
+		{
+			affs: []lib.AffData{
+				{Login: "a", Company: "A", From: start, To: dt1},
+				{Login: "a", Company: "B", From: dt1, To: end},
+				{Login: "a", Company: "B", From: dt1, To: end},
+				{Login: "b", Company: "A", From: start, To: end},
+			},
+			expectedReport: "Conflicts: 0, logins: 0, overlap: 0, zero-length: 0, inverted: 0, out-of-order: 0\n",
+		},
+		{
+			affs: []lib.AffData{
+				{Login: "b", Company: "A", From: dt1, To: dt1},
+				{Login: "a", Company: "A", From: start, To: dt2},
+				{Login: "a", Company: "B", From: dt2, To: dt1},
+			},
+			expected: []lib.AffsConflict{
+				{Kind: lib.AffsConflictInverted, Aff: lib.AffData{Login: "a", Company: "B", From: dt2, To: dt1}},
+				{Kind: lib.AffsConflictZeroLength, Aff: lib.AffData{Login: "b", Company: "A", From: dt1, To: dt1}},
+			},
+			expectedReport: "a: inverted: B [2017-01-01 - 2016-01-01]\n" +
+				"b: zero-length: A [2016-01-01 - 2016-01-01]\n" +
+				"Conflicts: 2, logins: 2, overlap: 0, zero-length: 1, inverted: 1, out-of-order: 0\n",
+		},
+		{
+			affs: []lib.AffData{
+				{Login: "a", Company: "B", From: dt1, To: end},
+				{Login: "a", Company: "A", From: start, To: dt2},
+			},
+			expected: []lib.AffsConflict{
+				{
+					Kind:  lib.AffsConflictOutOfOrder,
+					Aff:   lib.AffData{Login: "a", Company: "A", From: start, To: dt2},
+					Other: &lib.AffData{Login: "a", Company: "B", From: dt1, To: end},
+				},
+				{
+					Kind:  lib.AffsConflictOverlap,
+					Aff:   lib.AffData{Login: "a", Company: "B", From: dt1, To: end},
+					Other: &lib.AffData{Login: "a", Company: "A", From: start, To: dt2},
+				},
+			},
+			expectedReport: "a: out-of-order: A [1970-01-01 - 2017-01-01] and B [2016-01-01 - 2099-01-01]\n" +
+				"a: overlap: B [2016-01-01 - 2099-01-01] and A [1970-01-01 - 2017-01-01]\n" +
+				"Conflicts: 2, logins: 1, overlap: 1, zero-length: 0, inverted: 0, out-of-order: 1\n",
+		},
+		{
+			affs: []lib.AffData{
+				{Login: "a", Company: "B", From: dt1, To: end},
+				{Login: "a", Company: "X", From: start, To: start},
+				{Login: "a", Company: "A", From: dt0, To: dt1},
+				{Login: "b", Company: "A", From: start, To: dt1},
+				{Login: "b", Company: "X", From: dt2, To: dt1},
+				{Login: "b", Company: "B", From: dt1, To: end},
+			},
+			expected: []lib.AffsConflict{
+				{Kind: lib.AffsConflictZeroLength, Aff: lib.AffData{Login: "a", Company: "X", From: start, To: start}},
+				{
+					Kind:  lib.AffsConflictOutOfOrder,
+					Aff:   lib.AffData{Login: "a", Company: "A", From: dt0, To: dt1},
+					Other: &lib.AffData{Login: "a", Company: "B", From: dt1, To: end},
+				},
+				{Kind: lib.AffsConflictInverted, Aff: lib.AffData{Login: "b", Company: "X", From: dt2, To: dt1}},
+			},
+			expectedReport: "a: zero-length: X [1970-01-01 - 1970-01-01]\n" +
+				"a: out-of-order: A [2015-01-01 - 2016-01-01] and B [2016-01-01 - 2099-01-01]\n" +
+				"b: inverted: X [2017-01-01 - 2016-01-01]\n" +
+				"Conflicts: 3, logins: 2, overlap: 0, zero-length: 1, inverted: 1, out-of-order: 1\n",
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.CheckAffiliations(test.affs)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected:\n%+v\ngot:\n%+v", index+1, test.expected, got)
+		}
+		report := lib.AffsConflictsReport(got)
+		if report != test.expectedReport {
+			t.Errorf("test number %d, expected report:\n%s\ngot:\n%s", index+1, test.expectedReport, report)
+		}
+	}
+}
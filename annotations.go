@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	yaml "gopkg.in/yaml.v2"
 )
 
 // Annotations contain list of annotations
@@ -121,18 +123,11 @@ func GetAnnotations(ctx *Ctx, orgRepo, annoRegexp string) (annotations Annotatio
 		}
 		FatalOnError(err)
 		creatorDate := time.Unix(unixTimeStamp, 0)
-		message := tagDataAry[2]
-		if len(message) > 40 {
-			message = message[0:40]
-		}
-		replacer := strings.NewReplacer("\n", " ", "\r", " ", "\t", " ")
-		message = replacer.Replace(message)
-
 		annotations.Annotations = append(
 			annotations.Annotations,
 			Annotation{
 				Name:        tagName,
-				Description: message,
+				Description: AnnotationDescription(tagDataAry[2]),
 				Date:        creatorDate,
 			},
 		)
@@ -146,8 +141,266 @@ func GetAnnotations(ctx *Ctx, orgRepo, annoRegexp string) (annotations Annotatio
 	return
 }
 
-// ProcessAnnotations Creates IfluxDB annotations and quick_series
+// AnnotationDescription - returns annotation description: message truncated to 40 bytes in a single line
+func AnnotationDescription(message string) string {
+	if len(message) > 40 {
+		message = message[0:40]
+	}
+	replacer := strings.NewReplacer("\n", " ", "\r", " ", "\t", " ")
+	return replacer.Replace(message)
+}
+
+// GetReleasesAnnotations - returns GitHub releases (not drafts) from gha_releases matching `annoRegexp`
+// When `repo` is empty, releases from all project's repositories are returned
+// Release date is the earliest publish (or create) date of a given tag
+func GetReleasesAnnotations(ctx *Ctx, repo, annoRegexp string) (annotations Annotations) {
+	var re *regexp.Regexp
+	if annoRegexp != "" {
+		re = regexp.MustCompile(annoRegexp)
+	}
+	con := PgConn(ctx)
+	defer func() { FatalOnError(con.Close()) }()
+	rows := QuerySQLWithErr(
+		con,
+		ctx,
+		"select tag_name, max(coalesce(name, '')), min(coalesce(published_at, created_at)) from gha_releases "+
+			"where not draft and ("+NValue(1)+" = '' or dup_repo_name = "+NValue(1)+") group by tag_name",
+		repo,
+	)
+	defer func() { FatalOnError(rows.Close()) }()
+	var (
+		tagName string
+		name    string
+		date    time.Time
+	)
+	for rows.Next() {
+		FatalOnError(rows.Scan(&tagName, &name, &date))
+		if re != nil && !re.MatchString(tagName) {
+			continue
+		}
+		annotations.Annotations = append(
+			annotations.Annotations,
+			Annotation{
+				Name:        tagName,
+				Description: AnnotationDescription(name),
+				Date:        date,
+			},
+		)
+	}
+	FatalOnError(rows.Err())
+	if ctx.Debug > 0 {
+		Printf("Got %d releases for '%s'\n", len(annotations.Annotations), repo)
+	}
+	return
+}
+
+// YAMLAnnotations - hand maintained list of project milestones
+type YAMLAnnotations struct {
+	Annotations []YAMLAnnotation `yaml:"annotations"`
+}
+
+// YAMLAnnotation - single milestone: name, description and date
+type YAMLAnnotation struct {
+	Name        string    `yaml:"name"`
+	Description string    `yaml:"description"`
+	Date        time.Time `yaml:"date"`
+}
+
+// ParseYAMLAnnotations - returns annotations from YAML milestones list matching `annoRegexp`
+func ParseYAMLAnnotations(data []byte, annoRegexp string) (annotations Annotations, err error) {
+	var re *regexp.Regexp
+	if annoRegexp != "" {
+		re, err = regexp.Compile(annoRegexp)
+		if err != nil {
+			return
+		}
+	}
+	var list YAMLAnnotations
+	err = yaml.Unmarshal(data, &list)
+	if err != nil {
+		return
+	}
+	for _, item := range list.Annotations {
+		if item.Name == "" || item.Date.IsZero() {
+			err = fmt.Errorf("annotation must have name and date: %+v", item)
+			return
+		}
+		if re != nil && !re.MatchString(item.Name) {
+			continue
+		}
+		annotations.Annotations = append(
+			annotations.Annotations,
+			Annotation{
+				Name:        item.Name,
+				Description: AnnotationDescription(item.Description),
+				Date:        item.Date,
+			},
+		)
+	}
+	return
+}
+
+// MergeAnnotations - merges annotations from multiple sources, annotations with the same name are merged:
+// the earliest date and the first non-empty description are used, result is sorted by date
+func MergeAnnotations(lists ...Annotations) (annotations Annotations) {
+	byName := make(map[string]int)
+	for _, list := range lists {
+		for _, annotation := range list.Annotations {
+			i, ok := byName[annotation.Name]
+			if !ok {
+				byName[annotation.Name] = len(annotations.Annotations)
+				annotations.Annotations = append(annotations.Annotations, annotation)
+				continue
+			}
+			merged := &annotations.Annotations[i]
+			if annotation.Date.Before(merged.Date) {
+				merged.Date = annotation.Date
+			}
+			if merged.Description == "" {
+				merged.Description = annotation.Description
+			}
+		}
+	}
+	sort.Stable(AnnotationsByDate(annotations.Annotations))
+	return
+}
+
+// GetProjectAnnotations - returns merged annotations from all project's annotation sources and releases model (see GetVersionReleases)
+// Annotations are filtered using sources (or project's) regexps, releases are built from the filtered annotations,
+// so tags of other components or repos (for example "client-go/v1.2.3") are not counted as project's releases
+// Without sources project's main repo git tags are used, files are relative to `dataPrefix`
+func GetProjectAnnotations(ctx *Ctx, proj *Project, dataPrefix string) (Annotations, []VersionRelease) {
+	sources := proj.AnnotationSources
+	if len(sources) == 0 {
+		sources = []AnnotationSource{{Type: AnnotationSourceGitTags}}
+	}
+	lists := []Annotations{}
+	for _, source := range sources {
+		annoRegexp := source.Regexp
+		if annoRegexp == "" {
+			annoRegexp = proj.AnnotationRegexp
+		}
+		repo := source.Repo
+		switch source.Type {
+		case AnnotationSourceGitTags, "":
+			if repo == "" {
+				repo = proj.MainRepo
+			}
+			if repo == "" {
+				Fatalf("git tags annotation source requires repo or project main repo")
+			}
+			lists = append(lists, GetAnnotations(ctx, repo, annoRegexp))
+		case AnnotationSourceReleases:
+			lists = append(lists, GetReleasesAnnotations(ctx, repo, annoRegexp))
+		case AnnotationSourceYAML:
+			data, err := ReadFile(ctx, dataPrefix+source.File)
+			FatalOnError(err)
+			annotations, err := ParseYAMLAnnotations(data, annoRegexp)
+			if err != nil {
+				Fatalf("annotations file %s: %v", source.File, err)
+			}
+			lists = append(lists, annotations)
+		default:
+			Fatalf("unknown annotation source type: '%s'", source.Type)
+		}
+	}
+	annotations := MergeAnnotations(lists...)
+	releases := GetVersionReleases(annotations.Annotations)
+	if ctx.Debug > 0 {
+		Printf("Got %d annotations and %d releases from %d sources\n", len(annotations.Annotations), len(releases), len(sources))
+	}
+	return annotations, releases
+}
+
+// DefaultQuickRangePeriods - default "Last ..." quick ranges
+var DefaultQuickRangePeriods = []QuickRangePeriod{
+	{Suffix: "d", Name: "Last day", Interval: "1 day"},
+	{Suffix: "w", Name: "Last week", Interval: "1 week"},
+	{Suffix: "d10", Name: "Last 10 days", Interval: "10 days"},
+	{Suffix: "m", Name: "Last month", Interval: "1 month"},
+	{Suffix: "q", Name: "Last quarter", Interval: "3 months"},
+	{Suffix: "y", Name: "Last year", Interval: "1 year"},
+	{Suffix: "y10", Name: "Last decade", Interval: "10 years"},
+}
+
+// quickRangeSuffixRe - allowed "Last ..." quick range suffixes, "a_", "c_" and "f_" prefixes are used by generated ranges
+var quickRangeSuffixRe = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// Validate - checks quick ranges config
+func (qr *QuickRanges) Validate() error {
+	suffixes := make(map[string]struct{})
+	for _, period := range qr.Periods {
+		if !quickRangeSuffixRe.MatchString(period.Suffix) {
+			return fmt.Errorf("invalid quick range suffix '%s', only lowercase letters and digits are allowed", period.Suffix)
+		}
+		_, ok := suffixes[period.Suffix]
+		if ok {
+			return fmt.Errorf("duplicate quick range suffix '%s'", period.Suffix)
+		}
+		suffixes[period.Suffix] = struct{}{}
+		if period.Name == "" || period.Interval == "" {
+			return fmt.Errorf("quick range '%s' requires name and interval", period.Suffix)
+		}
+	}
+	switch qr.Annotations {
+	case "", QuickRangesAll, QuickRangesMinor, QuickRangesMajor, QuickRangesNone:
+	default:
+		return fmt.Errorf("invalid annotations quick ranges '%s', allowed: all, minor, major, none", qr.Annotations)
+	}
+	for _, rng := range qr.Ranges {
+		if rng.Name == "" {
+			return fmt.Errorf("fixed quick range from %s requires name", ToYMDDate(rng.From))
+		}
+		if rng.To != nil && !rng.To.After(rng.From) {
+			return fmt.Errorf("fixed quick range '%s' must end after it starts", rng.Name)
+		}
+	}
+	return nil
+}
+
+// GroupAnnotations - returns annotations used for annotations quick ranges at a given level (see QuickRanges)
+// "minor" and "major" return the first release (by date) of each major.minor or major version, annotations must be sorted by date
+func GroupAnnotations(annotations []Annotation, level string) []Annotation {
+	switch level {
+	case QuickRangesNone:
+		return []Annotation{}
+	case QuickRangesMinor, QuickRangesMajor:
+	default:
+		return annotations
+	}
+	grouped := []Annotation{}
+	seen := make(map[string]struct{})
+	for _, annotation := range annotations {
+		v, ok := ParseSemVer(annotation.Name)
+		if !ok || v.Pre != "" {
+			continue
+		}
+		key := strconv.Itoa(v.Major)
+		if level == QuickRangesMinor {
+			key += "." + strconv.Itoa(v.Minor)
+		}
+		_, ok = seen[key]
+		if ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		grouped = append(grouped, annotation)
+	}
+	return grouped
+}
+
+// ProcessAnnotations Creates IfluxDB annotations and quick_series using default quick ranges
 func ProcessAnnotations(ctx *Ctx, annotations *Annotations, startDate, joinDate *time.Time) {
+	ProcessAnnotationsRanges(ctx, annotations, startDate, joinDate, nil)
+}
+
+// ProcessAnnotationsRanges Creates IfluxDB annotations and quick_series using project's quick ranges config (nil means defaults)
+func ProcessAnnotationsRanges(ctx *Ctx, annotations *Annotations, startDate, joinDate *time.Time, quickRanges *QuickRanges) {
+	if quickRanges == nil {
+		quickRanges = &QuickRanges{}
+	}
+	FatalOnError(quickRanges.Validate())
+
 	// Connect to Postgres
 	ic := PgConn(ctx)
 	defer func() { FatalOnError(ic.Close()) }()
@@ -220,14 +473,9 @@ func ProcessAnnotations(ctx *Ctx, annotations *Annotations, startDate, joinDate
 	}
 
 	// Special ranges
-	periods := [][3]string{
-		{"d", "Last day", "1 day"},
-		{"w", "Last week", "1 week"},
-		{"d10", "Last 10 days", "10 days"},
-		{"m", "Last month", "1 month"},
-		{"q", "Last quarter", "3 months"},
-		{"y", "Last year", "1 year"},
-		{"y10", "Last decade", "10 years"},
+	periods := DefaultQuickRangePeriods
+	if len(quickRanges.Periods) > 0 {
+		periods = quickRanges.Periods
 	}
 
 	// tags:
@@ -245,9 +493,9 @@ func ProcessAnnotations(ctx *Ctx, annotations *Annotations, startDate, joinDate
 
 	// Last "..." periods
 	for _, period := range periods {
-		tags[tagName+"_suffix"] = period[0]
-		tags[tagName+"_name"] = period[1]
-		tags[tagName+"_data"] = period[0] + ";" + period[2] + ";;"
+		tags[tagName+"_suffix"] = period.Suffix
+		tags[tagName+"_name"] = period.Name
+		tags[tagName+"_data"] = period.Suffix + ";" + period.Interval + ";;"
 		if ctx.Debug > 0 {
 			Printf(
 				"Series: %v: %+v\n",
@@ -261,9 +509,10 @@ func ProcessAnnotations(ctx *Ctx, annotations *Annotations, startDate, joinDate
 		tm = tm.Add(time.Hour)
 	}
 
-	// Add '(i) - (i+1)' annotation ranges
-	lastIndex := len(annotations.Annotations) - 1
-	for index, annotation := range annotations.Annotations {
+	// Add '(i) - (i+1)' annotation ranges, optionally grouped by semantic version
+	rangesAnnotations := GroupAnnotations(annotations.Annotations, quickRanges.Annotations)
+	lastIndex := len(rangesAnnotations) - 1
+	for index, annotation := range rangesAnnotations {
 		if index == lastIndex {
 			sfx := fmt.Sprintf("a_%d_n", index)
 			tags[tagName+"_suffix"] = sfx
@@ -282,7 +531,7 @@ func ProcessAnnotations(ctx *Ctx, annotations *Annotations, startDate, joinDate
 			tm = tm.Add(time.Hour)
 			break
 		}
-		nextAnnotation := annotations.Annotations[index+1]
+		nextAnnotation := rangesAnnotations[index+1]
 		sfx := fmt.Sprintf("a_%d_%d", index, index+1)
 		tags[tagName+"_suffix"] = sfx
 		tags[tagName+"_name"] = fmt.Sprintf("%s - %s", annotation.Name, nextAnnotation.Name)
@@ -337,12 +586,38 @@ func ProcessAnnotations(ctx *Ctx, annotations *Annotations, startDate, joinDate
 		tm = tm.Add(time.Hour)
 	}
 
+	// Fixed dates ranges
+	for index, rng := range quickRanges.Ranges {
+		sfx := fmt.Sprintf("f_%d", index)
+		to := NextDayStart(time.Now())
+		if rng.To != nil {
+			to = *rng.To
+		} else {
+			sfx += "_n"
+		}
+		tags[tagName+"_suffix"] = sfx
+		tags[tagName+"_name"] = rng.Name
+		tags[tagName+"_data"] = fmt.Sprintf("%s;;%s;%s", sfx, ToYMDHMSDate(rng.From), ToYMDHMSDate(to))
+		if ctx.Debug > 0 {
+			Printf(
+				"Series: %v: %+v\n",
+				tagName,
+				tags,
+			)
+		}
+		// Add batch point
+		pt := NewTSPoint(ctx, tagName, "", tags, nil, tm)
+		AddTSPoint(ctx, &pts, pt)
+		tm = tm.Add(time.Hour)
+	}
+
 	// Write the batch
+	// Ranges ending now and ranges no longer defined (after config change or when there are less annotations) are removed
 	if !ctx.SkipTSDB {
 		table := "tquick_ranges"
 		column := "quick_ranges_suffix"
 		if TableExists(ic, ctx, table) && TableColumnExists(ic, ctx, table, column) {
-			ExecSQLWithErr(ic, ctx, fmt.Sprintf("delete from %s where %s like '%%_n'", table, column))
+			ExecSQLWithErr(ic, ctx, fmt.Sprintf("delete from %s where %s like '%%_n' or time >= %s", table, column, NValue(1)), tm)
 		}
 		WriteTSPoints(ctx, ic, &pts, "", nil)
 	} else if ctx.Debug > 0 {
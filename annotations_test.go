@@ -69,3 +69,142 @@ func TestGetFakeAnnotations(t *testing.T) {
 		}
 	}
 }
+
+func TestParseYAMLAnnotations(t *testing.T) {
+	ft := testlib.YMDHMS
+	data := []byte(
+		"annotations:\n" +
+			"- name: v1.0\n" +
+			"  description: First stable release with a very long description\n" +
+			"  date: 2018-03-01T00:00:00Z\n" +
+			"- name: Graduation\n" +
+			"  date: 2018-06-05T00:00:00Z\n",
+	)
+	got, err := lib.ParseYAMLAnnotations(data, "")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	expected := []lib.Annotation{
+		{Name: "v1.0", Description: "First stable release with a very long de", Date: ft(2018, 3, 1)},
+		{Name: "Graduation", Description: "", Date: ft(2018, 6, 5)},
+	}
+	if !reflect.DeepEqual(expected, got.Annotations) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", expected, got.Annotations)
+	}
+
+	// Regexp
+	got, err = lib.ParseYAMLAnnotations(data, `^v\d+`)
+	if err != nil || len(got.Annotations) != 1 || got.Annotations[0].Name != "v1.0" {
+		t.Errorf("expected only v1.0 annotation, got %+v, error: %v", got.Annotations, err)
+	}
+
+	// Missing date
+	_, err = lib.ParseYAMLAnnotations([]byte("annotations:\n- name: v2.0\n"), "")
+	if err == nil {
+		t.Errorf("expected error for annotation without date")
+	}
+}
+
+func TestMergeAnnotations(t *testing.T) {
+	ft := testlib.YMDHMS
+	tags := lib.Annotations{
+		Annotations: []lib.Annotation{
+			{Name: "v1.1", Description: "tag v1.1", Date: ft(2018, 5)},
+			{Name: "v1.0", Description: "", Date: ft(2018, 2)},
+		},
+	}
+	releases := lib.Annotations{
+		Annotations: []lib.Annotation{
+			{Name: "v1.0", Description: "Release 1.0", Date: ft(2018, 3)},
+			{Name: "v1.1", Description: "Release 1.1", Date: ft(2018, 4)},
+			{Name: "v1.2", Description: "Release 1.2", Date: ft(2018, 7)},
+		},
+	}
+	milestones := lib.Annotations{
+		Annotations: []lib.Annotation{
+			{Name: "Graduation", Description: "Graduated", Date: ft(2018, 6)},
+		},
+	}
+	expected := []lib.Annotation{
+		{Name: "v1.0", Description: "Release 1.0", Date: ft(2018, 2)},
+		{Name: "v1.1", Description: "tag v1.1", Date: ft(2018, 4)},
+		{Name: "Graduation", Description: "Graduated", Date: ft(2018, 6)},
+		{Name: "v1.2", Description: "Release 1.2", Date: ft(2018, 7)},
+	}
+	got := lib.MergeAnnotations(tags, releases, milestones)
+	if !reflect.DeepEqual(expected, got.Annotations) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", expected, got.Annotations)
+	}
+	got = lib.MergeAnnotations()
+	if len(got.Annotations) != 0 {
+		t.Errorf("expected no annotations, got %+v", got.Annotations)
+	}
+}
+
+func TestGroupAnnotations(t *testing.T) {
+	ft := testlib.YMDHMS
+	annotations := []lib.Annotation{
+		{Name: "v1.8.0", Date: ft(2017, 9)},
+		{Name: "v1.8.1", Date: ft(2017, 10)},
+		{Name: "v1.9.0-rc.1", Date: ft(2017, 11)},
+		{Name: "Graduation", Date: ft(2017, 11, 15)},
+		{Name: "v1.9.0", Date: ft(2017, 12)},
+		{Name: "v1.8.2", Date: ft(2018, 1)},
+		{Name: "v2.0.0", Date: ft(2018, 3)},
+		{Name: "v2.0.1", Date: ft(2018, 4)},
+	}
+	names := func(annotations []lib.Annotation) (res []string) {
+		res = []string{}
+		for _, annotation := range annotations {
+			res = append(res, annotation.Name)
+		}
+		return
+	}
+	var testCases = []struct {
+		level    string
+		expected []string
+	}{
+		{level: "", expected: names(annotations)},
+		{level: lib.QuickRangesAll, expected: names(annotations)},
+		{level: lib.QuickRangesMinor, expected: []string{"v1.8.0", "v1.9.0", "v2.0.0"}},
+		{level: lib.QuickRangesMajor, expected: []string{"v1.8.0", "v2.0.0"}},
+		{level: lib.QuickRangesNone, expected: []string{}},
+	}
+	for index, test := range testCases {
+		got := names(lib.GroupAnnotations(annotations, test.level))
+		if !reflect.DeepEqual(test.expected, got) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestQuickRangesValidate(t *testing.T) {
+	ft := testlib.YMDHMS
+	from := ft(2018, 1)
+	to := ft(2018, 4)
+	var testCases = []struct {
+		ranges lib.QuickRanges
+		valid  bool
+	}{
+		{ranges: lib.QuickRanges{}, valid: true},
+		{
+			ranges: lib.QuickRanges{
+				Periods:     []lib.QuickRangePeriod{{Suffix: "m6", Name: "Last 6 months", Interval: "6 months"}},
+				Annotations: lib.QuickRangesMinor,
+				Ranges:      []lib.QuickRangeFixed{{Name: "FY2018 Q1", From: from, To: &to}, {Name: "Since 2018", From: from}},
+			},
+			valid: true,
+		},
+		{ranges: lib.QuickRanges{Periods: []lib.QuickRangePeriod{{Suffix: "a_1", Name: "x", Interval: "1 day"}}}},
+		{ranges: lib.QuickRanges{Periods: []lib.QuickRangePeriod{{Suffix: "d", Name: "x", Interval: "1 day"}, {Suffix: "d", Name: "y", Interval: "2 days"}}}},
+		{ranges: lib.QuickRanges{Periods: []lib.QuickRangePeriod{{Suffix: "d", Name: "x"}}}},
+		{ranges: lib.QuickRanges{Annotations: "patch"}},
+		{ranges: lib.QuickRanges{Ranges: []lib.QuickRangeFixed{{Name: "Wrong", From: to, To: &from}}}},
+	}
+	for index, test := range testCases {
+		err := test.ranges.Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("test number %d, expected valid: %v, got error: %v", index+1, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package devstats
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/github"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Reasons why actor is a bot, stored in gha_bots.reason
+const (
+	BotReasonPattern   = "pattern"
+	BotReasonSuffix    = "suffix"
+	BotReasonEventRate = "event_rate"
+	BotReasonAPIType   = "api_type"
+)
+
+// Bots - bots detection config file
+// Patterns are SQL like patterns ("%" - any string, "_" - any character) matched against lowercase logins
+// NotBots are logins never considered bots (even if they match patterns or heuristics)
+// MaxDailyEvents - actors with more events on any single day are bots candidates, 0 disables this heuristic
+// Projects - per project overrides: patterns and not bots are added to global ones, max daily events replaces global one
+type Bots struct {
+	Patterns       []string               `yaml:"patterns"`
+	NotBots        []string               `yaml:"not_bots"`
+	MaxDailyEvents int                    `yaml:"max_daily_events"`
+	Projects       map[string]BotsProject `yaml:"projects"`
+}
+
+// BotsProject - per project bots detection overrides
+type BotsProject struct {
+	Patterns       []string `yaml:"patterns"`
+	NotBots        []string `yaml:"not_bots"`
+	MaxDailyEvents int      `yaml:"max_daily_events"`
+}
+
+// BotsMatcher - matches logins against bots patterns
+type BotsMatcher struct {
+	patterns []string
+	regexps  []*regexp.Regexp
+	notBots  map[string]struct{}
+}
+
+// ReadBots - reads bots detection config file, returns empty config when file doesn't exist
+func ReadBots(ctx *Ctx, fileName string) (*Bots, error) {
+	bots := &Bots{}
+	_, err := os.Stat(fileName)
+	if os.IsNotExist(err) {
+		if ctx.Debug > 0 {
+			Printf("No bots file: %s\n", fileName)
+		}
+		return bots, nil
+	}
+	data, err := ReadFile(ctx, fileName)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(data, bots)
+	if err != nil {
+		return nil, err
+	}
+	return bots, nil
+}
+
+// ForProject - returns bots config with a given project overrides applied
+func (b *Bots) ForProject(project string) Bots {
+	cfg := Bots{
+		Patterns:       append([]string{}, b.Patterns...),
+		NotBots:        append([]string{}, b.NotBots...),
+		MaxDailyEvents: b.MaxDailyEvents,
+	}
+	override, ok := b.Projects[project]
+	if !ok {
+		return cfg
+	}
+	cfg.Patterns = append(cfg.Patterns, override.Patterns...)
+	cfg.NotBots = append(cfg.NotBots, override.NotBots...)
+	if override.MaxDailyEvents > 0 {
+		cfg.MaxDailyEvents = override.MaxDailyEvents
+	}
+	return cfg
+}
+
+// LikeToRegexp - converts SQL like pattern into anchored regexp
+func LikeToRegexp(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// Matcher - returns bots matcher for config patterns and not bots
+func (b *Bots) Matcher() (*BotsMatcher, error) {
+	m := &BotsMatcher{notBots: make(map[string]struct{})}
+	for _, pattern := range b.Patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		re, err := LikeToRegexp(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern '%s': %v", pattern, err)
+		}
+		m.patterns = append(m.patterns, pattern)
+		m.regexps = append(m.regexps, re)
+	}
+	for _, login := range b.NotBots {
+		m.notBots[strings.ToLower(strings.TrimSpace(login))] = struct{}{}
+	}
+	return m, nil
+}
+
+// NotBot - returns true if login is explicitly marked as not a bot
+func (m *BotsMatcher) NotBot(login string) bool {
+	_, ok := m.notBots[strings.ToLower(login)]
+	return ok
+}
+
+// Match - checks if login is a bot using its name only: "[bot]" suffix (GitHub apps) or config patterns
+// Returns reason (BotReasonSuffix or BotReasonPattern) and detail (matching pattern)
+func (m *BotsMatcher) Match(login string) (reason, detail string, ok bool) {
+	login = strings.ToLower(login)
+	if m.NotBot(login) {
+		return
+	}
+	if strings.HasSuffix(login, "[bot]") {
+		return BotReasonSuffix, "[bot]", true
+	}
+	for i, re := range m.regexps {
+		if re.MatchString(login) {
+			return BotReasonPattern, m.patterns[i], true
+		}
+	}
+	return
+}
+
+// GHUserType - returns GitHub user type ("User", "Bot", "Organization") using GitHub API
+// Returns false when user was not found or API cannot be used now
+func GHUserType(gctx context.Context, gc *github.Client, ctx *Ctx, login string) (string, bool) {
+	var user *github.User
+	ok := ghAPICallWithRetry(
+		gctx,
+		gc,
+		ctx,
+		&IssueConfig{Repo: login},
+		"Users.Get",
+		func() (err error) {
+			user, _, err = gc.Users.Get(gctx, login)
+			return
+		},
+	)
+	if !ok || user == nil {
+		return "", false
+	}
+	return user.GetType(), true
+}
+
+// DailyEventsCandidates - returns lowercase logins with more than maxEvents events on any single day and their maximum
+func DailyEventsCandidates(con *sql.DB, ctx *Ctx, maxEvents int) map[string]int {
+	candidates := make(map[string]int)
+	if maxEvents <= 0 {
+		return candidates
+	}
+	rows := QuerySQLWithErr(
+		con,
+		ctx,
+		"select login, max(cnt) from ("+
+			"select lower(dup_actor_login) as login, date_trunc('day', created_at) as day, count(*) as cnt "+
+			"from gha_events group by login, day) sub "+
+			"group by login having max(cnt) > "+NValue(1),
+		maxEvents,
+	)
+	defer func() { FatalOnError(rows.Close()) }()
+	var (
+		login string
+		cnt   int
+	)
+	for rows.Next() {
+		FatalOnError(rows.Scan(&login, &cnt))
+		candidates[login] = cnt
+	}
+	FatalOnError(rows.Err())
+	return candidates
+}
+
+// EnsureBotsTable - creates gha_bots table using util_sql/bots_table.sql when it is missing
+// (database created before `bots` tool was added)
+func EnsureBotsTable(con *sql.DB, ctx *Ctx, dataPrefix string) {
+	if TableExists(con, ctx, "gha_bots") {
+		return
+	}
+	Printf("Creating missing gha_bots table\n")
+	bytes, err := ReadFile(ctx, dataPrefix+"util_sql/bots_table.sql")
+	FatalOnError(err)
+	ExecSQLWithErr(con, ctx, string(bytes))
+}
+
+// BotsDetected - checks if gha_bots table exists and was already filled by `bots` tool
+func BotsDetected(con *sql.DB, ctx *Ctx) bool {
+	if !TableExists(con, ctx, "gha_bots") {
+		return false
+	}
+	detected := false
+	FatalOnError(QueryRowSQL(con, ctx, "select exists(select 1 from gha_bots)").Scan(&detected))
+	return detected
+}
+
+// BotsPatternsSQL - returns {{exclude_bots}} partial SQL excluding lowercase logins matching patterns or "[bot]" suffix
+func BotsPatternsSQL(patterns []string) string {
+	quoted := []string{"'%[bot]'"}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		quoted = append(quoted, "'"+strings.Replace(pattern, "'", "''", -1)+"'")
+	}
+	return "not like all(array[" + strings.Join(quoted, ", ") + "])"
+}
+
+// ExcludeBotsSQL - returns {{exclude_bots}} partial SQL: util_sql/exclude_bots.sql that uses gha_bots table
+// When gha_bots is missing or empty (`bots` tool was not run yet) bots config patterns are used instead,
+// so bots are never counted in metrics and tags
+func ExcludeBotsSQL(con *sql.DB, ctx *Ctx, dataPrefix string) string {
+	if BotsDetected(con, ctx) {
+		bytes, err := ReadFile(ctx, dataPrefix+"util_sql/exclude_bots.sql")
+		FatalOnError(err)
+		return string(bytes)
+	}
+	Printf("Warning: gha_bots table is missing or empty, run `bots` tool, excluding bots using %s patterns\n", ctx.BotsYaml)
+	bots, err := ReadBots(ctx, dataPrefix+ctx.BotsYaml)
+	FatalOnError(err)
+	cfg := bots.ForProject(ctx.Project)
+	return BotsPatternsSQL(cfg.Patterns)
+}
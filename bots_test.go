@@ -0,0 +1,112 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestBotsForProject(t *testing.T) {
+	bots := lib.Bots{
+		Patterns:       []string{"%-bot"},
+		NotBots:        []string{"abbot"},
+		MaxDailyEvents: 1000,
+		Projects: map[string]lib.BotsProject{
+			"kubernetes": {Patterns: []string{"k8s-%"}, NotBots: []string{"k8s-human"}, MaxDailyEvents: 2000},
+			"prometheus": {Patterns: []string{"prombot"}},
+		},
+	}
+	// Test cases
+	var testCases = []struct {
+		project  string
+		expected lib.Bots
+	}{
+		{
+			project:  "",
+			expected: lib.Bots{Patterns: []string{"%-bot"}, NotBots: []string{"abbot"}, MaxDailyEvents: 1000},
+		},
+		{
+			project: "kubernetes",
+			expected: lib.Bots{
+				Patterns:       []string{"%-bot", "k8s-%"},
+				NotBots:        []string{"abbot", "k8s-human"},
+				MaxDailyEvents: 2000,
+			},
+		},
+		{
+			project:  "prometheus",
+			expected: lib.Bots{Patterns: []string{"%-bot", "prombot"}, NotBots: []string{"abbot"}, MaxDailyEvents: 1000},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := bots.ForProject(test.project)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected:\n%+v\ngot:\n%+v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestBotsMatcher(t *testing.T) {
+	bots := lib.Bots{
+		Patterns: []string{"googlebot", "K8S-%", "%-ci%bot", "%[bot]%", "bot_"},
+		NotBots:  []string{"k8s-Human"},
+	}
+	matcher, err := bots.Matcher()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Test cases
+	var testCases = []struct {
+		login          string
+		expectedReason string
+		expectedDetail string
+		expectedOk     bool
+	}{
+		{login: "GoogleBot", expectedReason: lib.BotReasonPattern, expectedDetail: "googlebot", expectedOk: true},
+		{login: "googlebots"},
+		{login: "k8s-ci-robot", expectedReason: lib.BotReasonPattern, expectedDetail: "k8s-%", expectedOk: true},
+		{login: "k8s-human"},
+		{login: "foo-ci-some-bot", expectedReason: lib.BotReasonPattern, expectedDetail: "%-ci%bot", expectedOk: true},
+		{login: "dependabot[bot]", expectedReason: lib.BotReasonSuffix, expectedDetail: "[bot]", expectedOk: true},
+		{login: "x[bot]y", expectedReason: lib.BotReasonPattern, expectedDetail: "%[bot]%", expectedOk: true},
+		{login: "bot1", expectedReason: lib.BotReasonPattern, expectedDetail: "bot_", expectedOk: true},
+		{login: "bot12"},
+		{login: "john"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		reason, detail, ok := matcher.Match(test.login)
+		if reason != test.expectedReason || detail != test.expectedDetail || ok != test.expectedOk {
+			t.Errorf(
+				"test number %d, expected (%v, %v, %v), got (%v, %v, %v), test case: %+v",
+				index+1, test.expectedReason, test.expectedDetail, test.expectedOk, reason, detail, ok, test,
+			)
+		}
+	}
+	if !matcher.NotBot("K8S-HUMAN") || matcher.NotBot("john") {
+		t.Errorf("not bots check failed")
+	}
+}
+
+func TestBotsPatternsSQL(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		patterns []string
+		expected string
+	}{
+		{expected: "not like all(array['%[bot]'])"},
+		{
+			patterns: []string{"GoogleBot", " k8s-% ", "", "o'bot"},
+			expected: "not like all(array['%[bot]', 'googlebot', 'k8s-%', 'o''bot'])",
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.BotsPatternsSQL(test.patterns)
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%v', got '%v', test case: %+v", index+1, test.expected, got, test)
+		}
+	}
+}
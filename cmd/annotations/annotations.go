@@ -37,13 +37,18 @@ func makeAnnotations() {
 		lib.Fatalf("project '%s' not found in '%s'", ctx.Project, ctx.ProjectsYaml)
 	}
 
-	// Get annotations using GitHub API and add annotations and quick ranges to TSDB
-	if proj.MainRepo != "" {
-		annotations := lib.GetAnnotations(&ctx, proj.MainRepo, proj.AnnotationRegexp)
-		lib.ProcessAnnotations(&ctx, &annotations, proj.StartDate, proj.JoinDate)
+	// Get annotations from project's annotation sources (default main repo tags) and add annotations and quick ranges to TSDB
+	if proj.MainRepo != "" || len(proj.AnnotationSources) > 0 {
+		annotations, releases := lib.GetProjectAnnotations(&ctx, &proj, dataPrefix)
+
+		// Save releases model used by release cadence metrics
+		con := lib.PgConn(&ctx)
+		lib.SaveVersionReleases(con, &ctx, releases)
+		lib.FatalOnError(con.Close())
+		lib.ProcessAnnotationsRanges(&ctx, &annotations, proj.StartDate, proj.JoinDate, proj.QuickRanges)
 	} else if proj.StartDate != nil && proj.JoinDate != nil {
 		annotations := lib.GetFakeAnnotations(*proj.StartDate, *proj.JoinDate)
-		lib.ProcessAnnotations(&ctx, &annotations, nil, nil)
+		lib.ProcessAnnotationsRanges(&ctx, &annotations, nil, nil, proj.QuickRanges)
 	}
 }
 
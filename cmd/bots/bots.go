@@ -0,0 +1,164 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	lib "devstats"
+)
+
+// bot - detected bot with the reason and detail
+type bot struct {
+	reason string
+	detail string
+}
+
+// getLogins - returns all distinct lowercase actors logins
+func getLogins(con *sql.DB, ctx *lib.Ctx) (logins []string) {
+	rows := lib.QuerySQLWithErr(con, ctx, "select distinct lower(login) from gha_actors")
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	var login string
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&login))
+		logins = append(logins, login)
+	}
+	lib.FatalOnError(rows.Err())
+	return
+}
+
+// getBots - returns bots saved by the previous run
+func getBots(con *sql.DB, ctx *lib.Ctx) map[string]bot {
+	rows := lib.QuerySQLWithErr(con, ctx, "select login, reason, detail from gha_bots")
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	bots := make(map[string]bot)
+	var (
+		login string
+		b     bot
+	)
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&login, &b.reason, &b.detail))
+		bots[login] = b
+	}
+	lib.FatalOnError(rows.Err())
+	return bots
+}
+
+// detectBots - detects bots using config patterns and heuristics and saves them in gha_bots table
+// Name heuristics: "[bot]" suffix (GitHub apps) and patterns from the config file (with per project overrides)
+// Event rate heuristic: actors with more than max_daily_events events on any day are bots candidates,
+// candidates are checked using GitHub API user type (unless GHA2DB_GHAPISKIP is set)
+// When API check fails (rate limit, not found) candidate keeps its previous gha_bots row, or is not a bot if it had none
+func detectBots() {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Connect to Postgres DB
+	con := lib.PgConn(&ctx)
+	defer func() { lib.FatalOnError(con.Close()) }()
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+	lib.EnsureBotsTable(con, &ctx, dataPrefix)
+
+	// Read bots config, apply current project overrides
+	allBots, err := lib.ReadBots(&ctx, dataPrefix+ctx.BotsYaml)
+	lib.FatalOnError(err)
+	cfg := allBots.ForProject(ctx.Project)
+	matcher, err := cfg.Matcher()
+	lib.FatalOnError(err)
+
+	// Name heuristics
+	bots := make(map[string]bot)
+	logins := getLogins(con, &ctx)
+	for _, login := range logins {
+		reason, detail, ok := matcher.Match(login)
+		if ok {
+			bots[login] = bot{reason: reason, detail: detail}
+		}
+	}
+
+	// Event rate heuristic, confirmed by GitHub API user type when possible
+	candidates := lib.DailyEventsCandidates(con, &ctx, cfg.MaxDailyEvents)
+	apiChecked, apiFailed := 0, 0
+	if !ctx.SkipGHAPI && len(candidates) > 0 {
+		prevBots := getBots(con, &ctx)
+		gctx, gc := lib.GHClient(&ctx)
+		defer lib.WriteGHAPIStats(con, &ctx, "bots")
+		for login, cnt := range candidates {
+			_, ok := bots[login]
+			if ok || matcher.NotBot(login) {
+				continue
+			}
+			userType, ok := lib.GHUserType(gctx, gc, &ctx, login)
+			if !ok {
+				apiFailed++
+				prev, ok := prevBots[login]
+				if ok {
+					bots[login] = prev
+				}
+				if ctx.Debug > 0 {
+					lib.Printf("%s: %d events/day, cannot check API type, previous bot status kept: %v\n", login, cnt, ok)
+				}
+				continue
+			}
+			apiChecked++
+			if userType == "Bot" {
+				bots[login] = bot{reason: lib.BotReasonAPIType, detail: userType}
+			} else if ctx.Debug > 0 {
+				lib.Printf("%s: %d events/day, but API type is %s, not a bot\n", login, cnt, userType)
+			}
+		}
+	} else {
+		for login, cnt := range candidates {
+			_, ok := bots[login]
+			if ok || matcher.NotBot(login) {
+				continue
+			}
+			bots[login] = bot{reason: lib.BotReasonEventRate, detail: fmt.Sprintf("%d events/day", cnt)}
+		}
+	}
+
+	// Save bots in transaction, so metrics and tags running meanwhile never see empty gha_bots table
+	reasons := make(map[string]int)
+	tx, err := con.Begin()
+	lib.FatalOnError(err)
+	lib.ExecSQLTxWithErr(tx, &ctx, "delete from gha_bots")
+	for login, b := range bots {
+		lib.ExecSQLTxWithErr(
+			tx,
+			&ctx,
+			lib.InsertIgnore("into gha_bots(login, reason, detail) "+lib.NValues(3)),
+			lib.AnyArray{login, b.reason, lib.TruncToBytes(b.detail, 160)}...,
+		)
+		reasons[b.reason]++
+		if ctx.Debug > 0 {
+			lib.Printf("Bot: %s (%s: %s)\n", login, b.reason, b.detail)
+		}
+	}
+	lib.FatalOnError(tx.Commit())
+	keys := []string{}
+	for reason := range reasons {
+		keys = append(keys, reason)
+	}
+	sort.Strings(keys)
+	for _, reason := range keys {
+		lib.Printf("%s: %d bots\n", reason, reasons[reason])
+	}
+	lib.Printf(
+		"%d actors, %d bots, %d event rate candidates, %d checked using GitHub API, %d API checks failed\n",
+		len(logins), len(bots), len(candidates), apiChecked, apiFailed,
+	)
+}
+
+func main() {
+	dtStart := time.Now()
+	detectBots()
+	dtEnd := time.Now()
+	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
+}
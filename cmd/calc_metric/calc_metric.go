@@ -603,7 +603,7 @@ func calcHistogram(
 
 func calcMetric(
 	seriesNameOrFunc, sqlFile, from, to, intervalAbbr string,
-	hist, multivalue, escapeValueName, annotationsRanges, skipPast bool,
+	hist, multivalue, escapeValueName, annotationsRanges, skipPast, inferredAffs bool,
 	desc, mergeSeries string,
 ) {
 	if intervalAbbr == "" {
@@ -624,10 +624,16 @@ func calcMetric(
 	lib.FatalOnError(err)
 	sqlQuery := string(bytes)
 
-	// Read bots exclusion partial SQL
-	bytes, err = lib.ReadFile(&ctx, dataPrefix+"util_sql/exclude_bots.sql")
-	lib.FatalOnError(err)
-	excludeBots := string(bytes)
+	// Affiliations sources to use, inferred affiliations are only used when requested
+	sqlQuery = strings.Replace(sqlQuery, "{{affs_sources}}", lib.AffsSourcesSQL(inferredAffs), -1)
+
+	// Count persons instead of logins
+	sqlQuery = lib.PreparePersonQuery(sqlQuery)
+
+	// Bots exclusion partial SQL
+	con := lib.PgConn(&ctx)
+	excludeBots := lib.ExcludeBotsSQL(con, &ctx, dataPrefix)
+	lib.FatalOnError(con.Close())
 
 	// Process interval
 	interval, nIntervals, intervalStart, nextIntervalStart, prevIntervalStart := lib.GetIntervalFunctions(intervalAbbr, annotationsRanges)
@@ -754,7 +760,7 @@ func main() {
 	if len(os.Args) < 6 {
 		lib.Printf(
 			"Required series name, SQL file name, from, to, period " +
-				"[series_name_or_func some.sql '2015-08-03' '2017-08-21' h|d|w|m|q|y [hist,desc:time_diff_as_string,multivalue,escape_value_name,annotations_ranges,skip_past]]\n",
+				"[series_name_or_func some.sql '2015-08-03' '2017-08-21' h|d|w|m|q|y [hist,desc:time_diff_as_string,multivalue,escape_value_name,annotations_ranges,skip_past,inferred_affs]]\n",
 		)
 		lib.Printf(
 			"Series name (series_name_or_func) will become exact series name if " +
@@ -769,6 +775,7 @@ func main() {
 	escapeValueName := false
 	annotationsRanges := false
 	skipPast := false
+	inferredAffs := false
 	desc := ""
 	mergeSeries := ""
 	if len(os.Args) > 6 {
@@ -798,6 +805,9 @@ func main() {
 		if _, ok := optMap["skip_past"]; ok {
 			skipPast = true
 		}
+		if _, ok := optMap["inferred_affs"]; ok {
+			inferredAffs = true
+		}
 		if d, ok := optMap["desc"]; ok {
 			desc = d
 		}
@@ -817,6 +827,7 @@ func main() {
 		escapeValueName,
 		annotationsRanges,
 		skipPast,
+		inferredAffs,
 		desc,
 		mergeSeries,
 	)
@@ -1,8 +1,15 @@
 package main
 
 import (
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	lib "devstats"
 
@@ -15,10 +22,165 @@ type columns struct {
 }
 
 // column contain configuration of columns needed on a specific series
+// Prune allows GHA2DB_COLUMNS_PRUNE to remove matching tables columns that are not in the tag,
+// only set it when all double precision columns of matching tables come from tags
 type column struct {
 	TableRegexp string `yaml:"table_regexp"`
 	Tag         string `yaml:"tag"`
 	Column      string `yaml:"column"`
+	Prune       bool   `yaml:"prune"`
+}
+
+// tableColumns - expected columns of a series table, collected from all column configs matching it
+// prune is only true when all matching configs allow pruning, complete is false when any of matching tags has no values
+type tableColumns struct {
+	tags     map[string]struct{}
+	expected map[string]struct{}
+	prune    bool
+	complete bool
+}
+
+// columnsDrift - series table whose columns differ from its tags
+// Missing - tag values without a column, Stale - columns without a tag value, Action - what prune mode did with stale columns
+type columnsDrift struct {
+	Table   string   `json:"table"`
+	Tags    []string `json:"tags"`
+	Missing []string `json:"missing"`
+	Stale   []string `json:"stale"`
+	Action  string   `json:"action"`
+}
+
+// Prune modes (GHA2DB_COLUMNS_PRUNE)
+const (
+	pruneDrop    = "drop"
+	pruneArchive = "archive"
+)
+
+// psqlColumnName - returns column name as stored by Postgres (identifiers are truncated to 63 bytes)
+func psqlColumnName(name string) string {
+	if len(name) <= 63 {
+		return name
+	}
+	n := 63
+	for n > 0 && !utf8.RuneStart(name[n]) {
+		n--
+	}
+	return name[:n]
+}
+
+func sortedKeys(m map[string]struct{}) (keys []string) {
+	keys = []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+// pruneColumn - drops column (archiving its non-zero values first in archive mode) in a single transaction
+func pruneColumn(con *sql.DB, ctx *lib.Ctx, table, column string, columns map[string]string) {
+	tx, err := con.Begin()
+	lib.FatalOnError(err)
+	if ctx.ColumnsPrune == pruneArchive {
+		period := "''"
+		if _, ok := columns["period"]; ok {
+			period = "period"
+		}
+		series := "''"
+		if _, ok := columns["series"]; ok {
+			series = "series"
+		}
+		lib.ExecSQLTxWithErr(
+			tx,
+			ctx,
+			lib.InsertIgnore(
+				"into gha_archived_columns(table_name, column_name, time, period, series, value, archived_at) "+
+					fmt.Sprintf(
+						"select %s, %s, time, %s, %s, \"%s\", now() from \"%s\" where \"%s\" != 0",
+						lib.NValue(1),
+						lib.NValue(2),
+						period,
+						series,
+						column,
+						table,
+						column,
+					),
+			),
+			table,
+			column,
+		)
+	}
+	lib.ExecSQLTxWithErr(tx, ctx, "alter table \""+table+"\" drop column \""+column+"\"")
+	lib.FatalOnError(tx.Commit())
+}
+
+// columnsDrifts - compares series tables columns with their tags, prunes stale columns when prune mode is set
+func columnsDrifts(con *sql.DB, ctx *lib.Ctx, tables map[string]*tableColumns) (drifts []columnsDrift) {
+	names := []string{}
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+	for _, table := range names {
+		tc := tables[table]
+		rows := lib.QuerySQLWithErr(
+			con,
+			ctx,
+			"select column_name, data_type from information_schema.columns "+
+				"where table_schema = 'public' and table_name = "+lib.NValue(1),
+			table,
+		)
+		columns := make(map[string]string)
+		column, dataType := "", ""
+		for rows.Next() {
+			lib.FatalOnError(rows.Scan(&column, &dataType))
+			columns[column] = dataType
+		}
+		lib.FatalOnError(rows.Err())
+		lib.FatalOnError(rows.Close())
+		drift := columnsDrift{Table: table, Tags: sortedKeys(tc.tags), Missing: []string{}, Stale: []string{}}
+		for _, column := range sortedKeys(tc.expected) {
+			if _, ok := columns[column]; !ok {
+				drift.Missing = append(drift.Missing, column)
+			}
+		}
+		// Only double precision columns are created from tags, "time", "period" and "series" are text or timestamp
+		for column, dataType := range columns {
+			if _, ok := tc.expected[column]; !ok && dataType == "double precision" {
+				drift.Stale = append(drift.Stale, column)
+			}
+		}
+		sort.Strings(drift.Stale)
+		if len(drift.Missing) == 0 && len(drift.Stale) == 0 {
+			continue
+		}
+		if len(drift.Stale) > 0 && ctx.ColumnsPrune != "" {
+			if !tc.prune {
+				drift.Action = "skipped: prune not enabled in columns config"
+			} else if !tc.complete {
+				drift.Action = "skipped: tag without values"
+			} else {
+				for _, column := range drift.Stale {
+					pruneColumn(con, ctx, table, column, columns)
+				}
+				if ctx.ColumnsPrune == pruneArchive {
+					drift.Action = "archived"
+				} else {
+					drift.Action = "dropped"
+				}
+			}
+		}
+		lib.Printf(
+			"Drift: table \"%s\" (%s): missing columns: %s, stale columns: %s %s\n",
+			table,
+			strings.Join(drift.Tags, ", "),
+			strings.Join(drift.Missing, ", "),
+			strings.Join(drift.Stale, ", "),
+			drift.Action,
+		)
+		drifts = append(drifts, drift)
+	}
+	return
 }
 
 // Ensure that specific TSDB series have all needed columns
@@ -26,6 +188,9 @@ func ensureColumns() {
 	// Environment context parse
 	var ctx lib.Ctx
 	ctx.Init()
+	if ctx.ColumnsPrune != "" && ctx.ColumnsPrune != pruneDrop && ctx.ColumnsPrune != pruneArchive {
+		lib.Fatalf("unknown columns prune mode '%s', allowed modes: %s, %s", ctx.ColumnsPrune, pruneDrop, pruneArchive)
+	}
 
 	// Connect to Postgres DB
 	con := lib.PgConn(&ctx)
@@ -52,6 +217,10 @@ func ensureColumns() {
 		dir += ctx.Project + "/"
 	}
 
+	// Series tables matched by column configs
+	tables := make(map[string]*tableColumns)
+	mtx := &sync.Mutex{}
+
 	thrN := lib.GetThreadsNum(&ctx)
 	ch := make(chan bool)
 	nThreads := 0
@@ -63,6 +232,27 @@ func ensureColumns() {
 			if ctx.Debug > 0 {
 				lib.Printf("Ensure column config: %+v\n", col)
 			}
+			rows := lib.QuerySQLWithErr(
+				con,
+				&ctx,
+				fmt.Sprintf(
+					"select tablename from pg_catalog.pg_tables where "+
+						"schemaname = 'public' and substring(tablename from %s) is not null",
+					lib.NValue(1),
+				),
+				col.TableRegexp,
+			)
+			defer func() { lib.FatalOnError(rows.Close()) }()
+			table := ""
+			colTables := []string{}
+			for rows.Next() {
+				lib.FatalOnError(rows.Scan(&table))
+				colTables = append(colTables, table)
+			}
+			lib.FatalOnError(rows.Err())
+			if len(colTables) == 0 {
+				lib.Printf("Warning: '%+v': no table hits", col)
+			}
 			crows := lib.QuerySQLWithErr(
 				con,
 				&ctx,
@@ -80,6 +270,26 @@ func ensureColumns() {
 				colNames = append(colNames, colName)
 			}
 			lib.FatalOnError(crows.Err())
+
+			// Remember expected columns for drift report
+			mtx.Lock()
+			for _, table := range colTables {
+				tc, ok := tables[table]
+				if !ok {
+					tc = &tableColumns{tags: make(map[string]struct{}), expected: make(map[string]struct{}), prune: true, complete: true}
+					tables[table] = tc
+				}
+				tc.prune = tc.prune && col.Prune
+				tc.tags[col.Tag+"."+col.Column] = struct{}{}
+				for _, colName := range colNames {
+					tc.expected[psqlColumnName(colName)] = struct{}{}
+				}
+				if len(colNames) == 0 {
+					tc.complete = false
+				}
+			}
+			mtx.Unlock()
+
 			if len(colNames) == 0 {
 				lib.Printf("Warning: no tag values for (%s, %s)\n", col.Column, col.Tag)
 				if ch != nil {
@@ -90,21 +300,7 @@ func ensureColumns() {
 			if ctx.Debug > 0 {
 				lib.Printf("Ensure columns: %+v --> %+v\n", col, colNames)
 			}
-			rows := lib.QuerySQLWithErr(
-				con,
-				&ctx,
-				fmt.Sprintf(
-					"select tablename from pg_catalog.pg_tables where "+
-						"schemaname = 'public' and substring(tablename from %s) is not null",
-					lib.NValue(1),
-				),
-				col.TableRegexp,
-			)
-			defer func() { lib.FatalOnError(rows.Close()) }()
-			table := ""
-			numTables := 0
-			for rows.Next() {
-				lib.FatalOnError(rows.Scan(&table))
+			for _, table := range colTables {
 				for _, colName := range colNames {
 					_, err := lib.ExecSQL(
 						con,
@@ -115,12 +311,8 @@ func ensureColumns() {
 						lib.Printf("Added column \"%s\" to \"%s\" table\n", colName, table)
 					}
 				}
-				numTables++
-			}
-			lib.FatalOnError(rows.Err())
-			if numTables == 0 {
-				lib.Printf("Warning: '%+v': no table hits", col)
 			}
+			numTables := len(colTables)
 			// Synchronize go routine
 			if ch != nil {
 				ch <- numTables > 0
@@ -139,6 +331,19 @@ func ensureColumns() {
 		<-ch
 		nThreads--
 	}
+
+	// Drift report and optional prune
+	drifts := columnsDrifts(con, &ctx, tables)
+	lib.Printf("%d series tables checked, %d with columns drift\n", len(tables), len(drifts))
+	if ctx.ColumnsReport != "" {
+		if drifts == nil {
+			drifts = []columnsDrift{}
+		}
+		jsonBytes, err := json.Marshal(drifts)
+		lib.FatalOnError(err)
+		lib.FatalOnError(ioutil.WriteFile(ctx.ColumnsReport, lib.PrettyPrintJSON(jsonBytes), 0644))
+		lib.Printf("Columns drift report saved to %s\n", ctx.ColumnsReport)
+	}
 }
 
 func main() {
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	lib "devstats"
+)
+
+// getCompanies - returns all company names with number of affiliations
+func getCompanies(con *sql.DB, ctx *lib.Ctx) (names []string, affs map[string]int) {
+	rows := lib.QuerySQLWithErr(
+		con,
+		ctx,
+		"select c.name, (select count(*) from gha_actors_affiliations aa where aa.company_name = c.name) "+
+			"from gha_companies c order by c.name",
+	)
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	affs = make(map[string]int)
+	var (
+		name string
+		n    int
+	)
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&name, &n))
+		names = append(names, name)
+		affs[name] = n
+	}
+	lib.FatalOnError(rows.Err())
+	return
+}
+
+// companyAliases - applies company aliases to already imported affiliations
+// or reports likely duplicate companies (mode "duplicates")
+func companyAliases(mode string) {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Connect to Postgres DB
+	con := lib.PgConn(&ctx)
+	defer func() { lib.FatalOnError(con.Close()) }()
+
+	// To handle GDPR
+	maybeHide := lib.MaybeHideFunc(lib.GetHidden(lib.HideCfgFile))
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
+	// Read company aliases
+	aliases, err := lib.ReadCompanyAliases(&ctx, dataPrefix+ctx.CompanyAliasesYaml)
+	lib.FatalOnError(err)
+	aliasesMap, err := aliases.Map()
+	lib.FatalOnError(err)
+
+	switch mode {
+	case "apply":
+		lib.SyncCompanyAliases(con, &ctx, aliasesMap, maybeHide)
+		updated := lib.ApplyCompanyAliases(con, &ctx, aliasesMap, maybeHide)
+		lib.Printf("%d companies, %d aliases, updated %d affiliations\n", len(aliases.Companies), len(aliasesMap), updated)
+	case "duplicates":
+		names, affs := getCompanies(con, &ctx)
+		dups := lib.FindCompanyDuplicates(names, aliasesMap)
+		for _, dup := range dups {
+			items := []string{}
+			for _, name := range dup {
+				items = append(items, fmt.Sprintf("'%s' (%d)", name, affs[name]))
+			}
+			lib.Printf("%s\n", strings.Join(items, ", "))
+		}
+		lib.Printf("%d companies, %d groups of likely duplicates\n", len(names), len(dups))
+	default:
+		lib.Fatalf("unknown mode: %s", mode)
+	}
+}
+
+func main() {
+	dtStart := time.Now()
+	mode := "apply"
+	if len(os.Args) > 1 {
+		mode = os.Args[1]
+	}
+	if mode != "apply" && mode != "duplicates" {
+		lib.Printf("Arguments: [apply|duplicates], default apply\n")
+		os.Exit(1)
+	}
+	companyAliases(mode)
+	dtEnd := time.Now()
+	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	lib "devstats"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// contributorReport - contributor data from all projects databases
+type contributorReport struct {
+	Login      string          `json:"login"`
+	Hidden     bool            `json:"hidden"`
+	Names      []string        `json:"names"`
+	Emails     []string        `json:"emails"`
+	FirstEvent *time.Time      `json:"firstEvent"`
+	LastEvent  *time.Time      `json:"lastEvent"`
+	Events     int             `json:"events"`
+	Projects   []projectReport `json:"projects"`
+	Timestamp  time.Time       `json:"timestamp"`
+}
+
+// projectReport - contributor data from a single project database
+type projectReport struct {
+	Project      string              `json:"project"`
+	Database     string              `json:"database"`
+	Error        string              `json:"error,omitempty"`
+	Affiliations []affiliationReport `json:"affiliations"`
+	Emails       []string            `json:"emails"`
+	FirstEvent   *time.Time          `json:"firstEvent"`
+	LastEvent    *time.Time          `json:"lastEvent"`
+	Events       int                 `json:"events"`
+	EventTypes   []eventTypeReport   `json:"eventTypes"`
+}
+
+// affiliationReport - single affiliation
+type affiliationReport struct {
+	Company    string    `json:"company"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Source     string    `json:"source"`
+	Confidence float64   `json:"confidence"`
+}
+
+// eventTypeReport - number of events of a given type
+type eventTypeReport struct {
+	Type       string    `json:"type"`
+	Count      int       `json:"count"`
+	FirstEvent time.Time `json:"firstEvent"`
+	LastEvent  time.Time `json:"lastEvent"`
+}
+
+// queryProject - returns contributor data from a single project database, login is already hidden when needed
+// Database errors (for example database not present on this host) are reported in the Error field
+func queryProject(ctx *lib.Ctx, project, db, login string) (rep projectReport, names []string) {
+	rep = projectReport{Project: project, Database: db, Affiliations: []affiliationReport{}, Emails: []string{}, EventTypes: []eventTypeReport{}}
+	con := lib.PgConnDB(ctx, db)
+	defer func() { lib.FatalOnError(con.Close()) }()
+
+	// query - runs query for a given login and calls scan for each row, sets rep.Error and returns false on error
+	query := func(sqlQuery string, scan func(rows *sql.Rows) error) bool {
+		rows, err := lib.QuerySQL(con, ctx, sqlQuery, login)
+		if err == nil {
+			for rows.Next() && err == nil {
+				err = scan(rows)
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+			errClose := rows.Close()
+			if err == nil {
+				err = errClose
+			}
+		}
+		if err != nil {
+			rep.Error = err.Error()
+			return false
+		}
+		return true
+	}
+
+	// Names
+	ok := query(
+		"select distinct coalesce(name, '') from gha_actors where lower(login) = lower("+lib.NValue(1)+") order by 1",
+		func(rows *sql.Rows) error {
+			var name string
+			err := rows.Scan(&name)
+			if err == nil && name != "" {
+				names = append(names, name)
+			}
+			return err
+		},
+	)
+	if !ok {
+		return
+	}
+
+	// Emails, hidden (GDPR) emails are stored anonymized
+	ok = query(
+		"select distinct ae.email from gha_actors_emails ae, gha_actors a "+
+			"where ae.actor_id = a.id and lower(a.login) = lower("+lib.NValue(1)+") order by 1",
+		func(rows *sql.Rows) error {
+			var email string
+			err := rows.Scan(&email)
+			if err == nil {
+				rep.Emails = append(rep.Emails, email)
+			}
+			return err
+		},
+	)
+	if !ok {
+		return
+	}
+
+	// Affiliations over time
+	ok = query(
+		"select distinct aa.company_name, aa.dt_from, aa.dt_to, aa.source, aa.confidence "+
+			"from gha_actors_affiliations aa, gha_actors a "+
+			"where aa.actor_id = a.id and lower(a.login) = lower("+lib.NValue(1)+") "+
+			"order by aa.dt_from, aa.company_name",
+		func(rows *sql.Rows) error {
+			var aff affiliationReport
+			err := rows.Scan(&aff.Company, &aff.From, &aff.To, &aff.Source, &aff.Confidence)
+			if err == nil {
+				rep.Affiliations = append(rep.Affiliations, aff)
+			}
+			return err
+		},
+	)
+	if !ok {
+		return
+	}
+
+	// Activity per event type
+	query(
+		"select type, count(*), min(created_at), max(created_at) from gha_events "+
+			"where lower(dup_actor_login) = lower("+lib.NValue(1)+") group by type order by type",
+		func(rows *sql.Rows) error {
+			var et eventTypeReport
+			err := rows.Scan(&et.Type, &et.Count, &et.FirstEvent, &et.LastEvent)
+			if err == nil {
+				rep.EventTypes = append(rep.EventTypes, et)
+				rep.Events += et.Count
+				rep.FirstEvent = minTime(rep.FirstEvent, et.FirstEvent)
+				rep.LastEvent = maxTime(rep.LastEvent, et.LastEvent)
+			}
+			return err
+		},
+	)
+	return
+}
+
+// minTime - returns earlier of the two times, nil means no time yet
+func minTime(curr *time.Time, t time.Time) *time.Time {
+	if curr == nil || t.Before(*curr) {
+		return &t
+	}
+	return curr
+}
+
+// maxTime - returns later of the two times, nil means no time yet
+func maxTime(curr *time.Time, t time.Time) *time.Time {
+	if curr == nil || t.After(*curr) {
+		return &t
+	}
+	return curr
+}
+
+// ymd - returns date or "-" when there is no date
+func ymd(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return lib.ToYMDDate(*t)
+}
+
+// markdown - returns report in Markdown format
+func (rep *contributorReport) markdown() string {
+	lines := []string{fmt.Sprintf("# Contributor report: %s", rep.Login), ""}
+	if rep.Hidden {
+		lines = append(lines, "Login is hidden (GDPR), data is stored anonymized.", "")
+	}
+	lines = append(
+		lines,
+		fmt.Sprintf("- Names: %s", strings.Join(rep.Names, ", ")),
+		fmt.Sprintf("- Emails: %s", strings.Join(rep.Emails, ", ")),
+		fmt.Sprintf("- Activity: %d events, first: %s, last: %s", rep.Events, ymd(rep.FirstEvent), ymd(rep.LastEvent)),
+		fmt.Sprintf("- Generated: %s", lib.ToYMDHMSDate(rep.Timestamp)),
+	)
+	for _, proj := range rep.Projects {
+		lines = append(lines, "", fmt.Sprintf("## %s", proj.Project), "")
+		if proj.Error != "" {
+			lines = append(lines, fmt.Sprintf("Error: %s", proj.Error))
+			continue
+		}
+		lines = append(
+			lines,
+			fmt.Sprintf("- Emails: %s", strings.Join(proj.Emails, ", ")),
+			fmt.Sprintf("- Activity: %d events, first: %s, last: %s", proj.Events, ymd(proj.FirstEvent), ymd(proj.LastEvent)),
+		)
+		if len(proj.Affiliations) > 0 {
+			lines = append(lines, "", "| Company | From | To | Source | Confidence |", "| --- | --- | --- | --- | --- |")
+			for _, aff := range proj.Affiliations {
+				lines = append(
+					lines,
+					fmt.Sprintf(
+						"| %s | %s | %s | %s | %.2f |",
+						aff.Company, lib.ToYMDDate(aff.From), lib.ToYMDDate(aff.To), aff.Source, aff.Confidence,
+					),
+				)
+			}
+		}
+		if len(proj.EventTypes) > 0 {
+			lines = append(lines, "", "| Event type | Count | First | Last |", "| --- | --- | --- | --- |")
+			for _, et := range proj.EventTypes {
+				lines = append(
+					lines,
+					fmt.Sprintf("| %s | %d | %s | %s |", et.Type, et.Count, lib.ToYMDDate(et.FirstEvent), lib.ToYMDDate(et.LastEvent)),
+				)
+			}
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// contributorReportData - generates contributor report for a given login from all projects databases
+func contributorReportData(login, format, outFile string) {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
+	// Read defined projects
+	data, err := lib.ReadFile(&ctx, dataPrefix+ctx.ProjectsYaml)
+	lib.FatalOnError(err)
+	var projects lib.AllProjects
+	lib.FatalOnError(yaml.Unmarshal(data, &projects))
+	names, projs := lib.GetProjectsList(&ctx, &projects)
+
+	// Databases store hidden (GDPR) logins
+	maybeHide := lib.MaybeHideFunc(lib.GetHidden(lib.HideCfgFile))
+	hlogin := maybeHide(login)
+	rep := contributorReport{Login: login, Hidden: hlogin != login, Names: []string{}, Emails: []string{}}
+
+	// Query all projects databases
+	thrN := lib.GetThreadsNum(&ctx)
+	type result struct {
+		index int
+		rep   projectReport
+		names []string
+	}
+	results := make([]result, len(names))
+	ch := make(chan result)
+	nThreads := 0
+	for i, name := range names {
+		go func(ch chan result, i int, name, db string) {
+			prep, pnames := queryProject(&ctx, name, db, hlogin)
+			ch <- result{index: i, rep: prep, names: pnames}
+		}(ch, i, name, projs[i].PDB)
+		nThreads++
+		if nThreads == thrN {
+			res := <-ch
+			results[res.index] = res
+			nThreads--
+		}
+	}
+	for nThreads > 0 {
+		res := <-ch
+		results[res.index] = res
+		nThreads--
+	}
+
+	// Summary from all projects
+	allNames := make(map[string]struct{})
+	allEmails := make(map[string]struct{})
+	for _, res := range results {
+		prep := res.rep
+		rep.Projects = append(rep.Projects, prep)
+		for _, name := range res.names {
+			allNames[name] = struct{}{}
+		}
+		for _, email := range prep.Emails {
+			allEmails[email] = struct{}{}
+		}
+		rep.Events += prep.Events
+		if prep.FirstEvent != nil {
+			rep.FirstEvent = minTime(rep.FirstEvent, *prep.FirstEvent)
+		}
+		if prep.LastEvent != nil {
+			rep.LastEvent = maxTime(rep.LastEvent, *prep.LastEvent)
+		}
+	}
+	for name := range allNames {
+		rep.Names = append(rep.Names, name)
+	}
+	sort.Strings(rep.Names)
+	for email := range allEmails {
+		rep.Emails = append(rep.Emails, email)
+	}
+	sort.Strings(rep.Emails)
+	rep.Timestamp = time.Now()
+
+	// Output
+	var out []byte
+	if format == "md" {
+		out = []byte(rep.markdown())
+	} else {
+		jsonBytes, err := json.Marshal(rep)
+		lib.FatalOnError(err)
+		out = lib.PrettyPrintJSON(jsonBytes)
+	}
+	if outFile == "" {
+		_, err = os.Stdout.Write(out)
+		lib.FatalOnError(err)
+		return
+	}
+	lib.FatalOnError(ioutil.WriteFile(outFile, out, 0644))
+	lib.Printf("Contributor report for %s (%d projects, %d events) written to %s\n", login, len(rep.Projects), rep.Events, outFile)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		lib.Printf("Required argument: login [json|md] [output_file], default format is json, default output is stdout\n")
+		os.Exit(1)
+	}
+	format := "json"
+	if len(os.Args) > 2 {
+		format = os.Args[2]
+	}
+	if format != "json" && format != "md" {
+		lib.Printf("Unknown format: %s, allowed: json, md\n", format)
+		os.Exit(1)
+	}
+	outFile := ""
+	if len(os.Args) > 3 {
+		outFile = os.Args[3]
+	}
+	contributorReportData(os.Args[1], format, outFile)
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
@@ -336,32 +335,34 @@ func processCommitsDB(ch chan dbCommits, ctx *lib.Ctx, db, filesSkipPattern, que
 	ch <- commits
 }
 
-// getCommitFiles get given commit's list of files and saves it in the database
-func getCommitFiles(ch chan int, ctx *lib.Ctx, con *sql.DB, filesSkipPattern *regexp.Regexp, repo, sha string) {
-	// Local or cron mode?
-	cmdPrefix := ""
-	if ctx.Local {
-		cmdPrefix = lib.LocalGitScripts
+// splitShas splits commits list into up to n chunks of (almost) equal size
+func splitShas(shas []string, n int) (chunks [][]string) {
+	if n < 1 {
+		n = 1
 	}
-
-	// Get files using shell script that does 'chdir'
-	// We cannot chdir because this is a multithreaded app
-	// And all threads share CWD (current working directory)
-	if ctx.Debug > 1 {
-		lib.Printf("Getting files for commit %s:%s\n", repo, sha)
+	size := (len(shas) + n - 1) / n
+	for size > 0 && len(shas) > 0 {
+		if size > len(shas) {
+			size = len(shas)
+		}
+		chunks = append(chunks, shas[:size])
+		shas = shas[size:]
 	}
-	dtStart := time.Now()
-	rwd := ctx.ReposDir + repo
-	filesStr, err := lib.ExecCommand(
-		ctx,
-		[]string{cmdPrefix + "git_files.sh", rwd, sha},
-		map[string]string{"GIT_TERMINAL_PROMPT": "0"},
-	)
-	dtEnd := time.Now()
-	if err != nil {
-		if ctx.Debug > 1 {
-			lib.Printf("Warning git_files.sh failed: %s:%s (took %v): %+v\n", repo, sha, dtEnd.Sub(dtStart), err)
-			fmt.Fprintf(os.Stderr, "Warning git_files.sh failed: %s:%s (took %v): %+v\n", repo, sha, dtEnd.Sub(dtStart), err)
+	return
+}
+
+// getCommitsFiles gets given repo commits list of files and saves them in the database
+// Uses a single in-process git repository access for all commits, sends number of commits with each status
+// When getting commit's files fails, git repository access is restarted (git process output can be left unread),
+// if that fails remaining commits are not processed (and not marked as skipped), so the next run retries them
+// In backfill mode (ctx.BackfillLines) commit's files already stored are replaced and failed commits are not marked as skipped
+func getCommitsFiles(ch chan map[int]int, ctx *lib.Ctx, con *sql.DB, filesSkipPattern *regexp.Regexp, repo string, shas []string) {
+	statuses := make(map[int]int)
+	// Commits that cannot be processed are marked as skipped not to process them again
+	skip := func(sha string) {
+		if ctx.BackfillLines {
+			statuses[-1]++
+			return
 		}
 		lib.ExecSQLWithErr(
 			con,
@@ -369,86 +370,106 @@ func getCommitFiles(ch chan int, ctx *lib.Ctx, con *sql.DB, filesSkipPattern *re
 			lib.InsertIgnore("into gha_skip_commits(sha, dt) "+lib.NValues(2)),
 			lib.AnyArray{sha, time.Now()}...,
 		)
-		ch <- -1
+		statuses[-1]++
+	}
+	rwd := ctx.ReposDir + repo
+	gitRepo, err := lib.NewGitRepo(rwd)
+	if err != nil {
+		if ctx.Debug > 1 {
+			lib.Printf("Warning cannot open git repo %s: %+v\n", repo, err)
+			fmt.Fprintf(os.Stderr, "Warning cannot open git repo %s: %+v\n", repo, err)
+		}
+		for _, sha := range shas {
+			skip(sha)
+		}
+		ch <- statuses
 		return
 	}
-	files := strings.Split(filesStr, "\n")
-	nFiles := 0
-	var commitDate time.Time
-
-	// Insert files in transaction: all or none
-	tx, err := con.Begin()
-	lib.FatalOnError(err)
-	for i, data := range files {
-		if i == 0 {
-			unixTimeStamp, err := strconv.ParseInt(data, 10, 64)
+	closeRepo := func() {
+		err := gitRepo.Close()
+		if err != nil && ctx.Debug > 1 {
+			lib.Printf("Warning closing git repo %s: %+v\n", repo, err)
+		}
+	}
+	defer func() {
+		if gitRepo != nil {
+			closeRepo()
+		}
+	}()
+	for i, sha := range shas {
+		if ctx.Debug > 1 {
+			lib.Printf("Getting files for commit %s:%s\n", repo, sha)
+		}
+		dtStart := time.Now()
+		// Files matching exclude pattern (vendored files) are skipped by CommitFiles, so their lines are not even counted
+		commitDate, files, err := gitRepo.CommitFiles(sha, filesSkipPattern)
+		dtEnd := time.Now()
+		if err != nil {
+			if ctx.Debug > 1 {
+				lib.Printf("Warning getting files failed: %s:%s (took %v): %+v\n", repo, sha, dtEnd.Sub(dtStart), err)
+				fmt.Fprintf(os.Stderr, "Warning getting files failed: %s:%s (took %v): %+v\n", repo, sha, dtEnd.Sub(dtStart), err)
+			}
+			skip(sha)
+			closeRepo()
+			gitRepo, err = lib.NewGitRepo(rwd)
 			if err != nil {
-				lib.Printf("Invalid time returned for repo: %s, sha: %s: '%s'\n", repo, sha, data)
+				gitRepo = nil
+				lib.Printf("Warning cannot reopen git repo %s, %d commits left for the next run: %+v\n", repo, len(shas)-i-1, err)
+				fmt.Fprintf(os.Stderr, "Warning cannot reopen git repo %s, %d commits left for the next run: %+v\n", repo, len(shas)-i-1, err)
+				break
 			}
-			lib.FatalOnError(err)
-			commitDate = time.Unix(unixTimeStamp, 0)
-			// fmt.Printf("unixTimeStamp: %v, commitDate: %v\n", unixTimeStamp, commitDate)
-			continue
-		}
-		fileData := strings.TrimSpace(data)
-		if fileData == "" {
 			continue
 		}
-		// Use '♂♀' separator to avoid any character that can appear inside file name
-		fileDataAry := strings.Split(fileData, "♂♀")
-		if len(fileDataAry) != 2 {
-			lib.Fatalf("invalid fileData returned for repo: %s, sha: %s: '%s'", repo, sha, fileData)
+		nFiles := 0
+
+		// Insert files in transaction: all or none
+		tx, err := con.Begin()
+		lib.FatalOnError(err)
+		if ctx.BackfillLines {
+			lib.ExecSQLTxWithErr(tx, ctx, "delete from gha_commits_files where sha = "+lib.NValue(1), sha)
 		}
-		fileName := fileDataAry[0]
-		// If file matches exclude pattern, skip it
-		if fileName == "" || (filesSkipPattern != nil && filesSkipPattern.MatchString(fileName)) {
+		for _, file := range files {
+			if file.Path == "" {
+				continue
+			}
+			lib.ExecSQLTxWithErr(
+				tx,
+				ctx,
+				lib.InsertIgnore("into gha_commits_files(sha, dt, path, size, added, removed) "+lib.NValues(6)),
+				lib.AnyArray{sha, commitDate, file.Path, file.Size, file.Added, file.Removed}...,
+			)
+			nFiles++
+		}
+		// Some commits have no files (for example only renames)
+		// Mark them as skipped not to process again
+		if nFiles == 0 {
+			lib.ExecSQLTxWithErr(
+				tx,
+				ctx,
+				lib.InsertIgnore("into gha_skip_commits(sha, dt) "+lib.NValues(2)),
+				lib.AnyArray{sha, time.Now()}...,
+			)
+			// Commit transaction
+			lib.FatalOnError(tx.Commit())
+			statuses[0]++
 			continue
 		}
-		// fileSize can be:
-		// > 0 - normal file size
-		// 0 - file created - no contenets
-		// -1 - file referenced in the commit SHA but not found in this commit (means deleted)
-		// -2 - file size returned as "-" from git ls-tree - means some special file, directory
-		fileSize, err := strconv.ParseInt(fileDataAry[1], 10, 64)
-		if err != nil {
-			fileSize = -2
-		}
-		// fmt.Printf("repo: %v, sha: %v, fileData: %v, fileDataAry: %v, fileName: %v, fileSize: %v\n", repo, sha, fileData, fileDataAry, fileName, fileSize)
-		lib.ExecSQLTxWithErr(
-			tx,
-			ctx,
-			lib.InsertIgnore("into gha_commits_files(sha, dt, path, size) "+lib.NValues(4)),
-			lib.AnyArray{sha, commitDate, fileName, fileSize}...,
-		)
-		nFiles++
-	}
-	// Some commits have no files (for example only renames)
-	// Mark them as skipped not to process again
-	if nFiles == 0 {
-		lib.ExecSQLTxWithErr(
-			tx,
-			ctx,
-			lib.InsertIgnore("into gha_skip_commits(sha, dt) "+lib.NValues(2)),
-			lib.AnyArray{sha, time.Now()}...,
-		)
 		// Commit transaction
 		lib.FatalOnError(tx.Commit())
-		ch <- 0
-		return
-	}
-	// Commit transaction
-	lib.FatalOnError(tx.Commit())
-	if ctx.Debug > 1 {
-		lib.Printf("Got %s:%s commit: %d files: took %v\n", repo, sha, nFiles, dtEnd.Sub(dtStart))
+		if ctx.Debug > 1 {
+			lib.Printf("Got %s:%s commit: %d files: took %v\n", repo, sha, nFiles, dtEnd.Sub(dtStart))
+		}
+		statuses[1]++
 	}
-	ch <- 1
+	ch <- statuses
 }
 
-// postprocessCommitsDB - calls given SQL on a given database
+// postprocessCommitsDB - calls given SQLs on a given database
 // to postprocess just created commit SHAs-files connections
-func postprocessCommitsDB(ch chan int, ctx *lib.Ctx, con *sql.DB, query string) {
-	_, err := con.Query(query)
-	lib.FatalOnError(err)
+func postprocessCommitsDB(ch chan int, ctx *lib.Ctx, con *sql.DB, queries []string) {
+	for _, query := range queries {
+		lib.ExecSQLWithErr(con, ctx, query)
+	}
 	// Close connection
 	lib.FatalOnError(con.Close())
 	ch <- 1
@@ -459,14 +480,19 @@ func postprocessCommitsDB(ch chan int, ctx *lib.Ctx, con *sql.DB, query string)
 // It is multithreaded processing up to NCPU databases at the same time
 func processCommits(ctx *lib.Ctx, dbs map[string]string) {
 	// Read SQL to get commits to sync from 'util_sql/list_unprocessed_commits.sql' file.
+	// In backfill mode commits stored without lines are listed using 'util_sql/list_commits_without_lines.sql' file.
 	// Local or cron mode?
 	dataPrefix := lib.DataDir
 	if ctx.Local {
 		dataPrefix = "./"
 	}
+	listSQL := "util_sql/list_unprocessed_commits.sql"
+	if ctx.BackfillLines {
+		listSQL = "util_sql/list_commits_without_lines.sql"
+	}
 	bytes, err := lib.ReadFile(
 		ctx,
-		dataPrefix+"util_sql/list_unprocessed_commits.sql",
+		dataPrefix+listSQL,
 	)
 	lib.FatalOnError(err)
 	sqlQuery := string(bytes)
@@ -494,14 +520,6 @@ func processCommits(ctx *lib.Ctx, dbs map[string]string) {
 	dtEnd := time.Now()
 	lib.Printf("Got new commits list: took %v\n", dtEnd.Sub(dtStart))
 
-	// Set non-fatal exec mode, we want to run sync for next project(s) if current fails
-	// Also set quite mode, many git-pulls or git-clones can fail and this is not needed to log it to DB
-	// User can set higher debug level and run manually to debug this
-	// Also set capture command's stdout mode
-	ctx.ExecFatal = false
-	ctx.ExecQuiet = true
-	ctx.ExecOutput = true
-
 	// Create final 'commits - file list' associations
 	dtStart = time.Now()
 	lastTime := dtStart
@@ -519,9 +537,18 @@ func processCommits(ctx *lib.Ctx, dbs map[string]string) {
 	for _, commits := range allCommits {
 		allN += len(commits.shas)
 	}
-	// process all commits
-	ch := make(chan int)
+	// process all commits, commits of each repo are split into up to thrN chunks
+	// each chunk is processed by a separate thread using its own git repository access
+	ch := make(chan map[int]int)
 	nThreads = 0
+	join := func(info string) {
+		for status, n := range <-ch {
+			statuses[status] += n
+			checked += n
+		}
+		nThreads--
+		lib.ProgressInfo(checked, allN, dtStart, &lastTime, time.Duration(10)*time.Second, info)
+	}
 	for _, commits := range allCommits {
 		con := commits.con
 		filesSkipPattern := commits.filesSkipPattern
@@ -529,23 +556,27 @@ func processCommits(ctx *lib.Ctx, dbs map[string]string) {
 		if filesSkipPattern != "" {
 			re = regexp.MustCompile(filesSkipPattern)
 		}
+		repos := []string{}
+		repoShas := make(map[string][]string)
 		for i, sha := range commits.shas {
 			repo := commits.repos[i]
-			go getCommitFiles(ch, ctx, con, re, repo, sha)
-			nThreads++
-			if nThreads == thrN {
-				statuses[<-ch]++
-				nThreads--
-				checked++
-				lib.ProgressInfo(checked, allN, dtStart, &lastTime, time.Duration(10)*time.Second, repo)
+			if _, ok := repoShas[repo]; !ok {
+				repos = append(repos, repo)
+			}
+			repoShas[repo] = append(repoShas[repo], sha)
+		}
+		for _, repo := range repos {
+			for _, shas := range splitShas(repoShas[repo], thrN) {
+				go getCommitsFiles(ch, ctx, con, re, repo, shas)
+				nThreads++
+				if nThreads == thrN {
+					join(repo)
+				}
 			}
 		}
 	}
 	for nThreads > 0 {
-		statuses[<-ch]++
-		nThreads--
-		checked++
-		lib.ProgressInfo(checked, allN, dtStart, &lastTime, time.Duration(10)*time.Second, "final join...")
+		join("final join...")
 	}
 	dtEnd = time.Now()
 	all := statuses[-1] + statuses[0] + statuses[1]
@@ -554,11 +585,12 @@ func processCommits(ctx *lib.Ctx, dbs map[string]string) {
 		perc = float64(statuses[1]) * 100.0 / (float64(all))
 	}
 	lib.Printf(
-		"Got %d (%.2f%%) new commit's files, %d without files, %d failed, all %d, took %v\n",
+		"Got %d (%.2f%%) new commit's files, %d without files, %d failed, %d left for the next run, all %d, took %v\n",
 		statuses[1],
 		perc,
 		statuses[0],
 		statuses[-1],
+		allN-all,
 		all,
 		dtEnd.Sub(dtStart),
 	)
@@ -567,26 +599,31 @@ func processCommits(ctx *lib.Ctx, dbs map[string]string) {
 	// This SQL updates 'gha_events_commits_files' table that
 	// holds connections between commits SHA and events that refer to it
 	// So we can query for files modified in the given events (via commits)
+	// In backfill mode 'util_sql/update_events_commits_lines.sql' then sets lines of already existing connections
 	dtStart = time.Now()
-	bytes, err = lib.ReadFile(
-		ctx,
-		dataPrefix+"util_sql/create_events_commits.sql",
-	)
-	lib.FatalOnError(err)
-	sqlQuery = string(bytes)
-	ch = make(chan int)
+	sqlFiles := []string{"util_sql/create_events_commits.sql"}
+	if ctx.BackfillLines {
+		sqlFiles = append(sqlFiles, "util_sql/update_events_commits_lines.sql")
+	}
+	queries := []string{}
+	for _, sqlFile := range sqlFiles {
+		bytes, err = lib.ReadFile(ctx, dataPrefix+sqlFile)
+		lib.FatalOnError(err)
+		queries = append(queries, string(bytes))
+	}
+	chP := make(chan int)
 	nThreads = 0
 	for _, commits := range allCommits {
 		con := commits.con
-		go postprocessCommitsDB(ch, ctx, con, sqlQuery)
+		go postprocessCommitsDB(chP, ctx, con, queries)
 		nThreads++
 		if nThreads == thrN {
-			<-ch
+			<-chP
 			nThreads--
 		}
 	}
 	for nThreads > 0 {
-		<-ch
+		<-chP
 		nThreads--
 	}
 	dtEnd = time.Now()
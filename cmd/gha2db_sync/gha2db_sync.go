@@ -32,6 +32,7 @@ type metric struct {
 	EscapeValueName   bool   `yaml:"escape_value_name"`
 	AnnotationsRanges bool   `yaml:"annotations_ranges"`
 	MergeSeries       string `yaml:"merge_series"`
+	InferredAffs      bool   `yaml:"inferred_affs"`
 }
 
 // Add _period to all array items
@@ -255,6 +256,21 @@ func sync(ctx *lib.Ctx, args []string) {
 			}
 		}
 
+		// Link logins of the same person: renamed accounts and new commits emails, {{person:...}} in metrics uses them
+		lib.Printf("Update identities\n")
+		merged := lib.UpdateIdentities(con, ctx)
+		lib.Printf("Identities updated, %d persons use multiple logins\n", merged)
+
+		// Detect bots, {{exclude_bots}} in metrics uses them
+		// Also detect them when they were never detected yet (database upgraded, gha_bots is missing or empty)
+		if ctx.ResetTSDB || time.Now().Hour() == 0 || !lib.BotsDetected(con, ctx) {
+			lib.Printf("Detect bots\n")
+			_, err = lib.ExecCommand(ctx, []string{cmdPrefix + "bots"}, nil)
+			lib.FatalOnError(err)
+		} else {
+			lib.Printf("Skipping `bots` detection, it is only computed once per day\n")
+		}
+
 		// Eventual postprocess SQL's from 'structure' call
 		lib.Printf("Update structure\n")
 		// Recompute views and DB summaries
@@ -351,6 +367,9 @@ func sync(ctx *lib.Ctx, args []string) {
 			if metric.MergeSeries != "" {
 				extraParams = append(extraParams, "merge_series:"+metric.MergeSeries)
 			}
+			if metric.InferredAffs {
+				extraParams = append(extraParams, "inferred_affs")
+			}
 			periods := strings.Split(metric.Periods, ",")
 			aggregate := metric.Aggregate
 			if aggregate == "" {
@@ -32,6 +32,14 @@ func syncEvents(ctx *lib.Ctx) {
 	c := lib.PgConn(ctx)
 	defer func() { lib.FatalOnError(c.Close()) }()
 
+	// Save GitHub API usage stats (calls, retries, waits, remaining points) when done
+	defer lib.WriteGHAPIStats(c, ctx, "ghapi2db")
+
+	// Take repositories metadata snapshots first, archived repos are skipped below
+	if !ctx.SkipReposMetadata {
+		lib.SyncReposMetadata(gctx, gc, ctx, c)
+	}
+
 	// Get list of repositories to process
 	recentReposDt := lib.GetDateAgo(c, ctx, lib.HourStart(time.Now()), ctx.RecentReposRange)
 	reposA, rids := lib.GetRecentRepos(c, ctx, recentReposDt)
@@ -201,27 +209,18 @@ func syncEvents(ctx *lib.Ctx) {
 			for {
 				got := false
 				for tr := 0; tr < ctx.MaxGHAPIRetry; tr++ {
-					_, rem, waitPeriod := lib.GetRateLimits(gctx, gc, true)
-					if ctx.Debug > 1 {
-						lib.Printf("Issues Repo Events Try: %d, rem: %v, waitPeriod: %v\n", tr, rem, waitPeriod)
+					rem, waitPeriod, status := lib.WaitForRateLimit(gctx, gc, ctx, "Issues.ListRepositoryEvents", tr)
+					if status == lib.Retry {
+						continue
 					}
-					if rem <= ctx.MinGHAPIPoints {
-						if waitPeriod.Seconds() <= float64(ctx.MaxGHAPIWaitSeconds) {
-							if ctx.Debug > 0 {
-								lib.Printf("API limit reached while getting events data, waiting %v (%d)\n", waitPeriod, tr)
-							}
-							time.Sleep(time.Duration(1) * time.Second)
-							time.Sleep(waitPeriod)
-							continue
+					if status == lib.RateLimit {
+						if ctx.GHAPIErrorIsFatal {
+							lib.Fatalf("API limit reached while getting issues events data, aborting, don't want to wait %v", waitPeriod)
+							os.Exit(1)
 						} else {
-							if ctx.GHAPIErrorIsFatal {
-								lib.Fatalf("API limit reached while getting issues events data, aborting, don't want to wait %v", waitPeriod)
-								os.Exit(1)
-							} else {
-								lib.Printf("Error: API limit reached while getting issues events data, aborting, don't want to wait %v", waitPeriod)
-								ch <- false
-								return
-							}
+							lib.Printf("Error: API limit reached while getting issues events data, aborting, don't want to wait %v", waitPeriod)
+							ch <- false
+							return
 						}
 					}
 					nPages++
@@ -247,6 +246,7 @@ func syncEvents(ctx *lib.Ctx) {
 								}
 							}
 							thrMutex.Unlock()
+							lib.RecordGHAPIWait("Issues.ListRepositoryEvents", wait)
 							time.Sleep(wait)
 						}
 						if res == lib.NotFound {
@@ -414,27 +414,18 @@ func syncEvents(ctx *lib.Ctx) {
 							prNum := *issue.Number
 							got = false
 							for tr := 0; tr < ctx.MaxGHAPIRetry; tr++ {
-								_, rem, waitPeriod := lib.GetRateLimits(gctx, gc, true)
-								if ctx.Debug > 1 {
-									lib.Printf("Get PR Try: %d, rem: %v, waitPeriod: %v\n", tr, rem, waitPeriod)
+								rem, waitPeriod, status := lib.WaitForRateLimit(gctx, gc, ctx, "PullRequests.Get", tr)
+								if status == lib.Retry {
+									continue
 								}
-								if rem <= ctx.MinGHAPIPoints {
-									if waitPeriod.Seconds() <= float64(ctx.MaxGHAPIWaitSeconds) {
-										if ctx.Debug > 0 {
-											lib.Printf("API limit reached while getting PR data, waiting %v (%d)\n", waitPeriod, tr)
-										}
-										time.Sleep(time.Duration(1) * time.Second)
-										time.Sleep(waitPeriod)
-										continue
+								if status == lib.RateLimit {
+									if ctx.GHAPIErrorIsFatal {
+										lib.Fatalf("API limit reached while getting PR data, aborting, don't want to wait %v", waitPeriod)
+										os.Exit(1)
 									} else {
-										if ctx.GHAPIErrorIsFatal {
-											lib.Fatalf("API limit reached while getting PR data, aborting, don't want to wait %v", waitPeriod)
-											os.Exit(1)
-										} else {
-											lib.Printf("Error: API limit reached while getting PR data, aborting, don't want to wait %v", waitPeriod)
-											ch <- false
-											return
-										}
+										lib.Printf("Error: API limit reached while getting PR data, aborting, don't want to wait %v", waitPeriod)
+										ch <- false
+										return
 									}
 								}
 								if ctx.Debug > 1 {
@@ -456,6 +447,7 @@ func syncEvents(ctx *lib.Ctx) {
 											}
 										}
 										thrMutex.Unlock()
+										lib.RecordGHAPIWait("PullRequests.Get", wait)
 										time.Sleep(wait)
 									}
 									continue
@@ -531,6 +523,11 @@ func syncEvents(ctx *lib.Ctx) {
 	// Do final corrections
 	// manual sync: false
 	lib.SyncIssuesState(gctx, gc, ctx, c, issues, prs, false)
+
+	// CI check runs and commit statuses of recently updated PRs (PRs data is up to date now)
+	if !ctx.SkipChecks {
+		lib.SyncChecks(gctx, gc, ctx, c)
+	}
 }
 
 func main() {
@@ -2,154 +2,49 @@ package main
 
 import (
 	"crypto/sha1"
+	"database/sql"
 	lib "devstats"
 	"encoding/csv"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	yaml "gopkg.in/yaml.v2"
 )
 
+// replaceConfig - free text table column
 type replaceConfig struct {
 	table  string
 	column string
 }
 
-func processHidden(ctx *lib.Ctx) {
-	replaces := []replaceConfig{
-		{
-			table:  "gha_actors",
-			column: "login",
-		},
-		{
-			table:  "gha_actors",
-			column: "name",
-		},
-		{
-			table:  "gha_actors_emails",
-			column: "email",
-		},
-		{
-			table:  "gha_actors_affiliations",
-			column: "company_name",
-		},
-		{
-			table:  "gha_companies",
-			column: "name",
-		},
-		{
-			table:  "gha_events",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_payloads",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_commits",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_commits",
-			column: "author_name",
-		},
-		{
-			table:  "gha_pages",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_comments",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_comments",
-			column: "dup_user_login",
-		},
-		{
-			table:  "gha_issues",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_issues",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_issues",
-			column: "dup_user_login",
-		},
-		{
-			table:  "gha_milestones",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_milestones",
-			column: "dupn_creator_login",
-		},
-		{
-			table:  "gha_issues_labels",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_forkees",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_forkees",
-			column: "dup_owner_login",
-		},
-		{
-			table:  "gha_releases",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_releases",
-			column: "dup_author_login",
-		},
-		{
-			table:  "gha_assets",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_assets",
-			column: "dup_uploader_login",
-		},
-		{
-			table:  "gha_pull_requests",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_pull_requests",
-			column: "dup_user_login",
-		},
-		{
-			table:  "gha_branches",
-			column: "dupn_forkee_name",
-		},
-		{
-			table:  "gha_branches",
-			column: "dupn_user_login",
-		},
-		{
-			table:  "gha_teams",
-			column: "dup_actor_login",
-		},
-		{
-			table:  "gha_texts",
-			column: "actor_login",
-		},
-		{
-			table:  "gha_issues_events_labels",
-			column: "actor_login",
-		},
-	}
-	configFile := lib.HideCfgFile
-	shaMap := lib.GetHidden(configFile)
+// hideTask - replaces values of a single hidden SHA1 in a single database
+// When from is empty, values with a given SHA1 are replaced, otherwise values equal to any of from (old pseudonyms) are replaced
+type hideTask struct {
+	db   string
+	sha  string
+	from []string
+	to   string
+}
+
+// auditEntry - number of rows touched in a single database table column
+type auditEntry struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	Column   string `json:"column"`
+	Action   string `json:"action"`
+	Rows     int64  `json:"rows"`
+}
 
+// getDatabases - returns projects databases to process, ordered as in projects.yaml, ONLY="proj1 proj2" limits projects
+func getDatabases(ctx *lib.Ctx) (dbs []string) {
 	dataPrefix := lib.DataDir
 	if ctx.Local {
 		dataPrefix = "./"
@@ -186,8 +81,6 @@ func processHidden(ctx *lib.Ctx) {
 		bOnly = true
 	}
 
-	tasks := [][3]string{}
-	dbs := []string{}
 	for _, order := range orders {
 		name := projectsMap[order]
 		if bOnly {
@@ -196,45 +89,568 @@ func processHidden(ctx *lib.Ctx) {
 				continue
 			}
 		}
-		proj := projects.Projects[name]
+		dbs = append(dbs, projects.Projects[name].PDB)
+	}
+	return
+}
+
+// rowsAffected - returns number of rows affected by a statement
+func rowsAffected(res sql.Result) int64 {
+	rows, err := res.RowsAffected()
+	lib.FatalOnError(err)
+	return rows
+}
+
+// hideValue - replaces hidden value (or its old pseudonyms) with pseudonym in all personal data columns (see lib.PersonalColumns)
+// Returns tables and columns where rows were updated
+func hideValue(ctx *lib.Ctx, con *sql.DB, task hideTask) (audit []auditEntry) {
+	for _, replace := range lib.PersonalColumns {
+		var res sql.Result
+		if len(task.from) == 0 {
+			res = lib.ExecSQLWithErr(
+				con,
+				ctx,
+				fmt.Sprintf(
+					"update %s set %s = %s where encode(digest(%s, 'sha1'), 'hex') = %s",
+					replace.Table,
+					replace.Column,
+					lib.NValue(1),
+					replace.Column,
+					lib.NValue(2),
+				),
+				lib.AnyArray{
+					task.to,
+					task.sha,
+				}...,
+			)
+		} else {
+			args := lib.AnyArray{task.to}
+			values := []string{}
+			for i, from := range task.from {
+				args = append(args, from)
+				values = append(values, lib.NValue(i+2))
+			}
+			res = lib.ExecSQLWithErr(
+				con,
+				ctx,
+				fmt.Sprintf(
+					"update %s set %s = %s where %s in (%s)",
+					replace.Table,
+					replace.Column,
+					lib.NValue(1),
+					replace.Column,
+					strings.Join(values, ", "),
+				),
+				args...,
+			)
+		}
+		rows := rowsAffected(res)
+		if rows > 0 {
+			audit = append(audit, auditEntry{Database: task.db, Table: replace.Table, Column: replace.Column, Action: "pseudonymize", Rows: rows})
+		}
+	}
+	return
+}
+
+// processHidden - replaces hidden data with keyed pseudonyms in all projects databases
+// In rekey mode values already hidden using legacy (unkeyed) pseudonyms or using the old key (GHA2DB_HIDE_OLD_KEY)
+// are replaced with pseudonyms using the current key (GHA2DB_HIDE_KEY), so the key is required
+func processHidden(ctx *lib.Ctx, rekey bool) {
+	configFile := lib.HideCfgFile
+	if rekey {
+		_, err := lib.GetHideKey(lib.HideKeyEnv)
+		lib.FatalOnError(err)
+	}
+	shaMap := lib.GetHidden(configFile)
+	var oldKey []byte
+	if rekey && os.Getenv(lib.HideOldKeyEnv) != "" {
+		var err error
+		oldKey, err = lib.GetHideKey(lib.HideOldKeyEnv)
+		lib.FatalOnError(err)
+	}
+
+	tasks := []hideTask{}
+	dbs := getDatabases(ctx)
+	for _, db := range dbs {
 		for sha, anon := range shaMap {
-			tasks = append(tasks, [3]string{proj.PDB, sha, anon})
+			task := hideTask{db: db, sha: sha, to: anon}
+			if rekey {
+				task.from = []string{lib.LegacyHidePseudonym(sha)}
+				if oldKey != nil {
+					task.from = append(task.from, lib.HidePseudonym(oldKey, sha))
+				}
+			}
+			tasks = append(tasks, task)
 		}
-		dbs = append(dbs, proj.PDB)
 	}
 	lib.Printf("Processing databases: %+v\n", dbs)
 	thrN := lib.GetThreadsNum(ctx)
 	ch := make(chan bool)
 	nThreads := 0
 	for _, task := range tasks {
-		go func(ch chan bool, task [3]string) {
-			con := lib.PgConnDB(ctx, task[0])
+		go func(ch chan bool, task hideTask) {
+			con := lib.PgConnDB(ctx, task.db)
+			defer func() { lib.FatalOnError(con.Close()) }()
+			for _, entry := range hideValue(ctx, con, task) {
+				lib.Printf("DB: %s, table: %s, column: %s, sha: %s, updated %d rows\n", task.db, entry.Table, entry.Column, task.sha, entry.Rows)
+			}
+			ch <- true
+		}(ch, task)
+		nThreads++
+		if nThreads == thrN {
+			<-ch
+			nThreads--
+		}
+	}
+	for nThreads > 0 {
+		<-ch
+		nThreads--
+	}
+}
+
+// eraseConfig - free text columns authored by a person, erased for that person
+// Rows are matched using author login (or email) column, deletable rows are deleted instead of redacted when GHA2DB_ERASE_DELETE is set
+type eraseConfig struct {
+	table     string
+	match     string
+	email     bool
+	texts     []string
+	deletable bool
+}
+
+// erases - all free text columns authored by a person
+var erases = []eraseConfig{
+	{
+		table:     "gha_texts",
+		match:     "actor_login",
+		texts:     []string{"body"},
+		deletable: true,
+	},
+	{
+		table:     "gha_comments",
+		match:     "dup_user_login",
+		texts:     []string{"body"},
+		deletable: true,
+	},
+	{
+		table: "gha_issues",
+		match: "dup_user_login",
+		texts: []string{"body"},
+	},
+	{
+		table: "gha_pull_requests",
+		match: "dup_user_login",
+		texts: []string{"body"},
+	},
+	{
+		table: "gha_releases",
+		match: "dup_author_login",
+		texts: []string{"body"},
+	},
+	{
+		table: "gha_commits",
+		match: "author_email",
+		email: true,
+		texts: []string{"message"},
+	},
+}
+
+// mentions - free text columns where "@login" mentions of a person are replaced with pseudonym
+var mentions = []replaceConfig{
+	{
+		table:  "gha_texts",
+		column: "body",
+	},
+	{
+		table:  "gha_comments",
+		column: "body",
+	},
+	{
+		table:  "gha_issues",
+		column: "title",
+	},
+	{
+		table:  "gha_issues",
+		column: "body",
+	},
+	{
+		table:  "gha_pull_requests",
+		column: "title",
+	},
+	{
+		table:  "gha_pull_requests",
+		column: "body",
+	},
+	{
+		table:  "gha_releases",
+		column: "body",
+	},
+	{
+		table:  "gha_commits",
+		column: "message",
+	},
+}
+
+// erasedText - text that replaces redacted free text
+const erasedText = "[erased]"
+
+// eraseReport - erasure audit report: rows touched per database, table and column
+type eraseReport struct {
+	Login     string       `json:"login"`
+	Pseudonym string       `json:"pseudonym"`
+	Mode      string       `json:"mode"`
+	Logins    int          `json:"logins"`
+	Emails    int          `json:"emails"`
+	Names     int          `json:"names"`
+	Databases []string     `json:"databases"`
+	Entries   []auditEntry `json:"entries"`
+	Rows      int64        `json:"rows"`
+	Timestamp time.Time    `json:"timestamp"`
+}
+
+// personValues - all logins (as stored), emails and names of a person: logins and emails linked in gha_identities,
+// emails from affiliations files, GitHub names and commit author names used with any of person's emails
+type personValues struct {
+	logins map[string]struct{}
+	emails map[string]struct{}
+	names  map[string]struct{}
+}
+
+// queryValues - adds all values returned by a query to a set, empty values are skipped
+func queryValues(ctx *lib.Ctx, con *sql.DB, set map[string]struct{}, query string, args ...interface{}) {
+	rows := lib.QuerySQLWithErr(con, ctx, query, args...)
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	var value string
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&value))
+		if strings.TrimSpace(value) != "" {
+			set[value] = struct{}{}
+		}
+	}
+	lib.FatalOnError(rows.Err())
+}
+
+// getPersonValues - finds values identifying a person in a single database
+func getPersonValues(ctx *lib.Ctx, db, login string, pv *personValues) {
+	con := lib.PgConnDB(ctx, db)
+	defer func() { lib.FatalOnError(con.Close()) }()
+	lowerLogins := map[string]struct{}{strings.ToLower(login): {}}
+	queryValues(
+		ctx,
+		con,
+		lowerLogins,
+		"select value from gha_identities where kind = 'login' and person_id = "+
+			"(select person_id from gha_identities where kind = 'login' and value = lower("+lib.NValue(1)+"))",
+		login,
+	)
+	queryValues(
+		ctx,
+		con,
+		pv.emails,
+		"select value from gha_identities where kind = 'email' and person_id = "+
+			"(select person_id from gha_identities where kind = 'login' and value = lower("+lib.NValue(1)+"))",
+		login,
+	)
+	for lowerLogin := range lowerLogins {
+		queryValues(ctx, con, pv.logins, "select distinct login from gha_actors where lower(login) = "+lib.NValue(1), lowerLogin)
+		queryValues(ctx, con, pv.logins, "select distinct dup_actor_login from gha_events where lower(dup_actor_login) = "+lib.NValue(1), lowerLogin)
+		queryValues(
+			ctx,
+			con,
+			pv.emails,
+			"select distinct ae.email from gha_actors_emails ae, gha_actors a where ae.actor_id = a.id and lower(a.login) = "+lib.NValue(1),
+			lowerLogin,
+		)
+		queryValues(ctx, con, pv.names, "select distinct coalesce(name, '') from gha_actors where lower(login) = "+lib.NValue(1), lowerLogin)
+	}
+	emails := []string{}
+	for email := range pv.emails {
+		emails = append(emails, email)
+	}
+	for _, email := range emails {
+		queryValues(
+			ctx,
+			con,
+			pv.emails,
+			"select distinct author_email from gha_commits where lower(author_email) = lower("+lib.NValue(1)+")",
+			email,
+		)
+		queryValues(
+			ctx,
+			con,
+			pv.names,
+			"select distinct author_name from gha_commits where lower(author_email) = lower("+lib.NValue(1)+")",
+			email,
+		)
+	}
+}
+
+// mentionPattern - returns Postgres regexp matching "@login" mentions, first group is the character before mention
+func mentionPattern(login string) string {
+	return "(^|[^[:alnum:]_/-])@" + regexp.QuoteMeta(login) + "(?=[^[:alnum:]_-]|$)"
+}
+
+// eraseTexts - redacts (or deletes) free text authored by a person and replaces person's mentions with pseudonyms
+func eraseTexts(ctx *lib.Ctx, con *sql.DB, db string, pv *personValues, anons map[string]string) (audit []auditEntry) {
+	for _, erase := range erases {
+		values := pv.logins
+		if erase.email {
+			values = pv.emails
+		}
+		for value := range values {
+			var (
+				res    sql.Result
+				action string
+			)
+			if erase.deletable && ctx.EraseDelete {
+				action = "delete"
+				res = lib.ExecSQLWithErr(
+					con,
+					ctx,
+					fmt.Sprintf("delete from %s where %s = %s", erase.table, erase.match, lib.NValue(1)),
+					value,
+				)
+			} else {
+				action = "redact"
+				sets := []string{}
+				for _, text := range erase.texts {
+					sets = append(sets, fmt.Sprintf("%s = %s", text, lib.NValue(2)))
+				}
+				res = lib.ExecSQLWithErr(
+					con,
+					ctx,
+					fmt.Sprintf("update %s set %s where %s = %s", erase.table, strings.Join(sets, ", "), erase.match, lib.NValue(1)),
+					value,
+					erasedText,
+				)
+			}
+			rows := rowsAffected(res)
+			if rows > 0 {
+				audit = append(audit, auditEntry{Database: db, Table: erase.table, Column: strings.Join(erase.texts, ","), Action: action, Rows: rows})
+			}
+		}
+	}
+	lowerLogins := make(map[string]struct{})
+	for login := range pv.logins {
+		lowerLogins[strings.ToLower(login)] = struct{}{}
+	}
+	for login := range lowerLogins {
+		for _, mention := range mentions {
+			res := lib.ExecSQLWithErr(
+				con,
+				ctx,
+				fmt.Sprintf(
+					"update %s set %s = regexp_replace(%s, %s, %s, 'gi') where %s ~* %s",
+					mention.table,
+					mention.column,
+					mention.column,
+					lib.NValue(1),
+					lib.NValue(2),
+					mention.column,
+					lib.NValue(1),
+				),
+				mentionPattern(login),
+				"\\1@"+anons[login],
+			)
+			rows := rowsAffected(res)
+			if rows > 0 {
+				audit = append(audit, auditEntry{Database: db, Table: mention.table, Column: mention.column, Action: "mention", Rows: rows})
+			}
+		}
+	}
+	return
+}
+
+// sha1Hex - returns SHA1 hash of a value
+func sha1Hex(value string) string {
+	hash := sha1.New()
+	_, err := hash.Write([]byte(value))
+	lib.FatalOnError(err)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// eraseData - right to erasure: finds all logins, emails and names of a person in all projects databases,
+// redacts (or deletes) free text authored by them, replaces their mentions and all values with pseudonyms
+// and adds values to the hide config file, so future imports hide them too
+// Writes audit report with rows touched per database, table and column (JSON) to reportFile or stdout
+func eraseData(ctx *lib.Ctx, login, reportFile string) {
+	key, err := lib.GetHideKey(lib.HideKeyEnv)
+	lib.FatalOnError(err)
+	dbs := getDatabases(ctx)
+	lib.Printf("Processing databases: %+v\n", dbs)
+	thrN := lib.GetThreadsNum(ctx)
+
+	// Find all person's values, databases are processed in parallel, each one into its own set
+	all := make([]personValues, len(dbs))
+	ch := make(chan bool)
+	nThreads := 0
+	for i, db := range dbs {
+		all[i] = personValues{logins: make(map[string]struct{}), emails: make(map[string]struct{}), names: make(map[string]struct{})}
+		go func(ch chan bool, db string, pv *personValues) {
+			getPersonValues(ctx, db, login, pv)
+			ch <- true
+		}(ch, db, &all[i])
+		nThreads++
+		if nThreads == thrN {
+			<-ch
+			nThreads--
+		}
+	}
+	for nThreads > 0 {
+		<-ch
+		nThreads--
+	}
+	pv := personValues{logins: map[string]struct{}{login: {}}, emails: make(map[string]struct{}), names: make(map[string]struct{})}
+	for _, dbValues := range all {
+		for _, set := range [][2]map[string]struct{}{{pv.logins, dbValues.logins}, {pv.emails, dbValues.emails}, {pv.names, dbValues.names}} {
+			for value := range set[1] {
+				set[0][value] = struct{}{}
+			}
+		}
+	}
+
+	// Pseudonyms, lowercase logins are used for mentions
+	anons := make(map[string]string)
+	values := []string{}
+	for _, set := range []map[string]struct{}{pv.logins, pv.emails, pv.names} {
+		for value := range set {
+			values = append(values, value)
+			anons[value] = lib.HidePseudonym(key, sha1Hex(value))
+		}
+	}
+	for value := range pv.logins {
+		anons[strings.ToLower(value)] = lib.HidePseudonym(key, sha1Hex(strings.ToLower(value)))
+	}
+	sort.Strings(values)
+	hideData(values)
+
+	// Erase in all databases
+	report := eraseReport{
+		Login:     login,
+		Pseudonym: anons[login],
+		Mode:      "redact",
+		Logins:    len(pv.logins),
+		Emails:    len(pv.emails),
+		Names:     len(pv.names),
+		Databases: dbs,
+		Entries:   []auditEntry{},
+	}
+	if ctx.EraseDelete {
+		report.Mode = "delete"
+	}
+	audits := make([][]auditEntry, len(dbs))
+	for i, db := range dbs {
+		go func(ch chan bool, i int, db string) {
+			con := lib.PgConnDB(ctx, db)
 			defer func() { lib.FatalOnError(con.Close()) }()
-			for _, replace := range replaces {
-				res := lib.ExecSQLWithErr(
+			audit := eraseTexts(ctx, con, db, &pv, anons)
+			for _, value := range values {
+				audit = append(audit, hideValue(ctx, con, hideTask{db: db, sha: sha1Hex(value), to: anons[value]})...)
+			}
+			audits[i] = audit
+			ch <- true
+		}(ch, i, db)
+		nThreads++
+		if nThreads == thrN {
+			<-ch
+			nThreads--
+		}
+	}
+	for nThreads > 0 {
+		<-ch
+		nThreads--
+	}
+	for _, audit := range audits {
+		for _, entry := range audit {
+			report.Entries = append(report.Entries, entry)
+			report.Rows += entry.Rows
+		}
+	}
+	report.Timestamp = time.Now()
+
+	// Audit report
+	jsonBytes, err := json.Marshal(report)
+	lib.FatalOnError(err)
+	out := lib.PrettyPrintJSON(jsonBytes)
+	if reportFile == "" {
+		_, err = os.Stdout.Write(out)
+		lib.FatalOnError(err)
+	} else {
+		lib.FatalOnError(ioutil.WriteFile(reportFile, out, 0644))
+	}
+	lib.Printf(
+		"Erased %s (%d logins, %d emails, %d names) in %d databases: %d rows touched\n",
+		login, len(pv.logins), len(pv.emails), len(pv.names), len(dbs), report.Rows,
+	)
+}
+
+// auditDatabase - returns personal data columns still holding hidden values and unclassified login and email columns in a database
+func auditDatabase(ctx *lib.Ctx, db string, shas []string) (hidden []auditEntry, unclassified []string) {
+	con := lib.PgConnDB(ctx, db)
+	defer func() { lib.FatalOnError(con.Close()) }()
+	if len(shas) > 0 {
+		for _, col := range lib.PersonalColumns {
+			var rows int64
+			lib.FatalOnError(
+				lib.QueryRowSQL(
 					con,
 					ctx,
 					fmt.Sprintf(
-						"update %s set %s = %s where encode(digest(%s, 'sha1'), 'hex') = %s",
-						replace.table,
-						replace.column,
+						"select count(*) from %s where encode(digest(%s, 'sha1'), 'hex') = any(%s)",
+						col.Table,
+						col.Column,
 						lib.NValue(1),
-						replace.column,
-						lib.NValue(2),
 					),
-					lib.AnyArray{
-						task[2],
-						task[1],
-					}...,
-				)
-				rows, err := res.RowsAffected()
-				lib.FatalOnError(err)
-				if rows > 0 {
-					lib.Printf("DB: %s, table: %s, column: %s, sha: %s, updated %d rows\n", task[0], replace.table, replace.column, task[1], rows)
-				}
+					pq.Array(shas),
+				).Scan(&rows),
+			)
+			if rows > 0 {
+				hidden = append(hidden, auditEntry{Database: db, Table: col.Table, Column: col.Column, Action: "not hidden", Rows: rows})
 			}
+		}
+	}
+	classified := make(map[string]struct{})
+	for _, cols := range [][]lib.PersonalColumn{lib.PersonalColumns, lib.NotPersonalColumns} {
+		for _, col := range cols {
+			classified[col.Table+"."+col.Column] = struct{}{}
+		}
+	}
+	rows := lib.QuerySQLWithErr(
+		con,
+		ctx,
+		"select table_name, column_name from information_schema.columns "+
+			"where table_schema = 'public' and table_name like 'gha_%' "+
+			"and (column_name like '%login%' or column_name like '%email%') order by table_name, column_name",
+	)
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	var table, column string
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&table, &column))
+		_, ok := classified[table+"."+column]
+		if !ok {
+			unclassified = append(unclassified, table+"."+column)
+		}
+	}
+	lib.FatalOnError(rows.Err())
+	return
+}
+
+// auditHidden - checks all projects databases: reports personal data columns (see lib.PersonalColumns) still holding
+// values from the hide config file and login or email columns that are not classified as personal data or not
+func auditHidden(ctx *lib.Ctx) {
+	shas := lib.GetHiddenShas(lib.HideCfgFile)
+	dbs := getDatabases(ctx)
+	lib.Printf("Auditing databases: %+v, %d hidden values\n", dbs, len(shas))
+	thrN := lib.GetThreadsNum(ctx)
+	hidden := make([][]auditEntry, len(dbs))
+	unclassified := make([][]string, len(dbs))
+	ch := make(chan bool)
+	nThreads := 0
+	for i, db := range dbs {
+		go func(ch chan bool, i int, db string) {
+			hidden[i], unclassified[i] = auditDatabase(ctx, db, shas)
 			ch <- true
-		}(ch, task)
+		}(ch, i, db)
 		nThreads++
 		if nThreads == thrN {
 			<-ch
@@ -245,22 +661,36 @@ func processHidden(ctx *lib.Ctx) {
 		<-ch
 		nThreads--
 	}
+	problems := 0
+	for i, db := range dbs {
+		for _, entry := range hidden[i] {
+			lib.Printf("DB: %s, table: %s, column: %s: %d rows with hidden values, run hide_data\n", db, entry.Table, entry.Column, entry.Rows)
+			problems++
+		}
+		for _, col := range unclassified[i] {
+			lib.Printf("DB: %s, column: %s is not classified, add it to PersonalColumns or NotPersonalColumns in structure.go\n", db, col)
+			problems++
+		}
+	}
+	lib.Printf("Audited %d databases: %d problems found\n", len(dbs), problems)
 }
 
+// hideData - adds SHA1 hashes of given values to the hide config file, pseudonymization key is not needed
 func hideData(args []string) {
-	shaMap := lib.GetHidden(lib.HideCfgFile)
+	shaMap := make(map[string]struct{})
+	for _, sha := range lib.GetHiddenShas(lib.HideCfgFile) {
+		shaMap[sha] = struct{}{}
+	}
 	added := false
 	for _, argo := range args {
 		arg := strings.TrimSpace(argo)
-		hash := sha1.New()
-		hash.Write([]byte(arg))
-		sha := hex.EncodeToString(hash.Sum(nil))
+		sha := sha1Hex(arg)
 		_, ok := shaMap[sha]
 		if ok {
 			lib.Printf("Skipping '%s', SHA1 '%s' - already added\n", arg, sha)
 			continue
 		}
-		shaMap[sha] = ""
+		shaMap[sha] = struct{}{}
 		added = true
 	}
 	if !added {
@@ -285,7 +715,21 @@ func main() {
 	dtStart := time.Now()
 	ctx.Init()
 	if len(os.Args) < 2 {
-		processHidden(&ctx)
+		processHidden(&ctx, false)
+	} else if os.Args[1] == "--rekey" {
+		processHidden(&ctx, true)
+	} else if os.Args[1] == "--audit" {
+		auditHidden(&ctx)
+	} else if os.Args[1] == "--erase" {
+		if len(os.Args) < 3 {
+			lib.Printf("Required arguments: --erase login [report_file], default report output is stdout\n")
+			os.Exit(1)
+		}
+		reportFile := ""
+		if len(os.Args) > 3 {
+			reportFile = os.Args[3]
+		}
+		eraseData(&ctx, os.Args[2], reportFile)
 	} else {
 		hideData(os.Args[1:])
 	}
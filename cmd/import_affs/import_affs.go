@@ -2,7 +2,6 @@ package main
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"os"
 	"regexp"
@@ -12,17 +11,6 @@ import (
 	lib "devstats"
 )
 
-// gitHubUsers - list of GitHub user data from cncf/gitdm.
-type gitHubUsers []gitHubUser
-
-// gitHubUser - single GitHug user entry from cncf/gitdm `github_users.json` JSON.
-type gitHubUser struct {
-	Login       string `json:"login"`
-	Email       string `json:"email"`
-	Affiliation string `json:"affiliation"`
-	Name        string `json:"name"`
-}
-
 // stringSet - set of strings
 type stringSet map[string]struct{}
 
@@ -32,13 +20,8 @@ type mapStringSet map[string]stringSet
 // mapIntArray - this is a map form string to array of ints
 type mapIntArray map[string][]int
 
-// affData - holds single affiliation data
-type affData struct {
-	Login   string
-	Company string
-	From    time.Time
-	To      time.Time
-}
+// mapAffsArray - this is a map from login to all its affiliations lists found in the sources
+type mapAffsArray map[string][][]lib.AffData
 
 // decode emails with ! instead of @
 func emailDecode(line string) string {
@@ -110,46 +93,156 @@ func addActor(con *sql.DB, ctx *lib.Ctx, login, name string, maybeHide func(stri
 	return aid
 }
 
-// Imports given JSON file.
-func importAffs(jsonFN string) {
+// clearActorsNames - removes names of actors whose logins are no longer present in the affiliations files
+// Names are only set from the affiliations files, GHA and GitHub API imports leave them empty
+func clearActorsNames(con *sql.DB, ctx *lib.Ctx, loginNames mapStringSet, maybeHide func(string) string) (cleared int) {
+	logins := make(stringSet)
+	for login := range loginNames {
+		logins[maybeHide(login)] = struct{}{}
+	}
+	rows := lib.QuerySQLWithErr(con, ctx, "select distinct login from gha_actors where name is not null and name != ''")
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	var (
+		login string
+		stale []string
+	)
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&login))
+		if _, ok := logins[login]; !ok {
+			stale = append(stale, login)
+		}
+	}
+	lib.FatalOnError(rows.Err())
+	for _, login := range stale {
+		lib.ExecSQLWithErr(con, ctx, "update gha_actors set name = null where login = "+lib.NValue(1), login)
+		cleared++
+	}
+	return
+}
+
+// removeStaleEmails - removes actors emails that are no longer present in the affiliations files
+// actorsEmails holds all (hidden) emails imported now for each actor ID
+func removeStaleEmails(con *sql.DB, ctx *lib.Ctx, actorsEmails map[int]stringSet) (removed int) {
+	rows := lib.QuerySQLWithErr(con, ctx, "select actor_id, email from gha_actors_emails")
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	type actorEmail struct {
+		aid   int
+		email string
+	}
+	var (
+		ae    actorEmail
+		stale []actorEmail
+	)
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&ae.aid, &ae.email))
+		if _, ok := actorsEmails[ae.aid][ae.email]; !ok {
+			stale = append(stale, ae)
+		}
+	}
+	lib.FatalOnError(rows.Err())
+	for _, ae := range stale {
+		lib.ExecSQLWithErr(
+			con,
+			ctx,
+			"delete from gha_actors_emails where actor_id = "+lib.NValue(1)+" and email = "+lib.NValue(2),
+			lib.AnyArray{ae.aid, ae.email}...,
+		)
+		removed++
+	}
+	return
+}
+
+// getAffiliations - returns all affiliations from the previous import(s), inferred affiliations are skipped
+// Actors with the same login and different IDs have the same affiliations, so they are returned once per login
+func getAffiliations(con *sql.DB, ctx *lib.Ctx) (affs []lib.AffData) {
+	rows := lib.QuerySQLWithErr(
+		con,
+		ctx,
+		"select distinct a.login, aa.company_name, aa.dt_from, aa.dt_to "+
+			"from gha_actors_affiliations aa, gha_actors a where aa.actor_id = a.id and aa.source = "+lib.NValue(1),
+		lib.AffsSourceFile,
+	)
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	var aff lib.AffData
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&aff.Login, &aff.Company, &aff.From, &aff.To))
+		affs = append(affs, aff)
+	}
+	lib.FatalOnError(rows.Err())
+	return
+}
+
+// addAffHistory - records affiliation added or removed by the import
+func addAffHistory(con *sql.DB, ctx *lib.Ctx, dt time.Time, action string, aff *lib.AffData, sourceHash string) {
+	lib.ExecSQLWithErr(con, ctx,
+		lib.InsertIgnore(
+			"into gha_affiliations_history(dt, action, login, company_name, dt_from, dt_to, source_hash) "+lib.NValues(7),
+		),
+		lib.AnyArray{dt, action, aff.Login, aff.Company, aff.From, aff.To, sourceHash}...,
+	)
+}
+
+// Imports given affiliations files, file format is detected by extension (see lib.AffsSources):
+// .json - cncf/gitdm github_users.json, .txt - cncf/gitdm developers_affiliations*.txt, .csv - login, company, from, to, .yaml/.yml
+// Import is incremental: affiliations added or removed since the previous import are recorded in gha_affiliations_history
+// together with the source file(s) hash. Emails and names no longer present in the source file(s) are removed.
+// Affiliations with conflicting date ranges are reported and not imported unless forced (GHA2DB_AFFS_FORCE).
+func importAffs(fileNames []string) {
 	// Environment context parse
 	var ctx lib.Ctx
 	ctx.Init()
 
-	// Connect to Postgres DB
-	con := lib.PgConn(&ctx)
-	defer func() { lib.FatalOnError(con.Close()) }()
-
 	// To handle GDPR
 	maybeHide := lib.MaybeHideFunc(lib.GetHidden(lib.HideCfgFile))
 
-	// Parse github_users.json
-	var users gitHubUsers
-	data, err := lib.ReadFile(&ctx, jsonFN)
-	if err != nil {
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
+	// Company aliases, all affiliations use canonical company names
+	aliases, err := lib.ReadCompanyAliases(&ctx, dataPrefix+ctx.CompanyAliasesYaml)
+	lib.FatalOnError(err)
+	aliasesMap, err := aliases.Map()
+	lib.FatalOnError(err)
+
+	// Parse all sources
+	var (
+		users []lib.AffsUser
+		data  []byte
+	)
+	for _, fileName := range fileNames {
+		source, err := lib.GetAffsSource(fileName)
 		lib.FatalOnError(err)
-		return
+		fileData, err := lib.ReadFile(&ctx, fileName)
+		lib.FatalOnError(err)
+		fileUsers, err := source.Users(fileData)
+		if err != nil {
+			lib.Fatalf("%s: %v", fileName, err)
+		}
+		lib.Printf("%s: %d users\n", fileName, len(fileUsers))
+		users = append(users, fileUsers...)
+		data = append(data, fileData...)
 	}
-	lib.FatalOnError(json.Unmarshal(data, &users))
 
 	// Process users affiliations
 	emptyVal := struct{}{}
 	loginEmails := make(mapStringSet)
 	loginNames := make(mapStringSet)
-	loginAffs := make(mapStringSet)
+	loginAffs := make(mapAffsArray)
 	eNames, eEmails, eAffs := 0, 0, 0
 	for _, user := range users {
-		// Email decode ! --> @
-		user.Email = emailDecode(user.Email)
 		login := user.Login
-		// Email
-		email := user.Email
-		if email != "" {
+		// Emails, decode ! --> @
+		if len(user.Emails) > 0 {
 			_, ok := loginEmails[login]
 			if !ok {
 				loginEmails[login] = stringSet{}
 			}
-			loginEmails[login][email] = emptyVal
+			for _, email := range user.Emails {
+				loginEmails[login][emailDecode(email)] = emptyVal
+			}
 		} else {
 			eEmails++
 		}
@@ -166,14 +259,9 @@ func importAffs(jsonFN string) {
 			eNames++
 		}
 
-		// Affiliation
-		aff := user.Affiliation
-		if aff != "NotFound" && aff != "(Unknown)" && aff != "?" {
-			_, ok := loginAffs[login]
-			if !ok {
-				loginAffs[login] = stringSet{}
-			}
-			loginAffs[login][aff] = emptyVal
+		// Affiliations
+		if len(user.Affiliations) > 0 {
+			loginAffs[login] = append(loginAffs[login], user.Affiliations)
 		} else {
 			eAffs++
 		}
@@ -184,6 +272,62 @@ func importAffs(jsonFN string) {
 	)
 	lib.Printf("Empty/Not found: names: %d, emails: %d, affiliations: %d\n", eNames, eEmails, eAffs)
 
+	// Login - Affiliation should be 1:1, but it is sometimes 1:2 or 1:3
+	// There are some ambigous affiliations in the sources
+	// For such cases we're picking up the one with most entries
+	// And then if more than 1 with the same number of entries, then pick up first
+	unique, nonUnique, allAffs := 0, 0, 0
+	companies := make(stringSet)
+	var affList []lib.AffData
+	for _, affsLists := range loginAffs {
+		affs := affsLists[0]
+		distinct := false
+		for _, list := range affsLists[1:] {
+			if fmt.Sprintf("%v", list) != fmt.Sprintf("%v", affs) {
+				distinct = true
+			}
+			if len(list) > len(affs) {
+				affs = list
+			}
+		}
+		if distinct {
+			// Count this as non-unique
+			nonUnique++
+		} else {
+			// This is a good definition, only one list of companies affiliation for this GitHub user login
+			unique++
+		}
+		for _, aff := range affs {
+			aff.Company = aliasesMap.Canonical(aff.Company)
+			companies[aff.Company] = emptyVal
+			affList = append(affList, aff)
+			allAffs++
+		}
+	}
+	lib.Printf(
+		"%d affiliations, unique: %d, non-unique: %d, all user-company connections: %d\n",
+		len(loginAffs), unique, nonUnique, allAffs,
+	)
+
+	// Validate affiliations date ranges, conflicting ranges would double count activity
+	conflicts := lib.CheckAffiliations(affList)
+	if len(conflicts) > 0 {
+		lib.Printf("Affiliations conflicts:\n%s", lib.AffsConflictsReport(conflicts))
+	} else {
+		lib.Printf("No affiliations conflicts found\n")
+	}
+	if ctx.AffsCheck {
+		return
+	}
+	if len(conflicts) > 0 && !ctx.AffsForce {
+		lib.Fatalf("%d affiliations conflicts found, fix them or set GHA2DB_AFFS_FORCE=1 to import anyway", len(conflicts))
+	}
+
+	// Connect to Postgres DB
+	con := lib.PgConn(&ctx)
+	defer func() { lib.FatalOnError(con.Close()) }()
+	lib.SyncCompanyAliases(con, &ctx, aliasesMap, maybeHide)
+
 	// Login - Names should be 1:1
 	added, updated := 0, 0
 	for login, names := range loginNames {
@@ -211,8 +355,13 @@ func importAffs(jsonFN string) {
 	}
 	lib.Printf("%d non-empty names, added actors: %d, updated actors: %d\n", len(loginNames), added, updated)
 
+	// Names no longer present in the affiliations files
+	cleared := clearActorsNames(con, &ctx, loginNames, maybeHide)
+	lib.Printf("Cleared %d actors names\n", cleared)
+
 	// Login - Email(s) 1:N
 	cacheActIDs := make(mapIntArray)
+	actorsEmails := make(map[int]stringSet)
 	added, allEmails := 0, 0
 	for login, emails := range loginEmails {
 		actIDs := findActorIDs(con, &ctx, login, maybeHide)
@@ -235,77 +384,40 @@ func importAffs(jsonFN string) {
 					lib.InsertIgnore("into gha_actors_emails(actor_id, email) "+lib.NValues(2)),
 					lib.AnyArray{aid, maybeHide(email)}...,
 				)
-				allEmails++
-			}
-		}
-	}
-	lib.Printf("%d emails lists, added actors: %d, all emails: %d\n", len(loginEmails), added, allEmails)
-
-	// Login - Affiliation should be 1:1, but it is sometimes 1:2 or 1:3
-	// There are some ambigous affiliations in github_users.json
-	// For such cases we're picking up the one with most entries
-	// And then if more than 1 with the same number of entries, then pick up first
-	unique, nonUnique, allAffs := 0, 0, 0
-	defaultStartDate := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
-	defaultEndDate := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
-	companies := make(stringSet)
-	var affList []affData
-	for login, affs := range loginAffs {
-		var affsAry []string
-		if len(affs) > 1 {
-			// This login has different affiliations definitions in the input JSON
-			// Look for an affiliation that list most companies
-			maxNum := 1
-			for aff := range affs {
-				num := len(strings.Split(aff, ", "))
-				if num > maxNum {
-					maxNum = num
-				}
-			}
-			// maxNum holds max number of companies listed in any of affiliations
-			for aff := range affs {
-				ary := strings.Split(aff, ", ")
-				// Just pick first affiliation defin ition that lists most companies
-				if len(ary) == maxNum {
-					affsAry = ary
-					break
+				_, ok := actorsEmails[aid]
+				if !ok {
+					actorsEmails[aid] = stringSet{}
 				}
+				actorsEmails[aid][maybeHide(email)] = emptyVal
+				allEmails++
 			}
-			// Count this as non-unique
-			nonUnique++
-		} else {
-			// This is a good definition, only one list of companies affiliation for this GitHub user login
-			affsAry = strings.Split(firstKey(affs), ", ")
-			unique++
-		}
-		// Affiliation has a form "com1 < dt1, com2 < dt2, ..., com(N-1) < dt(N-1), comN"
-		// We have array of companies affiliation with eventual end date: array item is:
-		// "company name" or "company name < date", lets iterate and parse it
-		prevDate := defaultStartDate
-		for _, aff := range affsAry {
-			var dtFrom, dtTo time.Time
-			ary := strings.Split(aff, " < ")
-			company := strings.TrimSpace(ary[0])
-			if len(ary) > 1 {
-				// "company < date" form
-				dtFrom = prevDate
-				dtTo = lib.TimeParseAny(ary[1])
-			} else {
-				// "company" form
-				dtFrom = prevDate
-				dtTo = defaultEndDate
-			}
-			companies[company] = emptyVal
-			affList = append(affList, affData{Login: login, Company: company, From: dtFrom, To: dtTo})
-			prevDate = dtTo
-			allAffs++
 		}
 	}
+	removed := removeStaleEmails(con, &ctx, actorsEmails)
 	lib.Printf(
-		"%d affiliations, unique: %d, non-unique: %d, all user-company connections: %d\n",
-		len(loginAffs), unique, nonUnique, allAffs,
+		"%d emails lists, added actors: %d, all emails: %d, removed emails: %d\n",
+		len(loginEmails), added, allEmails, removed,
 	)
 
+	// Diff against the previous import, DB holds hidden (GDPR) logins and company names
+	prevAffs := getAffiliations(con, &ctx)
+	origLogins := make(map[string]string)
+	var currAffs []lib.AffData
+	for _, aff := range affList {
+		hlogin := maybeHide(aff.Login)
+		origLogins[hlogin] = aff.Login
+		currAffs = append(
+			currAffs,
+			lib.AffData{Login: hlogin, Company: maybeHide(aff.Company), From: aff.From, To: aff.To},
+		)
+	}
+	diff := lib.DiffAffiliations(prevAffs, currAffs)
+	sourceHash := lib.AffsSourceHash(data)
+	dt := time.Now()
+
+	// Inferred affiliations are recalculated after import, actors can be added to affiliations files meanwhile
+	lib.ExecSQLWithErr(con, &ctx, "delete from gha_actors_affiliations where source = "+lib.NValue(1), lib.AffsSourceEmailDomain)
+
 	// Add companies
 	for company := range companies {
 		lib.ExecSQLWithErr(con, &ctx,
@@ -315,10 +427,25 @@ func importAffs(jsonFN string) {
 	}
 	lib.Printf("Processed %d companies\n", len(companies))
 
-	// Add affiliations
+	// Remove affiliations that are no longer present
+	for _, aff := range diff.Removed {
+		lib.ExecSQLWithErr(con, &ctx,
+			"delete from gha_actors_affiliations where actor_id in (select id from gha_actors where login = "+lib.NValue(1)+
+				") and company_name = "+lib.NValue(2)+" and dt_from = "+lib.NValue(3)+" and dt_to = "+lib.NValue(4)+
+				" and source = "+lib.NValue(5),
+			lib.AnyArray{aff.Login, aff.Company, aff.From, aff.To, lib.AffsSourceFile}...,
+		)
+		addAffHistory(con, &ctx, dt, "removed", &aff, sourceHash)
+		if ctx.Debug > 0 {
+			lib.Printf("Removed: %s: %s [%s - %s]\n", aff.Login, aff.Company, lib.ToYMDDate(aff.From), lib.ToYMDDate(aff.To))
+		}
+	}
+
+	// Insert all current affiliations, not only added ones: login can have new actor IDs since the previous import
+	// (pre-2015 negative ID and a new ID or a re-created actor), unchanged affiliation must be set for them too
 	added, cached, nonCached := 0, 0, 0
-	for _, aff := range affList {
-		login := aff.Login
+	for _, aff := range currAffs {
+		login := origLogins[aff.Login]
 		// Check if we have that actor IDs cached
 		actIDs, ok := cacheActIDs[login]
 		if !ok {
@@ -334,30 +461,50 @@ func importAffs(jsonFN string) {
 		} else {
 			cached++
 		}
-		company := aff.Company
-		dtFrom := aff.From
-		dtTo := aff.To
 		for _, aid := range actIDs {
 			lib.ExecSQLWithErr(con, &ctx,
 				lib.InsertIgnore(
 					"into gha_actors_affiliations(actor_id, company_name, dt_from, dt_to) "+lib.NValues(4)),
-				lib.AnyArray{aid, maybeHide(company), dtFrom, dtTo}...,
+				lib.AnyArray{aid, aff.Company, aff.From, aff.To}...,
 			)
 		}
 	}
+	for _, aff := range diff.Added {
+		addAffHistory(con, &ctx, dt, "added", &aff, sourceHash)
+		if ctx.Debug > 0 {
+			lib.Printf("Added: %s: %s [%s - %s]\n", aff.Login, aff.Company, lib.ToYMDDate(aff.From), lib.ToYMDDate(aff.To))
+		}
+	}
 	lib.Printf(
 		"Processed %d affiliations, added %d actors, cache hit: %d, miss: %d\n",
 		len(affList), added, cached, nonCached,
 	)
+
+	// Infer affiliations of actors still without affiliation from their email domains
+	inferred := lib.InferAffiliations(con, &ctx, aliases.DomainsMap(), maybeHide)
+	lib.Printf("Inferred %d affiliations from email domains\n", inferred)
+
+	// Link logins of the same person using emails from affiliations files and commits
+	merged := lib.UpdateIdentities(con, &ctx)
+	lib.Printf("Identities updated, %d persons use multiple logins\n", merged)
+
+	// Remove companies that have no affiliations anymore
+	lib.ExecSQLWithErr(con, &ctx,
+		"delete from gha_companies where name not in (select distinct company_name from gha_actors_affiliations)",
+	)
+
+	// Summary report
+	summary := diff.Summary()
+	lib.Printf("Source %s (%s), changes summary:\n%s", strings.Join(fileNames, ", "), sourceHash, summary.Report())
 }
 
 func main() {
 	dtStart := time.Now()
 	if len(os.Args) < 2 {
-		lib.Printf("Required argument: filename.json\n")
+		lib.Printf("Required argument(s): filename.json|filename.txt|filename.csv|filename.yaml [...]\n")
 		os.Exit(1)
 	}
-	importAffs(os.Args[1])
+	importAffs(os.Args[1:])
 	dtEnd := time.Now()
 	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
 }
@@ -39,6 +39,10 @@ func mergePDBs() {
 		}
 	}()
 
+	// Values from hide config file are hidden in personal data columns (input databases can be hidden using older hide config)
+	maybeHide := lib.MaybeHideFunc(lib.GetHidden(lib.HideCfgFile))
+	personalColumns := lib.PersonalColumnsMap()
+
 	// Connect to the output Postgres DB
 	co := lib.PgConnDB(&ctx, ctx.OutputDB)
 	// Defer close output connection
@@ -126,8 +130,13 @@ func mergePDBs() {
 				// Vals to hold any type as []interface{}
 				nColumns := len(columns)
 				vals := make([]interface{}, nColumns)
-				for i := range columns {
+				personal := []int{}
+				for i, column := range columns {
 					vals[i] = new(interface{})
+					_, ok := personalColumns[table][column]
+					if ok {
+						personal = append(personal, i)
+					}
 				}
 
 				// Get results into `results` array of maps
@@ -139,6 +148,15 @@ func mergePDBs() {
 				lastTime := dtStart
 				for rows.Next() {
 					lib.FatalOnError(rows.Scan(vals...))
+					for _, i := range personal {
+						val := vals[i].(*interface{})
+						switch value := (*val).(type) {
+						case string:
+							*val = maybeHide(value)
+						case []byte:
+							*val = maybeHide(string(value))
+						}
+					}
 					_, err := lib.ExecSQL(
 						co,
 						&ctx,
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"math"
 	"os"
@@ -12,45 +14,37 @@ import (
 	lib "devstats"
 
 	"github.com/google/go-github/github"
+	yaml "gopkg.in/yaml.v2"
 )
 
-// Sync issues state given by query from GHA2DB_ISSUES_SYNC_SQL env
-// Possible dynamic replacements inside the query via
-// FROM1=var1 TO1=val1, FROM2=..., TO2=..., ...
-func syncIssues(ctx *lib.Ctx) {
-	// Connect to GitHub API
-	gctx, gc := lib.GHClient(ctx)
+// syncFailed - issue sync status when GitHub API calls failed
+const syncFailed = "failed"
 
-	// Connect to Postgres DB
-	c := lib.PgConn(ctx)
-	defer func() { lib.FatalOnError(c.Close()) }()
+// selectIssues - returns list of issues (repos and numbers) given by selector's SQL
+func selectIssues(c *sql.DB, ctx *lib.Ctx, sel *lib.SyncIssuesSelector, since time.Time) (repos []string, numbers []int) {
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
+	// Per project directory for SQL files
+	dir := lib.Metrics
+	if ctx.Project != "" {
+		dir += ctx.Project + "/"
+	}
 
 	// Get SQL that will return list of issue numbers to sync
 	// each issue must be full_repo_name, number
 	// For example kubernetes/kubernetes, 60172
-	sql := os.Getenv("GHA2DB_ISSUES_SYNC_SQL")
-	if sql == "" {
-		lib.Printf("You have to provide a SQL query to get a list of issue numbers to sync. Use GHA2DB_ISSUES_SYNC_SQL environment variable for this")
-		lib.Fatalf("no sync issues sql query provided")
-	}
-
-	i := 1
-	for {
-		from := os.Getenv(fmt.Sprintf("FROM%d", i))
-		to := os.Getenv(fmt.Sprintf("TO%d", i))
-		if from != "" {
-			sql = strings.Replace(sql, from, to, -1)
-			i++
-		} else {
-			break
-		}
-	}
+	bytes, err := lib.ReadFile(ctx, dataPrefix+dir+sel.SQLFile+".sql")
+	lib.FatalOnError(err)
+	sqlQuery, err := lib.PrepareSyncIssuesSQL(string(bytes), sel, since)
+	lib.FatalOnError(err)
 
 	// Execute SQL
-	rows := lib.QuerySQLWithErr(c, ctx, sql)
+	rows := lib.QuerySQLWithErr(c, ctx, sqlQuery)
 	defer func() { lib.FatalOnError(rows.Close()) }()
-	numbers := []int{}
-	repos := []string{}
 	number := 0
 	repo := ""
 	seen := make(map[string]struct{})
@@ -69,6 +63,13 @@ func syncIssues(ctx *lib.Ctx) {
 		}
 	}
 	lib.FatalOnError(rows.Err())
+	return
+}
+
+// Sync issues state for given issues
+// Returns number of issues that failed to sync because of GitHub API errors
+// Issues not found in the API (deleted or transferred) are not counted as failures
+func syncIssues(gctx context.Context, gc *github.Client, ctx *lib.Ctx, c *sql.DB, repos []string, numbers []int) (failed int) {
 	nNumbers := len(numbers)
 	lib.Printf("sync_issues.go: Processing %d issues - GHAPI part\n", nNumbers)
 
@@ -85,7 +86,7 @@ func syncIssues(ctx *lib.Ctx) {
 	}
 	allowedThrN := maxThreads
 	var thrMutex = &sync.Mutex{}
-	ch := make(chan bool)
+	ch := make(chan string)
 	nThreads := 0
 	dtStart := time.Now()
 	lastTime := dtStart
@@ -94,22 +95,25 @@ func syncIssues(ctx *lib.Ctx) {
 	var issuesMutex = &sync.Mutex{}
 	prs := make(map[int64]github.PullRequest)
 	var prsMutex = &sync.Mutex{}
+	notFound := 0
 
 	// Process issues
 	for idx := range numbers {
-		go func(ch chan bool, orgRepo string, number int) {
+		go func(ch chan string, orgRepo string, number int) {
 			artificialUID := int64(-1)
 			artificialLogin := "devstats-sync"
 			artificialEvent := &github.IssueEvent{Actor: &github.User{ID: &artificialUID, Login: &artificialLogin}}
 			ary := strings.Split(orgRepo, "/")
 			if len(ary) < 2 {
-				ch <- false
+				lib.Printf("Warning: invalid repository name: '%s'\n", orgRepo)
+				ch <- lib.NotFound
 				return
 			}
 			org := ary[0]
 			repo := ary[1]
 			if org == "" || repo == "" {
-				ch <- false
+				lib.Printf("Warning: invalid repository name: '%s'\n", orgRepo)
+				ch <- lib.NotFound
 				return
 			}
 			gcfg := lib.IssueConfig{
@@ -122,20 +126,13 @@ func syncIssues(ctx *lib.Ctx) {
 			)
 			got := false
 			for tr := 0; tr < ctx.MaxGHAPIRetry; tr++ {
-				_, rem, waitPeriod := lib.GetRateLimits(gctx, gc, true)
-				if ctx.Debug > 1 {
-					lib.Printf("Get Issue Try: %d, rem: %v, waitPeriod: %v\n", tr, rem, waitPeriod)
+				rem, waitPeriod, status := lib.WaitForRateLimit(gctx, gc, ctx, "Issues.Get", tr)
+				if status == lib.Retry {
+					continue
 				}
-				if rem <= ctx.MinGHAPIPoints {
-					if waitPeriod.Seconds() <= float64(ctx.MaxGHAPIWaitSeconds) {
-						lib.Printf("API limit reached while getting issue data, waiting %v (%d)\n", waitPeriod, tr)
-						time.Sleep(time.Duration(1) * time.Second)
-						time.Sleep(waitPeriod)
-						continue
-					} else {
-						lib.Fatalf("API limit reached while getting issue data, aborting, don't want to wait %v", waitPeriod)
-						os.Exit(1)
-					}
+				if status == lib.RateLimit {
+					lib.Fatalf("API limit reached while getting issue data, aborting, don't want to wait %v", waitPeriod)
+					os.Exit(1)
 				}
 				if ctx.Debug > 1 {
 					lib.Printf("API call for Issue %s %d, remaining GHAPI points %d\n", orgRepo, number, rem)
@@ -156,11 +153,12 @@ func syncIssues(ctx *lib.Ctx) {
 							}
 						}
 						thrMutex.Unlock()
+						lib.RecordGHAPIWait("Issues.Get", wait)
 						time.Sleep(wait)
 					}
 					if res == lib.NotFound {
-						lib.Printf("Warning: not found: %s/%s %d", org, repo, number)
-						ch <- false
+						lib.Printf("Warning: not found: %s/%s %d\n", org, repo, number)
+						ch <- lib.NotFound
 						return
 					}
 					continue
@@ -178,8 +176,9 @@ func syncIssues(ctx *lib.Ctx) {
 				break
 			}
 			if !got {
-				lib.Fatalf("GetRateLimit call failed %d times while getting issue, aborting", ctx.MaxGHAPIRetry)
-				os.Exit(2)
+				lib.Printf("Error: GitHub API call failed %d times while getting issue %s %d\n", ctx.MaxGHAPIRetry, orgRepo, number)
+				ch <- syncFailed
+				return
 			}
 			cfg := lib.IssueConfig{Repo: orgRepo}
 			if issue.Milestone != nil {
@@ -256,26 +255,24 @@ func syncIssues(ctx *lib.Ctx) {
 					prNum := *issue.Number
 					got = false
 					for tr := 0; tr < ctx.MaxGHAPIRetry; tr++ {
-						_, rem, waitPeriod := lib.GetRateLimits(gctx, gc, true)
-						if ctx.Debug > 1 {
-							lib.Printf("Get PR Try: %d, rem: %v, waitPeriod: %v\n", tr, rem, waitPeriod)
+						rem, waitPeriod, status := lib.WaitForRateLimit(gctx, gc, ctx, "PullRequests.Get", tr)
+						if status == lib.Retry {
+							continue
 						}
-						if rem <= ctx.MinGHAPIPoints {
-							if waitPeriod.Seconds() <= float64(ctx.MaxGHAPIWaitSeconds) {
-								lib.Printf("API limit reached while getting PR data, waiting %v (%d)\n", waitPeriod, tr)
-								time.Sleep(time.Duration(1) * time.Second)
-								time.Sleep(waitPeriod)
-								continue
-							} else {
-								lib.Fatalf("API limit reached while getting PR data, aborting, don't want to wait %v", waitPeriod)
-								os.Exit(1)
-							}
+						if status == lib.RateLimit {
+							lib.Fatalf("API limit reached while getting PR data, aborting, don't want to wait %v", waitPeriod)
+							os.Exit(1)
 						}
 						if ctx.Debug > 1 {
 							lib.Printf("API call for PR %s %d, remaining GHAPI points %d\n", orgRepo, prNum, rem)
 						}
 						pr, _, err = gc.PullRequests.Get(gctx, org, repo, prNum)
 						res := lib.HandlePossibleError(err, &gcfg, "PullRequests.Get")
+						if res == lib.NotFound {
+							lib.Printf("Warning: PR not found: %s %d\n", orgRepo, prNum)
+							ch <- lib.NotFound
+							return
+						}
 						if res != "" {
 							if res == lib.Abuse {
 								wait := time.Duration(int(math.Pow(2.0, float64(tr+3)))) * time.Second
@@ -290,6 +287,7 @@ func syncIssues(ctx *lib.Ctx) {
 									}
 								}
 								thrMutex.Unlock()
+								lib.RecordGHAPIWait("PullRequests.Get", wait)
 								time.Sleep(wait)
 							}
 							continue
@@ -307,8 +305,9 @@ func syncIssues(ctx *lib.Ctx) {
 						break
 					}
 					if !got {
-						lib.Fatalf("GetRateLimit call failed %d times while getting PR, aborting", ctx.MaxGHAPIRetry)
-						os.Exit(2)
+						lib.Printf("Error: GitHub API call failed %d times while getting PR %s %d\n", ctx.MaxGHAPIRetry, orgRepo, prNum)
+						ch <- syncFailed
+						return
 					}
 					if pr != nil {
 						prsMutex.Lock()
@@ -319,11 +318,11 @@ func syncIssues(ctx *lib.Ctx) {
 			}
 			/* end handle pr */
 			// Synchronize go routine
-			ch <- true
+			ch <- ""
 		}(ch, repos[idx], numbers[idx])
 		nThreads++
 		for nThreads >= allowedThrN {
-			<-ch
+			countStatus(<-ch, &failed, &notFound)
 			nThreads--
 			checked++
 			// Get RateLimits info
@@ -336,7 +335,7 @@ func syncIssues(ctx *lib.Ctx) {
 		lib.Printf("Final GHAPI threads join\n")
 	}
 	for nThreads > 0 {
-		<-ch
+		countStatus(<-ch, &failed, &notFound)
 		nThreads--
 		checked++
 		// Get RateLimits info
@@ -344,9 +343,47 @@ func syncIssues(ctx *lib.Ctx) {
 		lib.ProgressInfo(checked, nNumbers, dtStart, &lastTime, time.Duration(10)*time.Second, fmt.Sprintf("API points: %d, resets in: %v", rem, wait))
 	}
 
+	if failed > 0 || notFound > 0 {
+		lib.Printf("sync_issues.go: %d issues failed to sync, %d issues not found\n", failed, notFound)
+	}
+
 	// Do final corrections
 	// manual sync: true
 	lib.SyncIssuesState(gctx, gc, ctx, c, issues, prs, true)
+	return
+}
+
+// countStatus - counts failed and not found issues by their sync status
+func countStatus(status string, failed, notFound *int) {
+	switch status {
+	case syncFailed:
+		*failed++
+	case lib.NotFound:
+		*notFound++
+	}
+}
+
+// parseArgs - parses command line: [--force] [--selector name] ... or [--selector=name] ...
+func parseArgs(args []string) (names []string, force bool) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--force" {
+			force = true
+			continue
+		}
+		if strings.HasPrefix(arg, "--selector=") {
+			names = append(names, arg[len("--selector="):])
+			continue
+		}
+		if arg == "--selector" && i+1 < len(args) {
+			names = append(names, args[i+1])
+			i++
+			continue
+		}
+		lib.Printf("Usage: %s [--force] [--selector name] [--selector name2] ...\n", os.Args[0])
+		lib.Fatalf("unknown argument: %s", arg)
+	}
+	return
 }
 
 func main() {
@@ -354,7 +391,47 @@ func main() {
 	var ctx lib.Ctx
 	ctx.Init()
 	dtStart := time.Now()
-	syncIssues(&ctx)
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
+	// Read selectors, sync all selectors when no --selector arguments given
+	data, err := lib.ReadFile(&ctx, dataPrefix+ctx.SyncIssuesYaml)
+	lib.FatalOnError(err)
+	var allSelectors lib.SyncIssuesSelectors
+	lib.FatalOnError(yaml.Unmarshal(data, &allSelectors))
+	names, force := parseArgs(os.Args[1:])
+	selectors, err := lib.GetSyncIssuesSelectors(&allSelectors, names)
+	lib.FatalOnError(err)
+
+	// Connect to GitHub API
+	gctx, gc := lib.GHClient(&ctx)
+
+	// Connect to Postgres DB
+	c := lib.PgConn(&ctx)
+	defer func() { lib.FatalOnError(c.Close()) }()
+
+	for i := range selectors {
+		sel := &selectors[i]
+		// New watermark is a time before selecting issues, so nothing changed during sync is missed next time
+		since := lib.GetSyncIssuesWatermark(c, &ctx, sel)
+		watermark := time.Now()
+		lib.Printf("sync_issues.go: selector '%s', changes since %s\n", sel.Name, lib.ToYMDHMSDate(since))
+		repos, numbers := selectIssues(c, &ctx, sel, since)
+		// Watermark is only moved when all issues were synced, so failed ones are selected again next time
+		failed := syncIssues(gctx, gc, &ctx, c, repos, numbers)
+		if failed > 0 && !force {
+			lib.Printf("sync_issues.go: selector '%s', %d issues failed to sync, not updating watermark (use --force to update)\n", sel.Name, failed)
+			continue
+		}
+		lib.SetSyncIssuesWatermark(c, &ctx, sel, watermark)
+	}
+
+	// Save GitHub API usage stats (calls, retries, waits, remaining points)
+	lib.WriteGHAPIStats(c, &ctx, "sync_issues")
 	dtEnd := time.Now()
 	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,31 +13,12 @@ import (
 	yaml "gopkg.in/yaml.v2"
 )
 
-// vars contain list of Postgres variables to set
-type pvars struct {
-	Vars []pvar `yaml:"vars"`
-}
-
-// pvar contain each Postgres data
-type pvar struct {
-	Name     string     `yaml:"name"`
-	Type     string     `yaml:"type"`
-	Value    string     `yaml:"value"`
-	Command  []string   `yaml:"command"`
-	Replaces [][]string `yaml:"replaces"`
-	Disabled bool       `yaml:"disabled"`
-}
-
 // Insert Postgres vars
 func pdbVars() {
 	// Environment context parse
 	var ctx lib.Ctx
 	ctx.Init()
 
-	// Connect to Postgres DB
-	c := lib.PgConn(&ctx)
-	defer func() { lib.FatalOnError(c.Close()) }()
-
 	// Local or cron mode?
 	dataPrefix := lib.DataDir
 	if ctx.Local {
@@ -49,11 +31,22 @@ func pdbVars() {
 		lib.FatalOnError(err)
 		return
 	}
-	var allVars pvars
+	var allVars lib.Vars
 	lib.FatalOnError(yaml.Unmarshal(data, &allVars))
 
+	// Order vars by their dependencies
+	vars, err := lib.OrderVars(allVars.Vars)
+	lib.FatalOnError(err)
+
+	// Connect to Postgres DB
+	var c *sql.DB
+	if !ctx.SkipPDB && !ctx.VarsDryRun {
+		c = lib.PgConn(&ctx)
+		defer func() { lib.FatalOnError(c.Close()) }()
+	}
+
 	// All key name - values are stored in map
-	// So next keys can replace strings using previous key values
+	// So next keys can replace strings using values of keys they depend on
 	replaces := make(map[string]string)
 	// Also make environemnt variables available too
 	for _, e := range os.Environ() {
@@ -61,29 +54,37 @@ func pdbVars() {
 		replaces["$"+pair[0]] = pair[1]
 	}
 	// Iterate vars
-	for _, va := range allVars.Vars {
+	for _, va := range vars {
 		if ctx.Debug > 0 {
-			lib.Printf("Variable Name '%s', Value '%s', Type '%s', Command %v, Replaces %v, Disabled: %v\n", va.Name, va.Value, va.Type, va.Command, va.Replaces, va.Disabled)
-		}
-		if va.Disabled {
-			continue
+			lib.Printf("Variable Name '%s', Value '%s', Type '%s', File '%s', Command %v, Replaces %v, Depends on: %v\n", va.Name, va.Value, va.Type, va.File, va.Command, va.Replaces, va.DependsOn)
 		}
-		if va.Type == "" || va.Name == "" || (va.Value == "" && len(va.Command) == 0) {
+		if va.Type == "" || va.Name == "" || (va.Value == "" && va.File == "" && len(va.Command) == 0) {
 			lib.Printf("Incorrect variable configuration, skipping\n")
 			continue
 		}
 
-		if len(va.Command) > 0 {
-			for i := range va.Command {
-				va.Command[i] = strings.Replace(va.Command[i], "{{datadir}}", dataPrefix, -1)
-			}
-			cmdBytes, err := exec.Command(va.Command[0], va.Command[1:]...).CombinedOutput()
-			if err != nil {
-				lib.Printf("Failed command: %s %v\n", va.Command[0], va.Command[1:])
-				lib.FatalOnError(err)
-				return
+		if va.File != "" || len(va.Command) > 0 {
+			var outBytes []byte
+			if va.File != "" {
+				// Built-in file read, limited to the data directory
+				outBytes, err = lib.ReadVarFile(&ctx, dataPrefix, va.File)
+				if err != nil {
+					lib.Printf("Failed reading variable '%s' file: %s\n", va.Name, va.File)
+					lib.FatalOnError(err)
+					return
+				}
+			} else {
+				for i := range va.Command {
+					va.Command[i] = strings.Replace(va.Command[i], "{{datadir}}", dataPrefix, -1)
+				}
+				outBytes, err = exec.Command(va.Command[0], va.Command[1:]...).CombinedOutput()
+				if err != nil {
+					lib.Printf("Failed command: %s %v\n", va.Command[0], va.Command[1:])
+					lib.FatalOnError(err)
+					return
+				}
 			}
-			outString := strings.TrimSpace(string(cmdBytes))
+			outString := strings.TrimSpace(string(outBytes))
 			if outString != "" {
 				// Handle replacements using variables defined so far
 				for _, repl := range va.Replaces {
@@ -122,9 +123,14 @@ func pdbVars() {
 				}
 			}
 		}
+		if err := lib.ValidateVarValue(va.Type, va.Value); err != nil {
+			lib.Fatalf("Variable '%s': %v", va.Name, err)
+		}
 		replaces[va.Name] = va.Value
 
-		if !ctx.SkipPDB {
+		if ctx.VarsDryRun {
+			lib.Printf("%s (%s) = '%s'\n", va.Name, va.Type, va.Value)
+		} else if !ctx.SkipPDB {
 			// Start transaction
 			con, err := c.Begin()
 			lib.FatalOnError(err)
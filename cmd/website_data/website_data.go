@@ -1,219 +1,231 @@
 package main
 
 import (
+	"bytes"
 	"database/sql"
 	lib "devstats"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	yaml "gopkg.in/yaml.v2"
 )
 
-type allProjects struct {
-	Projects  []project `json:"projects"`
-	Summary   string    `json:"summary"`
-	Timestamp time.Time `json:"timestamp"`
+// websiteOutput - where generated website data is saved, selected by GHA2DB_WEBSITE_OUTPUT
+// projects is called once, stats once per project (possibly from many threads) and close at the end
+type websiteOutput interface {
+	projects(projs *lib.WebsiteProjects)
+	stats(name string, stats *lib.WebsiteProjectStats)
+	close()
 }
 
-type project struct {
-	Name         string `json:"name"`
-	Title        string `json:"title"`
-	Status       string `json:"status"`
-	Repo         string `json:"repo"`
-	DashboardURL string `json:"dashboardUrl"`
-	DBDumpURL    string `json:"dbDumpUrl"`
+// filesOutput - saves projects.json and project_name.json files in JSONsDir
+type filesOutput struct {
+	dir string
 }
 
-type projectStats struct {
-	Totals           activityTotals `json:"activityTotals"`
-	LatestVersion    string         `json:"latestVersion"`
-	OpenIssues       int            `json:"openIssues"`
-	RecentDiscussion int            `json:"recentDiscussion"`
-	Stars            int            `json:"stars"`
-	CommitGraph      commitGraph    `json:"commitGraph"`
-	Timestamp        time.Time      `json:"timestamp"`
+// combinedOutput - collects all data and saves it as a single JSON (file in JSONsDir or POST to HTTP(S) URL)
+type combinedOutput struct {
+	mtx  *sync.Mutex
+	data lib.WebsiteData
+	dir  string
+	url  string
 }
 
-type commitGraph struct {
-	Day   [24][2]int `json:"day"`
-	Week  [7][2]int  `json:"week"`
-	Month [4][2]int  `json:"month"`
+func newWebsiteOutput(ctx *lib.Ctx) websiteOutput {
+	if ctx.WebsiteOutput == lib.WebsiteOutputFiles {
+		return &filesOutput{dir: ctx.JSONsDir}
+	}
+	out := &combinedOutput{
+		mtx:  &sync.Mutex{},
+		data: lib.WebsiteData{SchemaVersion: lib.WebsiteDataVersion, Stats: make(map[string]lib.WebsiteProjectStats)},
+	}
+	if ctx.WebsiteOutput == lib.WebsiteOutputCombined {
+		out.dir = ctx.JSONsDir
+	} else {
+		out.url = ctx.WebsiteOutput
+	}
+	return out
 }
 
-type activityTotals struct {
-	Day   activityTotal `json:"day"`
-	Week  activityTotal `json:"week"`
-	Month activityTotal `json:"month"`
+func writeJSON(fn string, obj interface{}) {
+	jsonBytes, err := json.Marshal(obj)
+	lib.FatalOnError(err)
+	pretty := lib.PrettyPrintJSON(jsonBytes)
+	lib.FatalOnError(ioutil.WriteFile(fn, pretty, 0644))
 }
 
-type activityTotal struct {
-	Commits    int `json:"commits"`
-	Discussion int `json:"discussion"`
-	Stars      int `json:"stars"`
+func (o *filesOutput) projects(projs *lib.WebsiteProjects) {
+	writeJSON(o.dir+"projects.json", projs)
 }
 
-func getIntValue(con *sql.DB, ctx *lib.Ctx, sql string) (ival int) {
-	rows := lib.QuerySQLWithErr(con, ctx, sql)
-	defer func() { lib.FatalOnError(rows.Close()) }()
-	for rows.Next() {
-		lib.FatalOnError(rows.Scan(&ival))
-	}
-	lib.FatalOnError(rows.Err())
-	return
+func (o *filesOutput) stats(name string, stats *lib.WebsiteProjectStats) {
+	writeJSON(o.dir+name+".json", stats)
 }
 
-func generateJSONData(ctx *lib.Ctx, name, excludeBots, lastTagCmd, repo string, stats *projectStats) {
-	if name == lib.Kubernetes {
-		name = "gha"
-	} else if name == lib.All {
-		name = "allprj"
+func (o *filesOutput) close() {
+}
+
+func (o *combinedOutput) projects(projs *lib.WebsiteProjects) {
+	o.mtx.Lock()
+	o.data.Projects = *projs
+	o.mtx.Unlock()
+}
+
+func (o *combinedOutput) stats(name string, stats *lib.WebsiteProjectStats) {
+	o.mtx.Lock()
+	o.data.Stats[name] = *stats
+	o.mtx.Unlock()
+}
+
+func (o *combinedOutput) close() {
+	o.data.Timestamp = time.Now()
+	if o.url == "" {
+		writeJSON(o.dir+"website_data.json", o.data)
+		return
 	}
-	// Connect to Postgres DB
-	con := lib.PgConnDB(ctx, name)
-	defer func() { lib.FatalOnError(con.Close()) }()
+	jsonBytes, err := json.Marshal(o.data)
+	lib.FatalOnError(err)
+	response, err := http.Post(o.url, "application/json", bytes.NewReader(jsonBytes))
+	lib.FatalOnError(err)
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(response.Body)
+		lib.Fatalf("POST %s failed: %s: %s", o.url, response.Status, strings.TrimSpace(string(body)))
+	}
+	lib.Printf("Posted website data to %s: %s\n", o.url, response.Status)
+}
+
+// commitsGraph - commit graph and commits totals from a single query
+// Commits from the last month are put into hour (h), day (d) and week (w) buckets counted back from now,
+// grouping sets return distinct commits per each bucket and the month total
+func commitsGraph(con *sql.DB, ctx *lib.Ctx, excludeBots string, stats *lib.WebsiteProjectStats) {
 	for i := 0; i < 24; i++ {
-		to := 23 - i
-		from := to + 1
-		commits := getIntValue(
-			con,
-			ctx,
-			fmt.Sprintf(
-				"select count(distinct sha) from gha_commits "+
-					"where dup_created_at >= now() - '%d hours'::interval "+
-					"and dup_created_at < now() - '%d hours'::interval ",
-				from,
-				to,
-			)+"and (lower(dup_actor_login) "+excludeBots+")",
-		)
 		stats.CommitGraph.Day[i][0] = i
-		stats.CommitGraph.Day[i][1] = commits
 	}
 	for i := 0; i < 7; i++ {
-		to := 6 - i
-		from := to + 1
-		commits := getIntValue(
-			con,
-			ctx,
-			fmt.Sprintf(
-				"select count(distinct sha) from gha_commits "+
-					"where dup_created_at >= now() - '%d days'::interval "+
-					"and dup_created_at < now() - '%d days'::interval ",
-				from,
-				to,
-			)+"and (lower(dup_actor_login) "+excludeBots+")",
-		)
 		stats.CommitGraph.Week[i][0] = i
-		stats.CommitGraph.Week[i][1] = commits
 	}
 	for i := 0; i < 4; i++ {
-		to := 3 - i
-		from := to + 1
-		commits := getIntValue(
-			con,
-			ctx,
-			fmt.Sprintf(
-				"select count(distinct sha) from gha_commits "+
-					"where dup_created_at >= now() - '%d weeks'::interval "+
-					"and dup_created_at < now() - '%d weeks'::interval ",
-				from,
-				to,
-			)+"and (lower(dup_actor_login) "+excludeBots+")",
-		)
 		stats.CommitGraph.Month[i][0] = i
-		stats.CommitGraph.Month[i][1] = commits
 	}
-	stats.Totals.Day.Commits = stats.CommitGraph.Week[6][1]
-	stats.Totals.Week.Commits = stats.CommitGraph.Month[3][1]
-	stats.Totals.Month.Commits = getIntValue(
+	rows := lib.QuerySQLWithErr(
 		con,
 		ctx,
-		"select count(distinct sha) from gha_commits "+
-			"where dup_created_at >= now() - '1 month'::interval "+
-			"and (lower(dup_actor_login) "+excludeBots+")",
+		"select grouping(h), grouping(d), grouping(w), coalesce(h, 0), coalesce(d, 0), coalesce(w, 0), "+
+			"count(distinct sha) from (select sha, "+
+			"floor(extract(epoch from now() - dup_created_at) / 3600)::int as h, "+
+			"floor(extract(epoch from now() - dup_created_at) / 86400)::int as d, "+
+			"floor(extract(epoch from now() - dup_created_at) / 604800)::int as w "+
+			"from gha_commits where dup_created_at >= now() - '1 month'::interval "+
+			"and dup_created_at < now() "+
+			"and (lower(dup_actor_login) "+excludeBots+")) sub "+
+			"group by grouping sets ((h), (d), (w), ())",
 	)
-	stats.Totals.Day.Discussion = getIntValue(
-		con,
-		ctx,
-		"select count(distinct event_id) from gha_texts "+
-			"where created_at >= now() - '1 day'::interval "+
-			"and (lower(actor_login) "+excludeBots+")",
-	)
-	stats.Totals.Week.Discussion = getIntValue(
-		con,
-		ctx,
-		"select count(distinct event_id) from gha_texts "+
-			"where created_at >= now() - '1 week'::interval "+
-			"and (lower(actor_login) "+excludeBots+")",
-	)
-	stats.Totals.Month.Discussion = getIntValue(
-		con,
-		ctx,
-		"select count(distinct event_id) from gha_texts "+
-			"where created_at >= now() - '1 month'::interval "+
-			"and (lower(actor_login) "+excludeBots+")",
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	var gh, gd, gw, h, d, w, commits int
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&gh, &gd, &gw, &h, &d, &w, &commits))
+		switch {
+		case gh == 0:
+			if h < 24 {
+				stats.CommitGraph.Day[23-h][1] = commits
+			}
+		case gd == 0:
+			if d < 7 {
+				stats.CommitGraph.Week[6-d][1] = commits
+			}
+		case gw == 0:
+			if w < 4 {
+				stats.CommitGraph.Month[3-w][1] = commits
+			}
+		default:
+			stats.Totals.Month.Commits = commits
+		}
+	}
+	lib.FatalOnError(rows.Err())
+	stats.Totals.Day.Commits = stats.CommitGraph.Week[6][1]
+	stats.Totals.Week.Commits = stats.CommitGraph.Month[3][1]
+}
+
+// discussion - number of discussion events in the last day, week and month
+func discussion(con *sql.DB, ctx *lib.Ctx, excludeBots string, stats *lib.WebsiteProjectStats) {
+	lib.FatalOnError(
+		lib.QueryRowSQL(
+			con,
+			ctx,
+			"select count(distinct event_id) filter (where created_at >= now() - '1 day'::interval), "+
+				"count(distinct event_id) filter (where created_at >= now() - '1 week'::interval), "+
+				"count(distinct event_id) from gha_texts "+
+				"where created_at >= now() - '1 month'::interval "+
+				"and (lower(actor_login) "+excludeBots+")",
+		).Scan(&stats.Totals.Day.Discussion, &stats.Totals.Week.Discussion, &stats.Totals.Month.Discussion),
 	)
 	stats.RecentDiscussion = stats.Totals.Month.Discussion
-	stats.Totals.Day.Stars = getIntValue(
-		con,
-		ctx,
-		"select coalesce(sum(sub.diff), 0) "+
-			"from (select min(stargazers_count) as fmin, "+
-			"max(stargazers_count) - min(stargazers_count) as diff "+
-			"from gha_forkees where dup_repo_name = full_name and "+
-			"dup_created_at >= now() - '1 day'::interval "+
-			"group by dup_repo_name) sub where fmin > 0 and diff > 0",
-	)
-	stats.Totals.Week.Stars = getIntValue(
-		con,
-		ctx,
-		"select coalesce(sum(sub.diff), 0) "+
-			"from (select min(stargazers_count) as fmin, "+
-			"max(stargazers_count) - min(stargazers_count) as diff "+
-			"from gha_forkees where dup_repo_name = full_name and "+
-			"dup_created_at >= now() - '1 week'::interval "+
-			"group by dup_repo_name) sub where fmin > 0 and diff > 0",
-	)
-	stats.Totals.Month.Stars = getIntValue(
-		con,
-		ctx,
-		"select coalesce(sum(sub.diff), 0) "+
-			"from (select min(stargazers_count) as fmin, "+
-			"max(stargazers_count) - min(stargazers_count) as diff "+
-			"from gha_forkees where dup_repo_name = full_name and "+
-			"dup_created_at >= now() - '1 month'::interval "+
-			"group by dup_repo_name) sub where fmin > 0 and diff > 0",
-	)
-	stats.Totals.Month.Stars = getIntValue(
-		con,
-		ctx,
-		"select coalesce(sum(sub.diff), 0) "+
-			"from (select min(stargazers_count) as fmin, "+
-			"max(stargazers_count) - min(stargazers_count) as diff "+
-			"from gha_forkees where dup_repo_name = full_name and "+
-			"dup_created_at >= now() - '1 month'::interval "+
-			"group by dup_repo_name) sub where fmin > 0 and diff > 0",
-	)
-	stats.Stars = getIntValue(
-		con,
-		ctx,
-		"select sum(fmax) from (select max(stargazers_count) as fmax "+
-			"from gha_forkees where dup_repo_name = full_name "+
-			"and dup_created_at >= now() - '3 months'::interval "+
-			"group by dup_repo_name) sub",
+}
+
+// stars - new stars in the last day, week and month and the total number of stars (from the last 3 months forkee data)
+// For every period and repo new stars are max - min stargazers count, repos with min = 0 are skipped
+func stars(con *sql.DB, ctx *lib.Ctx, stats *lib.WebsiteProjectStats) {
+	periods := []string{"1 day", "1 week", "1 month"}
+	cols := []string{}
+	sums := []string{}
+	for i, period := range periods {
+		filter := fmt.Sprintf(" filter (where dup_created_at >= now() - '%s'::interval)", period)
+		cols = append(
+			cols,
+			fmt.Sprintf("min(stargazers_count)%s as min%d", filter, i),
+			fmt.Sprintf("max(stargazers_count)%s as max%d", filter, i),
+		)
+		sums = append(
+			sums,
+			fmt.Sprintf("coalesce(sum(max%d - min%d) filter (where min%d > 0 and max%d > min%d), 0)", i, i, i, i, i),
+		)
+	}
+	lib.FatalOnError(
+		lib.QueryRowSQL(
+			con,
+			ctx,
+			"select "+strings.Join(sums, ", ")+", coalesce(sum(fmax), 0) "+
+				"from (select "+strings.Join(cols, ", ")+", max(stargazers_count) as fmax "+
+				"from gha_forkees where dup_repo_name = full_name "+
+				"and dup_created_at >= now() - '3 months'::interval "+
+				"group by dup_repo_name) sub",
+		).Scan(&stats.Totals.Day.Stars, &stats.Totals.Week.Stars, &stats.Totals.Month.Stars, &stats.Stars),
 	)
-	stats.OpenIssues = getIntValue(
-		con,
-		ctx,
-		"select count(sub.id) from (select distinct id, "+
-			"last_value(closed_at) over update_date as closed_at "+
-			"from gha_issues where is_pull_request = false "+
-			"window update_date as (partition by id order by "+
-			"updated_at asc, event_id asc range between current row "+
-			"and unbounded following)) sub where sub.closed_at is null",
+}
+
+func generateJSONData(ctx *lib.Ctx, name, dataPrefix, lastTagCmd, repo string, stats *lib.WebsiteProjectStats) {
+	if name == lib.Kubernetes {
+		name = "gha"
+	} else if name == lib.All {
+		name = "allprj"
+	}
+	// Connect to Postgres DB
+	con := lib.PgConnDB(ctx, name)
+	defer func() { lib.FatalOnError(con.Close()) }()
+
+	// Bots exclusion partial SQL, depends on project's gha_bots table
+	excludeBots := lib.ExcludeBotsSQL(con, ctx, dataPrefix)
+	commitsGraph(con, ctx, excludeBots, stats)
+	discussion(con, ctx, excludeBots, stats)
+	stars(con, ctx, stats)
+	lib.FatalOnError(
+		lib.QueryRowSQL(
+			con,
+			ctx,
+			"select count(sub.id) from (select distinct id, "+
+				"last_value(closed_at) over update_date as closed_at "+
+				"from gha_issues where is_pull_request = false "+
+				"window update_date as (partition by id order by "+
+				"updated_at asc, event_id asc range between current row "+
+				"and unbounded following)) sub where sub.closed_at is null",
+		).Scan(&stats.OpenIssues),
 	)
 	tag := "-"
 	if repo != "" {
@@ -262,9 +274,11 @@ func generateWebsiteData() {
 	var projects lib.AllProjects
 	lib.FatalOnError(yaml.Unmarshal(data, &projects))
 
+	// Output
+	out := newWebsiteOutput(&ctx)
+
 	// Get ordered & filtered projects
-	var jprojs allProjects
-	pstats := make(map[string]projectStats)
+	jprojs := lib.WebsiteProjects{SchemaVersion: lib.WebsiteDataVersion}
 	names, projs := lib.GetProjectsList(&ctx, &projects)
 	for i, name := range names {
 		proj := projs[i]
@@ -272,7 +286,7 @@ func generateWebsiteData() {
 		if name == lib.Kubernetes {
 			dashURL = proto + "k8s." + hostname
 		}
-		jproj := project{
+		jproj := lib.WebsiteProject{
 			Name:         name,
 			Title:        proj.FullName,
 			Status:       proj.Status,
@@ -281,22 +295,18 @@ func generateWebsiteData() {
 			DBDumpURL:    prefix + proj.PDB + ".dump",
 		}
 		jprojs.Projects = append(jprojs.Projects, jproj)
-		pstats[name] = projectStats{}
 	}
 	jprojs.Summary = lib.All
-
-	// Marshal JSON
 	jprojs.Timestamp = time.Now()
-	jsonBytes, err := json.Marshal(jprojs)
-	lib.FatalOnError(err)
-	pretty := lib.PrettyPrintJSON(jsonBytes)
-	fn := ctx.JSONsDir + "projects.json"
-	lib.FatalOnError(ioutil.WriteFile(fn, pretty, 0644))
+	out.projects(&jprojs)
 
-	// Read bots exclusion partial SQL
-	bytes, err := lib.ReadFile(&ctx, dataPrefix+"util_sql/exclude_bots.sql")
-	lib.FatalOnError(err)
-	excludeBots := string(bytes)
+	// Generate and output single project stats
+	generate := func(name, repo string) {
+		stats := lib.WebsiteProjectStats{SchemaVersion: lib.WebsiteDataVersion}
+		generateJSONData(&ctx, name, dataPrefix, lastTagCmd, repo, &stats)
+		stats.Timestamp = time.Now()
+		out.stats(name, &stats)
+	}
 
 	// Get number of CPUs available
 	thrN := lib.GetThreadsNum(&ctx)
@@ -304,17 +314,11 @@ func generateWebsiteData() {
 	if thrN > 1 {
 		ch := make(chan struct{})
 		nThreads := 0
-		for name, stats := range pstats {
-			go func(ch chan struct{}, name string, stats projectStats) {
-				generateJSONData(&ctx, name, excludeBots, lastTagCmd, projects.Projects[name].MainRepo, &stats)
-				stats.Timestamp = time.Now()
-				jsonBytes, err := json.Marshal(stats)
-				lib.FatalOnError(err)
-				pretty := lib.PrettyPrintJSON(jsonBytes)
-				fn := ctx.JSONsDir + name + ".json"
-				lib.FatalOnError(ioutil.WriteFile(fn, pretty, 0644))
+		for i, name := range names {
+			go func(ch chan struct{}, name, repo string) {
+				generate(name, repo)
 				ch <- struct{}{}
-			}(ch, name, stats)
+			}(ch, name, projs[i].MainRepo)
 			nThreads++
 			if nThreads == thrN {
 				<-ch
@@ -327,15 +331,11 @@ func generateWebsiteData() {
 		}
 	} else {
 		lib.Printf("Using single threaded version\n")
-		for name, stats := range pstats {
-			generateJSONData(&ctx, name, excludeBots, lastTagCmd, projects.Projects[name].MainRepo, &stats)
-			jsonBytes, err := json.Marshal(stats)
-			lib.FatalOnError(err)
-			pretty := lib.PrettyPrintJSON(jsonBytes)
-			fn := ctx.JSONsDir + name + ".json"
-			lib.FatalOnError(ioutil.WriteFile(fn, pretty, 0644))
+		for i, name := range names {
+			generate(name, projs[i].MainRepo)
 		}
 	}
+	out.close()
 }
 
 func main() {
@@ -0,0 +1,187 @@
+package devstats
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// CompanyAliases - company aliases file: list of canonical companies and their alternative names
+type CompanyAliases struct {
+	Companies []CompanyAlias `yaml:"companies"`
+}
+
+// CompanyAlias - canonical company: canonical name and list of aliases
+// Canonical name identifies company in affiliations and metrics, for example: Name "Google", Aliases ["Google LLC", "Google Inc."]
+// Domains are company email domains, used to infer affiliations of actors missing in affiliations files
+type CompanyAlias struct {
+	Name    string   `yaml:"name"`
+	Aliases []string `yaml:"aliases"`
+	Domains []string `yaml:"domains"`
+}
+
+// CompanyAliasesMap - maps company name or alias to its canonical company
+type CompanyAliasesMap map[string]*CompanyAlias
+
+// companyLegalSuffixes - words ignored at the end of a company name when looking for duplicates
+var companyLegalSuffixes = map[string]struct{}{
+	"inc": {}, "incorporated": {}, "llc": {}, "ltd": {}, "limited": {}, "corp": {}, "corporation": {},
+	"co": {}, "company": {}, "gmbh": {}, "ag": {}, "sa": {}, "bv": {}, "plc": {}, "srl": {}, "oy": {}, "ab": {},
+}
+
+// ReadCompanyAliases - reads company aliases file, returns empty aliases when file doesn't exist
+func ReadCompanyAliases(ctx *Ctx, fileName string) (*CompanyAliases, error) {
+	aliases := &CompanyAliases{}
+	_, err := os.Stat(fileName)
+	if os.IsNotExist(err) {
+		if ctx.Debug > 0 {
+			Printf("No company aliases file: %s\n", fileName)
+		}
+		return aliases, nil
+	}
+	data, err := ReadFile(ctx, fileName)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(data, aliases)
+	if err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// Map - returns map from canonical names and aliases to canonical companies
+// Returns error when canonical company has no name or when name is used for different companies
+func (ca *CompanyAliases) Map() (CompanyAliasesMap, error) {
+	aliasesMap := make(CompanyAliasesMap)
+	for i := range ca.Companies {
+		company := &ca.Companies[i]
+		if company.Name == "" {
+			return nil, fmt.Errorf("company without name: %+v", company)
+		}
+		for _, name := range append([]string{company.Name}, company.Aliases...) {
+			prev, ok := aliasesMap[name]
+			if ok && prev != company {
+				return nil, fmt.Errorf("'%s' is used by '%s' and '%s'", name, prev.Name, company.Name)
+			}
+			aliasesMap[name] = company
+		}
+	}
+	return aliasesMap, nil
+}
+
+// Canonical - returns canonical company name for a given name (or name itself when it is not an alias)
+func (am CompanyAliasesMap) Canonical(name string) string {
+	company, ok := am[name]
+	if !ok {
+		return name
+	}
+	return company.Name
+}
+
+// CompanyFuzzyKey - returns company name key used to detect likely duplicates
+// Legal form suffixes (Inc., LLC, Ltd, ...) are removed and the rest is normalized by NormalizeName
+// "Google LLC", "Google, Inc." and "google" all have "google" key
+func CompanyFuzzyKey(name string) string {
+	words := strings.FieldsFunc(
+		strings.ToLower(name),
+		func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) },
+	)
+	for len(words) > 1 {
+		_, suffix := companyLegalSuffixes[words[len(words)-1]]
+		if !suffix {
+			break
+		}
+		words = words[:len(words)-1]
+	}
+	return NormalizeName(strings.Join(words, " "))
+}
+
+// FindCompanyDuplicates - returns groups of company names that are likely the same company
+// Names with the same CompanyFuzzyKey and not already mapped to the same canonical company are grouped
+// Groups are sorted, names in groups are sorted
+func FindCompanyDuplicates(names []string, aliasesMap CompanyAliasesMap) (dups [][]string) {
+	groups := make(map[string]map[string]struct{})
+	for _, name := range names {
+		key := CompanyFuzzyKey(name)
+		if key == "" {
+			continue
+		}
+		_, ok := groups[key]
+		if !ok {
+			groups[key] = make(map[string]struct{})
+		}
+		groups[key][name] = struct{}{}
+	}
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		canonical := make(map[string]struct{})
+		dup := []string{}
+		for name := range group {
+			canonical[aliasesMap.Canonical(name)] = struct{}{}
+			dup = append(dup, name)
+		}
+		if len(canonical) < 2 {
+			continue
+		}
+		sort.Strings(dup)
+		dups = append(dups, dup)
+	}
+	sort.Slice(dups, func(i, j int) bool { return dups[i][0] < dups[j][0] })
+	return
+}
+
+// SyncCompanyAliases - saves company aliases in gha_company_aliases table (canonical names map to themselves)
+func SyncCompanyAliases(con *sql.DB, ctx *Ctx, aliasesMap CompanyAliasesMap, maybeHide func(string) string) {
+	ExecSQLWithErr(con, ctx, "delete from gha_company_aliases")
+	for alias, company := range aliasesMap {
+		ExecSQLWithErr(
+			con,
+			ctx,
+			InsertIgnore("into gha_company_aliases(alias, company_name) "+NValues(2)),
+			AnyArray{maybeHide(alias), maybeHide(company.Name)}...,
+		)
+	}
+}
+
+// ApplyCompanyAliases - retroactively replaces company aliases with canonical names in gha_actors_affiliations and gha_companies
+// Returns number of affiliations updated
+func ApplyCompanyAliases(con *sql.DB, ctx *Ctx, aliasesMap CompanyAliasesMap, maybeHide func(string) string) (updated int) {
+	for alias, company := range aliasesMap {
+		if alias == company.Name {
+			continue
+		}
+		halias := maybeHide(alias)
+		hname := maybeHide(company.Name)
+		ExecSQLWithErr(
+			con,
+			ctx,
+			InsertIgnore(
+				"into gha_actors_affiliations(actor_id, company_name, dt_from, dt_to, source, confidence) "+
+					"select actor_id, "+NValue(1)+", dt_from, dt_to, source, confidence "+
+					"from gha_actors_affiliations where company_name = "+NValue(2),
+			),
+			hname,
+			halias,
+		)
+		res := ExecSQLWithErr(con, ctx, "delete from gha_actors_affiliations where company_name = "+NValue(1), halias)
+		rows, err := res.RowsAffected()
+		FatalOnError(err)
+		if rows > 0 {
+			ExecSQLWithErr(con, ctx, InsertIgnore("into gha_companies(name) "+NValues(1)), hname)
+			if ctx.Debug > 0 {
+				Printf("%s -> %s: %d affiliations\n", alias, company.Name, rows)
+			}
+		}
+		ExecSQLWithErr(con, ctx, "delete from gha_companies where name = "+NValue(1), halias)
+		updated += int(rows)
+	}
+	return
+}
@@ -0,0 +1,113 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestCompanyAliasesMap(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		yaml      string
+		canonical map[string]string
+		err       bool
+	}{
+		{
+			yaml: "companies:\n  - name: Google\n    aliases: [Google LLC, Google Inc.]\n" +
+				"  - name: Red Hat\n    aliases: [RedHat]\n",
+			canonical: map[string]string{
+				"Google":      "Google",
+				"Google LLC":  "Google",
+				"Google Inc.": "Google",
+				"RedHat":      "Red Hat",
+				"Red Hat":     "Red Hat",
+				"Other":       "Other",
+			},
+		},
+		{
+			yaml: "companies:\n  - name: A\n    aliases: [X]\n  - name: B\n    aliases: [X]\n",
+			err:  true,
+		},
+		{
+			yaml: "companies:\n  - aliases: [X]\n",
+			err:  true,
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		var aliases lib.CompanyAliases
+		err := yaml.Unmarshal([]byte(test.yaml), &aliases)
+		if err != nil {
+			t.Errorf("test number %d, unexpected YAML error: %v", index+1, err)
+			continue
+		}
+		aliasesMap, err := aliases.Map()
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got %+v", index+1, aliasesMap)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		for name, expected := range test.canonical {
+			got := aliasesMap.Canonical(name)
+			if got != expected {
+				t.Errorf("test number %d, '%s': expected '%s', got '%s'", index+1, name, expected, got)
+			}
+		}
+	}
+}
+
+func TestCompanyFuzzyKey(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		name, expected string
+	}{
+		{name: "Google", expected: "google"},
+		{name: "Google LLC", expected: "google"},
+		{name: "Google, Inc.", expected: "google"},
+		{name: "Red Hat Inc.", expected: "redhat"},
+		{name: "RedHat", expected: "redhat"},
+		{name: "Cisco", expected: "cisco"},
+		{name: "Cisco Systems Co. Ltd", expected: "ciscosystems"},
+		{name: "Inc.", expected: "inc"},
+		{name: "", expected: ""},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.CompanyFuzzyKey(test.name)
+		if got != test.expected {
+			t.Errorf("test number %d, '%s': expected '%s', got '%s'", index+1, test.name, test.expected, got)
+		}
+	}
+}
+
+func TestFindCompanyDuplicates(t *testing.T) {
+	aliases := lib.CompanyAliases{
+		Companies: []lib.CompanyAlias{{Name: "Google", Aliases: []string{"Google LLC"}}},
+	}
+	aliasesMap, err := aliases.Map()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	names := []string{"Google", "Google LLC", "Red Hat", "RedHat Inc.", "Google, Inc.", "Cisco", "Microsoft"}
+	expected := [][]string{{"Google", "Google LLC", "Google, Inc."}, {"Red Hat", "RedHat Inc."}}
+	got := lib.FindCompanyDuplicates(names, aliasesMap)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	// All duplicates already mapped to the same canonical company
+	got = lib.FindCompanyDuplicates([]string{"Google", "Google LLC"}, aliasesMap)
+	if len(got) != 0 {
+		t.Errorf("expected no duplicates, got %+v", got)
+	}
+}
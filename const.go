@@ -54,6 +54,18 @@ const Null string = "null"
 // HideCfgFile - common constant string
 const HideCfgFile string = "hide/hide.csv"
 
+// HideKeyFile - default hidden data pseudonymization key file, must be kept outside of the repository
+const HideKeyFile string = "/etc/gha2db/hide.key"
+
+// HideKeyEnv - environment variable with hidden data pseudonymization key (or key file name with HideKeyFilePrefix)
+const HideKeyEnv string = "GHA2DB_HIDE_KEY"
+
+// HideKeyFilePrefix - key environment variable value with this prefix is a key file name, "file:/etc/gha2db/hide.key"
+const HideKeyFilePrefix string = "file:"
+
+// HideOldKeyEnv - environment variable with the previous pseudonymization key (or key file name with HideKeyFilePrefix), used by `hide_data --rekey`
+const HideOldKeyEnv string = "GHA2DB_HIDE_OLD_KEY"
+
 // All - common constant string
 const All string = "all"
 
@@ -65,3 +77,6 @@ const Abuse string = "abuse"
 
 // NotFound - common constant string
 const NotFound string = "not_found"
+
+// RateLimit - common constant string
+const RateLimit string = "rate"
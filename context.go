@@ -45,8 +45,13 @@ type Ctx struct {
 	MetricsYaml         string          // From GHA2DB_METRICS_YAML gha2db_sync tool, set other metrics.yaml file, default is "metrics/{{project}}metrics.yaml"
 	TagsYaml            string          // From GHA2DB_TAGS_YAML tags tool, set other tags.yaml file, default is "metrics/{{project}}/tags.yaml"
 	ColumnsYaml         string          // From GHA2DB_COLUMNS_YAML tags tool, set other columns.yaml file, default is "metrics/{{project}}/columns.yaml"
+	ColumnsPrune        string          // From GHA2DB_COLUMNS_PRUNE, columns tool - "drop" or "archive" (save values in gha_archived_columns and drop) series columns no longer present in their tags, default "" - do not prune
+	ColumnsReport       string          // From GHA2DB_COLUMNS_REPORT, columns tool - save JSON drift report (series tables with missing or stale columns) to this file, default "" - only log drift
 	VarsYaml            string          // From GHA2DB_VARS_YAML db_vars tool, set other vars.yaml file, default is "metrics/{{project}}/vars.yaml"
+	VarsDryRun          bool            // From GHA2DB_VARS_DRY_RUN, vars tool - only print resolved variables values, do not write them to gha_vars, default false
+	SyncIssuesYaml      string          // From GHA2DB_SYNC_ISSUES_YAML sync_issues tool, set other sync_issues.yaml file, default is "metrics/{{project}}/sync_issues.yaml"
 	GitHubOAuth         string          // From GHA2DB_GITHUB_OAUTH ghapi2db tool, if not set reads from /etc/github/oauth file, set to "-" to force public access.
+	GitHubURL           string          // From GHA2DB_GITHUB_URL ghapi2db, sync_issues tools, GitHub API base URL, default "" which means "https://api.github.com/", can point to GitHub Enterprise or to a fake API server in tests.
 	ClearDBPeriod       string          // From GHA2DB_MAXLOGAGE gha2db_sync tool, maximum age of devstats.gha_logs entries, default "1 week"
 	Trials              []int           // From GHA2DB_TRIALS, all Postgres related tools, retry periods for "too many connections open" error
 	WebHookRoot         string          // From GHA2DB_WHROOT, webhook tool, default "/hook", must match .travis.yml notifications webhooks
@@ -69,7 +74,13 @@ type Ctx struct {
 	ProcessCommits      bool            // From GHA2DB_PROCESS_COMMITS get_repos tool, enable update/create mapping table: commit - list of file that commit refers to, default false
 	ExternalInfo        bool            // From GHA2DB_EXTERNAL_INFO get_repos tool, enable outputing data needed by external tools (cncf/gitdm), default false
 	ProjectsCommits     string          // From GHA2DB_PROJECTS_COMMITS get_repos tool, set list of projects for commits analysis instead of analysing all, default "" - means all
+	BackfillLines       bool            // From GHA2DB_BACKFILL_LINES get_repos tool, with GHA2DB_PROCESS_COMMITS: recompute files of commits stored without added/removed lines, default false
 	ProjectsYaml        string          // From GHA2DB_PROJECTS_YAML, many tools - set main projects file, default "projects.yaml"
+	CompanyAliasesYaml  string          // From GHA2DB_COMPANY_ALIASES_YAML, import_affs and company_aliases tools - set company aliases file, default "company_aliases.yaml"
+	BotsYaml            string          // From GHA2DB_BOTS_YAML, bots tool - set bots detection config file, default "bots.yaml"
+	AffsCheck           bool            // From GHA2DB_AFFS_CHECK, import_affs tool - only validate affiliations (report conflicting date ranges) without importing, default false
+	AffsForce           bool            // From GHA2DB_AFFS_FORCE, import_affs tool - import affiliations even if they have conflicting date ranges, default false
+	EraseDelete         bool            // From GHA2DB_ERASE_DELETE, hide_data tool - `--erase` deletes comments and texts authored by the erased person instead of redacting them, default false
 	ProjectsOverride    map[string]bool // From GHA2DB_PROJECTS_OVERRIDE, get_repos and ./devstats tools - for example "-pro1,+pro2" means never sync pro1 and always sync pro2 (even if disabled in `projects.yaml`).
 	ExcludeRepos        map[string]bool // From GHA2DB_EXCLUDE_REPOS, gha2db tool, default "" - comma separated list of repos to exclude, example: "theupdateframework/notary,theupdateframework/other"
 	InputDBs            []string        // From GHA2DB_INPUT_DBS, merge_dbs tool - list of input databases to merge, order matters - first one will insert on a clean DB, next will do insert ignore (to avoid constraints failure due to common data)
@@ -78,11 +89,14 @@ type Ctx struct {
 	DefaultHostname     string          // "devstats.cncf.io"
 	RecentRange         string          // From GHA2DB_RECENT_RANGE, ghapi2db tool, default '2 hours'. This is a recent period to check open issues/PR to fix their labels and milestones.
 	RecentReposRange    string          // From GHA2DB_RECENT_REPOS_RANGE, ghapi2db tool, default '1 day'. This is a recent period to check modified repositories.
+	ReposMetadataRange  string          // From GHA2DB_REPOS_METADATA_RANGE, ghapi2db tool, default '1 day'. Repositories metadata snapshot is taken when the last one is older than this.
 	MinGHAPIPoints      int             // From GHA2DB_MIN_GHAPI_POINTS, ghapi2db tool, minimum GitHub API points, before waiting for reset.
 	MaxGHAPIWaitSeconds int             // From GHA2DB_MAX_GHAPI_WAIT, ghapi2db tool, maximum wait time for GitHub API points reset (in seconds).
 	MaxGHAPIRetry       int             // From GHA2DB_MAX_GHAPI_RETRY, ghapi2db tool, maximum wait retries
 	GHAPIErrorIsFatal   bool            // From GHA2DB_GHAPI_ERROR_FATAL, ghapi2db tool, make any GH API error fatal, default false
 	SkipGHAPI           bool            // From GHA2DB_GHAPISKIP, ghapi2db tool, if set then tool is not creating artificial events using GitHub API
+	SkipReposMetadata   bool            // From GHA2DB_REPOS_METADATA_SKIP, ghapi2db tool, if set then tool is not taking repositories metadata snapshots
+	SkipChecks          bool            // From GHA2DB_CHECKS_SKIP, ghapi2db tool, if set then tool is not getting PRs CI check runs and commit statuses
 	SkipGetRepos        bool            // From GHA2DB_GETREPOSSKIP, get_repos tool, if set then tool does nothing
 	CSVFile             string          // From GHA2DB_CSVOUT, runq tool, if set, saves result in this file
 	ComputeAll          bool            // From GHA2DB_COMPUTE_ALL, all tools, if set then no period decisions are taken based on time, but all possible periods are recalculated
@@ -92,6 +106,7 @@ type Ctx struct {
 	OnlyMetrics         map[string]bool // From GHA2DB_ONLY_METRICS, gha2db_sync tool, default "" - comma separated list of metrics to process, as fiven my "sql: name" in the "metrics.yaml" file. Only those metrics will be calculated.
 	AllowBrokenJSON     bool            // From GHA2DB_ALLOW_BROKEN_JSON, gha2db tool, default false. If set then gha2db skips broken jsons and saves them as jsons/error_YYYY-MM-DD-h-n-m.json (n is the JSON number (1-m) of m JSONS array)
 	JSONsDir            string          // From GHA2DB_JSONS_DIR, website_data tool, default "./jsons/"
+	WebsiteOutput       string          // From GHA2DB_WEBSITE_OUTPUT, website_data tool - "files" (default, projects.json and project.json files in JSONsDir), "combined" (single website_data.json in JSONsDir) or HTTP(S) URL to POST combined JSON to
 	WebsiteData         bool            // From GHA2DB_WEBSITEDATA, devstats tool, run website_data just after sync is complete, default false.
 	SkipUpdateEvents    bool            // FROM GHA2DB_SKIP_UPDATE_EVENTS, ghapi2db tool, drop and recreate artificial events if their state differs, default false
 }
@@ -227,6 +242,8 @@ func (ctx *Ctx) Init() {
 	// Skip ghapi2db and/or get_repos
 	ctx.SkipGetRepos = os.Getenv("GHA2DB_GETREPOSSKIP") != ""
 	ctx.SkipGHAPI = os.Getenv("GHA2DB_GHAPISKIP") != ""
+	ctx.SkipReposMetadata = os.Getenv("GHA2DB_REPOS_METADATA_SKIP") != ""
+	ctx.SkipChecks = os.Getenv("GHA2DB_CHECKS_SKIP") != ""
 	ctx.GHAPIErrorIsFatal = os.Getenv("GHA2DB_GHAPI_ERROR_FATAL") != ""
 
 	// Last TS series
@@ -278,7 +295,11 @@ func (ctx *Ctx) Init() {
 	ctx.MetricsYaml = os.Getenv("GHA2DB_METRICS_YAML")
 	ctx.TagsYaml = os.Getenv("GHA2DB_TAGS_YAML")
 	ctx.ColumnsYaml = os.Getenv("GHA2DB_COLUMNS_YAML")
+	ctx.ColumnsPrune = os.Getenv("GHA2DB_COLUMNS_PRUNE")
+	ctx.ColumnsReport = os.Getenv("GHA2DB_COLUMNS_REPORT")
 	ctx.VarsYaml = os.Getenv("GHA2DB_VARS_YAML")
+	ctx.VarsDryRun = os.Getenv("GHA2DB_VARS_DRY_RUN") != ""
+	ctx.SyncIssuesYaml = os.Getenv("GHA2DB_SYNC_ISSUES_YAML")
 	if ctx.MetricsYaml == "" {
 		ctx.MetricsYaml = "metrics/" + proj + "metrics.yaml"
 	}
@@ -291,6 +312,9 @@ func (ctx *Ctx) Init() {
 	if ctx.VarsYaml == "" {
 		ctx.VarsYaml = "metrics/" + proj + "vars.yaml"
 	}
+	if ctx.SyncIssuesYaml == "" {
+		ctx.SyncIssuesYaml = "metrics/" + proj + "sync_issues.yaml"
+	}
 
 	// GitHub OAuth
 	ctx.GitHubOAuth = os.Getenv("GHA2DB_GITHUB_OAUTH")
@@ -298,6 +322,12 @@ func (ctx *Ctx) Init() {
 		ctx.GitHubOAuth = "/etc/github/oauth"
 	}
 
+	// GitHub API base URL
+	ctx.GitHubURL = os.Getenv("GHA2DB_GITHUB_URL")
+	if ctx.GitHubURL != "" && ctx.GitHubURL[len(ctx.GitHubURL)-1:] != "/" {
+		ctx.GitHubURL += "/"
+	}
+
 	// Max DB logs age
 	ctx.ClearDBPeriod = os.Getenv("GHA2DB_MAXLOGAGE")
 	if ctx.ClearDBPeriod == "" {
@@ -427,6 +457,25 @@ func (ctx *Ctx) Init() {
 		ctx.ProjectsYaml = "projects.yaml"
 	}
 
+	// Company aliases file
+	ctx.CompanyAliasesYaml = os.Getenv("GHA2DB_COMPANY_ALIASES_YAML")
+	if ctx.CompanyAliasesYaml == "" {
+		ctx.CompanyAliasesYaml = "company_aliases.yaml"
+	}
+
+	// Bots detection config file
+	ctx.BotsYaml = os.Getenv("GHA2DB_BOTS_YAML")
+	if ctx.BotsYaml == "" {
+		ctx.BotsYaml = "bots.yaml"
+	}
+
+	// Affiliations validation
+	ctx.AffsCheck = os.Getenv("GHA2DB_AFFS_CHECK") != ""
+	ctx.AffsForce = os.Getenv("GHA2DB_AFFS_FORCE") != ""
+
+	// Right to erasure mode
+	ctx.EraseDelete = os.Getenv("GHA2DB_ERASE_DELETE") != ""
+
 	// `get_repos` repositories dir
 	ctx.ReposDir = os.Getenv("GHA2DB_REPOS_DIR")
 	if ctx.ReposDir == "" {
@@ -440,6 +489,7 @@ func (ctx *Ctx) Init() {
 	ctx.ProcessCommits = os.Getenv("GHA2DB_PROCESS_COMMITS") != ""
 	ctx.ExternalInfo = os.Getenv("GHA2DB_EXTERNAL_INFO") != ""
 	ctx.ProjectsCommits = os.Getenv("GHA2DB_PROJECTS_COMMITS")
+	ctx.BackfillLines = os.Getenv("GHA2DB_BACKFILL_LINES") != ""
 
 	// `website_data` JSONs dir
 	ctx.JSONsDir = os.Getenv("GHA2DB_JSONS_DIR")
@@ -450,6 +500,12 @@ func (ctx *Ctx) Init() {
 		ctx.JSONsDir += "/"
 	}
 
+	// `website_data` output
+	ctx.WebsiteOutput = os.Getenv("GHA2DB_WEBSITE_OUTPUT")
+	if ctx.WebsiteOutput == "" {
+		ctx.WebsiteOutput = "files"
+	}
+
 	// Calculate all periods?
 	ctx.ComputeAll = os.Getenv("GHA2DB_COMPUTE_ALL") != ""
 
@@ -482,6 +538,10 @@ func (ctx *Ctx) Init() {
 	if ctx.RecentReposRange == "" {
 		ctx.RecentReposRange = "1 day"
 	}
+	ctx.ReposMetadataRange = os.Getenv("GHA2DB_REPOS_METADATA_RANGE")
+	if ctx.ReposMetadataRange == "" {
+		ctx.ReposMetadataRange = "1 day"
+	}
 
 	ctx.CSVFile = os.Getenv("GHA2DB_CSVOUT")
 
@@ -42,6 +42,8 @@ func copyContext(in *lib.Ctx) *lib.Ctx {
 		SkipTSDB:            in.SkipTSDB,
 		SkipPDB:             in.SkipPDB,
 		SkipGHAPI:           in.SkipGHAPI,
+		SkipReposMetadata:   in.SkipReposMetadata,
+		SkipChecks:          in.SkipChecks,
 		GHAPIErrorIsFatal:   in.GHAPIErrorIsFatal,
 		AllowBrokenJSON:     in.AllowBrokenJSON,
 		WebsiteData:         in.WebsiteData,
@@ -57,8 +59,13 @@ func copyContext(in *lib.Ctx) *lib.Ctx {
 		MetricsYaml:         in.MetricsYaml,
 		TagsYaml:            in.TagsYaml,
 		ColumnsYaml:         in.ColumnsYaml,
+		ColumnsPrune:        in.ColumnsPrune,
+		ColumnsReport:       in.ColumnsReport,
 		VarsYaml:            in.VarsYaml,
+		VarsDryRun:          in.VarsDryRun,
+		SyncIssuesYaml:      in.SyncIssuesYaml,
 		GitHubOAuth:         in.GitHubOAuth,
+		GitHubURL:           in.GitHubURL,
 		ClearDBPeriod:       in.ClearDBPeriod,
 		Trials:              in.Trials,
 		LogTime:             in.LogTime,
@@ -76,6 +83,7 @@ func copyContext(in *lib.Ctx) *lib.Ctx {
 		TestsYaml:           in.TestsYaml,
 		ReposDir:            in.ReposDir,
 		JSONsDir:            in.JSONsDir,
+		WebsiteOutput:       in.WebsiteOutput,
 		ExecFatal:           in.ExecFatal,
 		ExecQuiet:           in.ExecQuiet,
 		ExecOutput:          in.ExecOutput,
@@ -83,7 +91,13 @@ func copyContext(in *lib.Ctx) *lib.Ctx {
 		ProcessCommits:      in.ProcessCommits,
 		ExternalInfo:        in.ExternalInfo,
 		ProjectsCommits:     in.ProjectsCommits,
+		BackfillLines:       in.BackfillLines,
 		ProjectsYaml:        in.ProjectsYaml,
+		CompanyAliasesYaml:  in.CompanyAliasesYaml,
+		BotsYaml:            in.BotsYaml,
+		AffsCheck:           in.AffsCheck,
+		AffsForce:           in.AffsForce,
+		EraseDelete:         in.EraseDelete,
 		ProjectsOverride:    in.ProjectsOverride,
 		ExcludeRepos:        in.ExcludeRepos,
 		InputDBs:            in.InputDBs,
@@ -91,6 +105,7 @@ func copyContext(in *lib.Ctx) *lib.Ctx {
 		TmOffset:            in.TmOffset,
 		RecentRange:         in.RecentRange,
 		RecentReposRange:    in.RecentReposRange,
+		ReposMetadataRange:  in.ReposMetadataRange,
 		CSVFile:             in.CSVFile,
 		ComputeAll:          in.ComputeAll,
 		ActorsFilter:        in.ActorsFilter,
@@ -233,6 +248,8 @@ func TestInit(t *testing.T) {
 		SkipTSDB:            false,
 		SkipPDB:             false,
 		SkipGHAPI:           false,
+		SkipReposMetadata:   false,
+		SkipChecks:          false,
 		GHAPIErrorIsFatal:   false,
 		AllowBrokenJSON:     false,
 		WebsiteData:         false,
@@ -248,8 +265,13 @@ func TestInit(t *testing.T) {
 		MetricsYaml:         "metrics/metrics.yaml",
 		TagsYaml:            "metrics/tags.yaml",
 		ColumnsYaml:         "metrics/columns.yaml",
+		ColumnsPrune:        "",
+		ColumnsReport:       "",
 		VarsYaml:            "metrics/vars.yaml",
+		VarsDryRun:          false,
+		SyncIssuesYaml:      "metrics/sync_issues.yaml",
 		GitHubOAuth:         "/etc/github/oauth",
+		GitHubURL:           "",
 		ClearDBPeriod:       "1 week",
 		Trials:              []int{10, 30, 60, 120, 300, 600},
 		LogTime:             true,
@@ -267,6 +289,7 @@ func TestInit(t *testing.T) {
 		TestsYaml:           "tests.yaml",
 		ReposDir:            os.Getenv("HOME") + "/devstats_repos/",
 		JSONsDir:            "./jsons/",
+		WebsiteOutput:       "files",
 		ExecFatal:           true,
 		ExecQuiet:           false,
 		ExecOutput:          false,
@@ -274,7 +297,13 @@ func TestInit(t *testing.T) {
 		ProcessCommits:      false,
 		ExternalInfo:        false,
 		ProjectsCommits:     "",
+		BackfillLines:       false,
 		ProjectsYaml:        "projects.yaml",
+		CompanyAliasesYaml:  "company_aliases.yaml",
+		BotsYaml:            "bots.yaml",
+		AffsCheck:           false,
+		AffsForce:           false,
+		EraseDelete:         false,
 		ProjectsOverride:    map[string]bool{},
 		ExcludeRepos:        map[string]bool{},
 		InputDBs:            []string{},
@@ -282,6 +311,7 @@ func TestInit(t *testing.T) {
 		TmOffset:            0,
 		RecentRange:         "2 hours",
 		RecentReposRange:    "1 day",
+		ReposMetadataRange:  "1 day",
 		CSVFile:             "",
 		ComputeAll:          false,
 		ActorsFilter:        false,
@@ -538,9 +568,11 @@ func TestInit(t *testing.T) {
 		{
 			"Setting skip GHAPI and GetRepos",
 			map[string]string{
-				"GHA2DB_GETREPOSSKIP":      "1",
-				"GHA2DB_GHAPISKIP":         "1",
-				"GHA2DB_GHAPI_ERROR_FATAL": "1",
+				"GHA2DB_GETREPOSSKIP":        "1",
+				"GHA2DB_GHAPISKIP":           "1",
+				"GHA2DB_GHAPI_ERROR_FATAL":   "1",
+				"GHA2DB_REPOS_METADATA_SKIP": "1",
+				"GHA2DB_CHECKS_SKIP":         "1",
 			},
 			dynamicSetFields(
 				t,
@@ -549,6 +581,8 @@ func TestInit(t *testing.T) {
 					"SkipGetRepos":      true,
 					"SkipGHAPI":         true,
 					"GHAPIErrorIsFatal": true,
+					"SkipReposMetadata": true,
+					"SkipChecks":        true,
 				},
 			),
 		},
@@ -591,6 +625,19 @@ func TestInit(t *testing.T) {
 				},
 			),
 		},
+		{
+			"Setting GitHub API base URL",
+			map[string]string{
+				"GHA2DB_GITHUB_URL": "http://127.0.0.1:8080",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"GitHubURL": "http://127.0.0.1:8080/",
+				},
+			),
+		},
 		{
 			"Setting explain query mode",
 			map[string]string{"GHA2DB_EXPLAIN": "1"},
@@ -681,19 +728,21 @@ func TestInit(t *testing.T) {
 		{
 			"Setting non standard YAML files",
 			map[string]string{
-				"GHA2DB_METRICS_YAML": "met.YAML",
-				"GHA2DB_TAGS_YAML":    "/t/g/s.yml",
-				"GHA2DB_COLUMNS_YAML": "/t/cols.yml",
-				"GHA2DB_VARS_YAML":    "/vars.yml",
+				"GHA2DB_METRICS_YAML":     "met.YAML",
+				"GHA2DB_TAGS_YAML":        "/t/g/s.yml",
+				"GHA2DB_COLUMNS_YAML":     "/t/cols.yml",
+				"GHA2DB_VARS_YAML":        "/vars.yml",
+				"GHA2DB_SYNC_ISSUES_YAML": "/sync/issues.yml",
 			},
 			dynamicSetFields(
 				t,
 				copyContext(&defaultContext),
 				map[string]interface{}{
-					"MetricsYaml": "met.YAML",
-					"TagsYaml":    "/t/g/s.yml",
-					"ColumnsYaml": "/t/cols.yml",
-					"VarsYaml":    "/vars.yml",
+					"MetricsYaml":    "met.YAML",
+					"TagsYaml":       "/t/g/s.yml",
+					"ColumnsYaml":    "/t/cols.yml",
+					"VarsYaml":       "/vars.yml",
+					"SyncIssuesYaml": "/sync/issues.yml",
 				},
 			),
 		},
@@ -813,11 +862,12 @@ func TestInit(t *testing.T) {
 				t,
 				copyContext(&defaultContext),
 				map[string]interface{}{
-					"Project":     "prometheus",
-					"MetricsYaml": "metrics/prometheus/metrics.yaml",
-					"TagsYaml":    "metrics/prometheus/tags.yaml",
-					"ColumnsYaml": "metrics/prometheus/columns.yaml",
-					"VarsYaml":    "metrics/prometheus/vars.yaml",
+					"Project":        "prometheus",
+					"MetricsYaml":    "metrics/prometheus/metrics.yaml",
+					"TagsYaml":       "metrics/prometheus/tags.yaml",
+					"ColumnsYaml":    "metrics/prometheus/columns.yaml",
+					"VarsYaml":       "metrics/prometheus/vars.yaml",
+					"SyncIssuesYaml": "metrics/prometheus/sync_issues.yaml",
 				},
 			),
 		},
@@ -830,11 +880,12 @@ func TestInit(t *testing.T) {
 				t,
 				copyContext(&defaultContext),
 				map[string]interface{}{
-					"Project":     "prometheus",
-					"MetricsYaml": "metrics/prometheus/metrics.yaml",
-					"TagsYaml":    "metrics/prometheus/tags.yaml",
-					"ColumnsYaml": "metrics/prometheus/columns.yaml",
-					"VarsYaml":    "metrics/prometheus/vars.yaml",
+					"Project":        "prometheus",
+					"MetricsYaml":    "metrics/prometheus/metrics.yaml",
+					"TagsYaml":       "metrics/prometheus/tags.yaml",
+					"ColumnsYaml":    "metrics/prometheus/columns.yaml",
+					"VarsYaml":       "metrics/prometheus/vars.yaml",
+					"SyncIssuesYaml": "metrics/prometheus/sync_issues.yaml",
 				},
 			),
 		},
@@ -864,6 +915,80 @@ func TestInit(t *testing.T) {
 				},
 			),
 		},
+		{
+			"Setting company aliases yaml",
+			map[string]string{
+				"GHA2DB_COMPANY_ALIASES_YAML": "aliases.yml",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"CompanyAliasesYaml": "aliases.yml",
+				},
+			),
+		},
+		{
+			"Setting bots yaml",
+			map[string]string{
+				"GHA2DB_BOTS_YAML": "my_bots.yml",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"BotsYaml": "my_bots.yml",
+				},
+			),
+		},
+		{
+			"Setting affiliations check and force",
+			map[string]string{
+				"GHA2DB_AFFS_CHECK": "1",
+				"GHA2DB_AFFS_FORCE": "y",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"AffsCheck": true,
+					"AffsForce": true,
+				},
+			),
+		},
+		{
+			"Setting erase delete mode",
+			map[string]string{"GHA2DB_ERASE_DELETE": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"EraseDelete": true},
+			),
+		},
+		{
+			"Setting vars dry run mode",
+			map[string]string{"GHA2DB_VARS_DRY_RUN": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"VarsDryRun": true},
+			),
+		},
+		{
+			"Setting columns prune mode and drift report",
+			map[string]string{
+				"GHA2DB_COLUMNS_PRUNE":  "archive",
+				"GHA2DB_COLUMNS_REPORT": "drift.json",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"ColumnsPrune":  "archive",
+					"ColumnsReport": "drift.json",
+				},
+			),
+		},
 		{
 			"Setting repos dir without ending '/'",
 			map[string]string{
@@ -916,18 +1041,33 @@ func TestInit(t *testing.T) {
 				},
 			),
 		},
+		{
+			"Setting website data output",
+			map[string]string{
+				"GHA2DB_WEBSITE_OUTPUT": "https://example.com/devstats",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"WebsiteOutput": "https://example.com/devstats",
+				},
+			),
+		},
 		{
 			"Setting recent range",
 			map[string]string{
-				"GHA2DB_RECENT_RANGE":       "6 hours",
-				"GHA2DB_RECENT_REPOS_RANGE": "1 week",
+				"GHA2DB_RECENT_RANGE":         "6 hours",
+				"GHA2DB_RECENT_REPOS_RANGE":   "1 week",
+				"GHA2DB_REPOS_METADATA_RANGE": "12 hours",
 			},
 			dynamicSetFields(
 				t,
 				copyContext(&defaultContext),
 				map[string]interface{}{
-					"RecentRange":      "6 hours",
-					"RecentReposRange": "1 week",
+					"RecentRange":        "6 hours",
+					"RecentReposRange":   "1 week",
+					"ReposMetadataRange": "12 hours",
 				},
 			),
 		},
@@ -1064,6 +1204,19 @@ func TestInit(t *testing.T) {
 				},
 			),
 		},
+		{
+			"Setting backfill lines",
+			map[string]string{
+				"GHA2DB_BACKFILL_LINES": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"BackfillLines": true,
+				},
+			),
+		},
 		{
 			"Setting projects override",
 			map[string]string{
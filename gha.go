@@ -22,18 +22,72 @@ type AllProjects struct {
 
 // Project contain mapping from project name to its command line used to sync it
 type Project struct {
-	CommandLine      []string          `yaml:"command_line"`
-	StartDate        *time.Time        `yaml:"start_date"`
-	PDB              string            `yaml:"psql_db"`
-	Disabled         bool              `yaml:"disabled"`
-	MainRepo         string            `yaml:"main_repo"`
-	AnnotationRegexp string            `yaml:"annotation_regexp"`
-	Order            int               `yaml:"order"`
-	JoinDate         *time.Time        `yaml:"join_date"`
-	FilesSkipPattern string            `yaml:"files_skip_pattern"`
-	Env              map[string]string `yaml:"env"`
-	FullName         string            `yaml:"name"`
-	Status           string            `yaml:"status"`
+	CommandLine       []string           `yaml:"command_line"`
+	StartDate         *time.Time         `yaml:"start_date"`
+	PDB               string             `yaml:"psql_db"`
+	Disabled          bool               `yaml:"disabled"`
+	MainRepo          string             `yaml:"main_repo"`
+	AnnotationRegexp  string             `yaml:"annotation_regexp"`
+	AnnotationSources []AnnotationSource `yaml:"annotation_sources"`
+	QuickRanges       *QuickRanges       `yaml:"quick_ranges"`
+	Order             int                `yaml:"order"`
+	JoinDate          *time.Time         `yaml:"join_date"`
+	FilesSkipPattern  string             `yaml:"files_skip_pattern"`
+	Env               map[string]string  `yaml:"env"`
+	FullName          string             `yaml:"name"`
+	Status            string             `yaml:"status"`
+}
+
+// Annotation sources types
+const (
+	AnnotationSourceGitTags  = "git_tags"
+	AnnotationSourceReleases = "releases"
+	AnnotationSourceYAML     = "yaml"
+)
+
+// AnnotationSource - project annotations source, all sources are merged (annotations with the same name are deduplicated)
+// Type: "git_tags" - tags from a local clone of Repo (default main repo), "releases" - GitHub releases from gha_releases
+// of Repo (default all repos), "yaml" - hand maintained list of milestones from File
+// Regexp filters annotations names, default is project's annotation_regexp
+type AnnotationSource struct {
+	Type   string `yaml:"type"`
+	Repo   string `yaml:"repo"`
+	Regexp string `yaml:"regexp"`
+	File   string `yaml:"file"`
+}
+
+// Annotations ranges grouping levels
+const (
+	QuickRangesAll   = "all"
+	QuickRangesMinor = "minor"
+	QuickRangesMajor = "major"
+	QuickRangesNone  = "none"
+)
+
+// QuickRanges - project quick ranges config
+// Periods - "Last ..." ranges, when set they replace default periods (last day, week, 10 days, month, quarter, year and decade)
+// Annotations - ranges between consecutive annotations: "all" (default) - all annotations, "minor" - first release of each
+// major.minor version, "major" - first release of each major version, "none" - no annotations ranges
+// When grouping by semantic version, pre-releases and annotations that are not semantic versions are skipped
+// Ranges - fixed date ranges, for example fiscal quarters, empty To means now
+type QuickRanges struct {
+	Periods     []QuickRangePeriod `yaml:"periods"`
+	Annotations string             `yaml:"annotations"`
+	Ranges      []QuickRangeFixed  `yaml:"ranges"`
+}
+
+// QuickRangePeriod - "Last ..." quick range: suffix (series name suffix), name (Grafana drop-down name) and Postgres interval
+type QuickRangePeriod struct {
+	Suffix   string `yaml:"suffix"`
+	Name     string `yaml:"name"`
+	Interval string `yaml:"interval"`
+}
+
+// QuickRangeFixed - fixed dates quick range
+type QuickRangeFixed struct {
+	Name string     `yaml:"name"`
+	From time.Time  `yaml:"from"`
+	To   *time.Time `yaml:"to"`
 }
 
 // AnyArray - holds array of interface{} - just a shortcut
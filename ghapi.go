@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math"
+	"net/http"
+	"net/url"
 	"os"
 	"sort"
 	"strings"
@@ -225,18 +228,95 @@ func GHClient(ctx *Ctx) (ghCtx context.Context, client *github.Client) {
 		tc := oauth2.NewClient(ghCtx, ts)
 		client = github.NewClient(tc)
 	}
+
+	// Non-standard API endpoint (GitHub Enterprise or fake API server used by tests)
+	if ctx.GitHubURL != "" {
+		baseURL, err := url.Parse(ctx.GitHubURL)
+		FatalOnError(err)
+		client.BaseURL = baseURL
+	}
 	return
 }
 
+// WaitForRateLimit - checks remaining GitHub API points, when there are no more than ctx.MinGHAPIPoints
+// it waits for the reset, but only when reset happens in no more than ctx.MaxGHAPIWaitSeconds
+// returns remaining points, wait period and status:
+// "" - API can be called, Retry - waited for reset (check again), RateLimit - limit reached and reset is too far
+// info is the endpoint that is about to be called, remaining points and waits are recorded in its GitHub API usage stats
+func WaitForRateLimit(gctx context.Context, gc *github.Client, ctx *Ctx, info string, tr int) (int, time.Duration, string) {
+	_, rem, waitPeriod := GetRateLimits(gctx, gc, true)
+	recordGHAPIRemaining(info, rem)
+	if ctx.Debug > 1 {
+		Printf("%s try: %d, rem: %v, waitPeriod: %v\n", info, tr, rem, waitPeriod)
+	}
+	if rem > ctx.MinGHAPIPoints {
+		return rem, waitPeriod, ""
+	}
+	if waitPeriod.Seconds() > float64(ctx.MaxGHAPIWaitSeconds) {
+		return rem, waitPeriod, RateLimit
+	}
+	if ctx.Debug > 0 {
+		Printf("API limit reached (%s), waiting %v (%d)\n", info, waitPeriod, tr)
+	}
+	RecordGHAPIWait(info, waitPeriod+time.Duration(1)*time.Second)
+	time.Sleep(time.Duration(1) * time.Second)
+	time.Sleep(waitPeriod)
+	return rem, waitPeriod, Retry
+}
+
+// ghAPICallWithRetry - calls GitHub API using `call`, waits for rate limit reset and retries on abuse detection
+// Returns false when object was not found, API limit was reached or all retries failed
+// When ctx.GHAPIErrorIsFatal is set, API limit and retries failures are fatal
+func ghAPICallWithRetry(gctx context.Context, gc *github.Client, ctx *Ctx, cfg *IssueConfig, info string, call func() error) bool {
+	for tr := 0; tr < ctx.MaxGHAPIRetry; tr++ {
+		rem, waitPeriod, status := WaitForRateLimit(gctx, gc, ctx, info, tr)
+		if status == Retry {
+			continue
+		}
+		if status == RateLimit {
+			if ctx.GHAPIErrorIsFatal {
+				Fatalf("API limit reached while calling %s, aborting, don't want to wait %v", info, waitPeriod)
+			}
+			Printf("Error: API limit reached while calling %s, aborting, don't want to wait %v\n", info, waitPeriod)
+			return false
+		}
+		if ctx.Debug > 1 {
+			Printf("API call %s for %s, remaining GHAPI points %d\n", info, cfg.Repo, rem)
+		}
+		res := HandlePossibleError(call(), cfg, info)
+		if res == "" {
+			return true
+		}
+		if res == Abuse {
+			wait := time.Duration(int(math.Pow(2.0, float64(tr+3)))) * time.Second
+			if ctx.Debug > 0 {
+				Printf("GitHub API abuse detected (%s), wait %v\n", info, wait)
+			}
+			RecordGHAPIWait(info, wait)
+			time.Sleep(wait)
+		}
+		if res == NotFound {
+			Printf("Warning: not found (%s): %s\n", info, cfg.Repo)
+			return false
+		}
+	}
+	if ctx.GHAPIErrorIsFatal {
+		Fatalf("GetRateLimit call failed %d times while calling %s, aborting", ctx.MaxGHAPIRetry, info)
+	}
+	Printf("Error: GetRateLimit call failed %d times while calling %s, aborting\n", ctx.MaxGHAPIRetry, info)
+	return false
+}
+
 // HandlePossibleError - display error specific message, detect rate limit and abuse
-func HandlePossibleError(err error, cfg *IssueConfig, info string) string {
+func HandlePossibleError(err error, cfg *IssueConfig, info string) (res string) {
+	defer func() { recordGHAPICall(info, res) }()
 	if err != nil {
 		_, rate := err.(*github.RateLimitError)
 		_, abuse := err.(*github.AbuseRateLimitError)
 		if abuse || rate {
 			if rate {
 				Printf("Rate limit (%s) for %v\n", info, cfg)
-				return "rate"
+				return RateLimit
 			}
 			if abuse {
 				Printf("Abuse detected (%s) for %v\n", info, cfg)
@@ -362,13 +442,48 @@ func ghMilestone(con *sql.Tx, ctx *Ctx, eid int64, ic *IssueConfig, maybeHide fu
 }
 
 // GetRecentRepos - get list of repos active last day
+// Skips repos that were archived according to their most recent metadata snapshot
 func GetRecentRepos(c *sql.DB, ctx *Ctx, dtFrom time.Time) (repos []string, rids []int64) {
 	rows := QuerySQLWithErr(
 		c,
 		ctx,
 		fmt.Sprintf(
 			"select distinct repo_id, dup_repo_name from gha_events "+
-				"where created_at > %s",
+				"where created_at > %s and repo_id not in ("+
+				"select sub.id from (select distinct on (id) id, archived "+
+				"from gha_repos_metadata order by id, dt desc) sub where sub.archived)",
+			NValue(1),
+		),
+		dtFrom,
+	)
+	defer func() { FatalOnError(rows.Close()) }()
+	var (
+		repo string
+		rid  int64
+	)
+	for rows.Next() {
+		FatalOnError(rows.Scan(&rid, &repo))
+		repos = append(repos, repo)
+		rids = append(rids, rid)
+	}
+	FatalOnError(rows.Err())
+	return
+}
+
+// getReposForMetadata - get list of repos without metadata checked after dtFrom
+// Repos with the most recent snapshot archived or not found are not checked again
+// Renamed repos have the same ID and multiple names, any of them works because GitHub redirects old names
+func getReposForMetadata(c *sql.DB, ctx *Ctx, dtFrom time.Time) (repos []string, rids []int64) {
+	rows := QuerySQLWithErr(
+		c,
+		ctx,
+		fmt.Sprintf(
+			"select r.id, max(r.name) from gha_repos r left join ("+
+				"select distinct on (id) id, last_checked, archived, not_found "+
+				"from gha_repos_metadata order by id, dt desc) m on m.id = r.id "+
+				"where r.name like '%%_/_%%' "+
+				"and (m.id is null or (not m.archived and not m.not_found and m.last_checked <= %s)) "+
+				"group by r.id order by r.id",
 			NValue(1),
 		),
 		dtFrom,
@@ -387,6 +502,200 @@ func GetRecentRepos(c *sql.DB, ctx *Ctx, dtFrom time.Time) (repos []string, rids
 	return
 }
 
+// repoMetadata - repository metadata as stored in gha_repos_metadata
+type repoMetadata struct {
+	name          string
+	defaultBranch *string
+	licenseKey    *string
+	licenseName   *string
+	topics        string
+	archived      bool
+	notFound      bool
+}
+
+// sameStringPtr - checks if two optional strings are equal
+func sameStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// equal - checks if metadata is the same (ignoring name case because GitHub names are case insensitive)
+func (m *repoMetadata) equal(o *repoMetadata) bool {
+	return strings.ToLower(m.name) == strings.ToLower(o.name) &&
+		sameStringPtr(m.defaultBranch, o.defaultBranch) &&
+		sameStringPtr(m.licenseKey, o.licenseKey) &&
+		sameStringPtr(m.licenseName, o.licenseName) &&
+		m.topics == o.topics &&
+		m.archived == o.archived &&
+		m.notFound == o.notFound
+}
+
+// saveReposMetadata - adds a new metadata snapshot only when metadata differs from the most recent one
+// Otherwise only updates the most recent snapshot's last_checked date
+func saveReposMetadata(c *sql.DB, ctx *Ctx, rid int64, dt time.Time, md *repoMetadata) {
+	if ctx.SkipPDB {
+		if ctx.Debug > 0 {
+			Printf("No DB write: repo metadata '%d' '%+v'\n", rid, *md)
+		}
+		return
+	}
+	rows := QuerySQLWithErr(
+		c,
+		ctx,
+		"select dt, name, default_branch, license_key, license_name, topics, archived, not_found "+
+			"from gha_repos_metadata where id = $1 order by dt desc limit 1",
+		rid,
+	)
+	defer func() { FatalOnError(rows.Close()) }()
+	var (
+		last   repoMetadata
+		lastDt time.Time
+		found  bool
+	)
+	for rows.Next() {
+		FatalOnError(
+			rows.Scan(
+				&lastDt,
+				&last.name,
+				&last.defaultBranch,
+				&last.licenseKey,
+				&last.licenseName,
+				&last.topics,
+				&last.archived,
+				&last.notFound,
+			),
+		)
+		found = true
+	}
+	FatalOnError(rows.Err())
+	if found && last.equal(md) {
+		ExecSQLWithErr(
+			c,
+			ctx,
+			"update gha_repos_metadata set last_checked = $1 where id = $2 and dt = $3",
+			dt,
+			rid,
+			lastDt,
+		)
+		return
+	}
+	ExecSQLWithErr(
+		c,
+		ctx,
+		"insert into gha_repos_metadata("+
+			"id, dt, last_checked, name, default_branch, license_key, license_name, topics, archived, not_found"+
+			") "+NValues(10),
+		AnyArray{
+			rid,
+			dt,
+			dt,
+			md.name,
+			StringOrNil(md.defaultBranch),
+			StringOrNil(md.licenseKey),
+			StringOrNil(md.licenseName),
+			md.topics,
+			md.archived,
+			md.notFound,
+		}...,
+	)
+}
+
+// ReposMetadataSnapshot - saves repository metadata snapshot in gha_repos_metadata
+// rid is a repository ID from gha_repos, name comes from the API (it can be renamed)
+// Snapshot is only added when metadata changed, otherwise the most recent one is marked as checked at dt
+func ReposMetadataSnapshot(c *sql.DB, ctx *Ctx, rid int64, dt time.Time, repo *github.Repository) {
+	md := repoMetadata{
+		name:          repo.GetFullName(),
+		defaultBranch: repo.DefaultBranch,
+		archived:      repo.GetArchived(),
+	}
+	if repo.License != nil {
+		md.licenseKey = repo.License.Key
+		md.licenseName = repo.License.Name
+	}
+	topics := append([]string{}, repo.Topics...)
+	sort.Strings(topics)
+	md.topics = strings.Join(topics, ",")
+	saveReposMetadata(c, ctx, rid, dt, &md)
+}
+
+// ReposMetadataNotFound - saves snapshot marking repository as not found in GitHub API
+// Such repositories are not checked again
+func ReposMetadataNotFound(c *sql.DB, ctx *Ctx, rid int64, dt time.Time, name string) {
+	saveReposMetadata(c, ctx, rid, dt, &repoMetadata{name: name, notFound: true})
+}
+
+// SyncReposMetadata - takes repositories metadata snapshots using GitHub API
+// Only repos not checked in the last ctx.ReposMetadataRange are processed
+func SyncReposMetadata(gctx context.Context, gc *github.Client, ctx *Ctx, c *sql.DB) {
+	dtFrom := GetDateAgo(c, ctx, time.Now(), ctx.ReposMetadataRange)
+	repos, rids := getReposForMetadata(c, ctx, dtFrom)
+	nRepos := len(repos)
+	Printf("ghapi2db.go: Processing %d repos metadata - GHAPI part\n", nRepos)
+
+	// GitHub don't like MT quering - use the same threads limit as for issue events
+	thrN := GetThreadsNum(ctx)
+	maxThreads := 16
+	if maxThreads > thrN {
+		maxThreads = thrN
+	}
+	ch := make(chan bool)
+	nThreads := 0
+	dtStart := time.Now()
+	lastTime := dtStart
+	checked := 0
+	for i := range repos {
+		go func(ch chan bool, orgRepo string, rid int64) {
+			ary := strings.Split(orgRepo, "/")
+			if len(ary) != 2 || ary[0] == "" || ary[1] == "" {
+				ch <- false
+				return
+			}
+			var (
+				repo     *github.Repository
+				notFound bool
+			)
+			ok := ghAPICallWithRetry(
+				gctx,
+				gc,
+				ctx,
+				&IssueConfig{Repo: orgRepo},
+				"Repositories.Get",
+				func() (err error) {
+					var resp *github.Response
+					repo, resp, err = gc.Repositories.Get(gctx, ary[0], ary[1])
+					notFound = resp != nil && resp.StatusCode == http.StatusNotFound
+					return
+				},
+			)
+			if !ok {
+				if notFound {
+					ReposMetadataNotFound(c, ctx, rid, time.Now(), orgRepo)
+				}
+				ch <- false
+				return
+			}
+			ReposMetadataSnapshot(c, ctx, rid, time.Now(), repo)
+			ch <- true
+		}(ch, repos[i], rids[i])
+		nThreads++
+		if nThreads >= maxThreads {
+			<-ch
+			nThreads--
+			checked++
+			ProgressInfo(checked, nRepos, dtStart, &lastTime, time.Duration(10)*time.Second, "")
+		}
+	}
+	for nThreads > 0 {
+		<-ch
+		nThreads--
+		checked++
+		ProgressInfo(checked, nRepos, dtStart, &lastTime, time.Duration(10)*time.Second, "")
+	}
+}
+
 // DeleteArtificialPREvent - create artificial API event (but from the past)
 func DeleteArtificialPREvent(c *sql.DB, ctx *Ctx, cfg *IssueConfig) (err error) {
 	if ctx.SkipPDB {
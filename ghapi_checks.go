@@ -0,0 +1,231 @@
+package devstats
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// CheckRun - holds single GitHub check run data
+// Vendored go-github has no Checks API support, so we only define what we need
+type CheckRun struct {
+	ID          *int64     `json:"id,omitempty"`
+	HeadSHA     *string    `json:"head_sha,omitempty"`
+	Name        *string    `json:"name,omitempty"`
+	Status      *string    `json:"status,omitempty"`
+	Conclusion  *string    `json:"conclusion,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// CheckRuns - holds GitHub check runs list response
+type CheckRuns struct {
+	TotalCount *int        `json:"total_count,omitempty"`
+	CheckRuns  []*CheckRun `json:"check_runs"`
+}
+
+// checkRunsPreview - Checks API is in preview and requires this accept header
+const checkRunsPreview = "application/vnd.github.antiope-preview+json"
+
+// prHead - holds PR head commit SHA and its repository
+type prHead struct {
+	sha  string
+	rid  int64
+	repo string
+}
+
+// ListCheckRuns - lists check runs for a given commit SHA (single page)
+func ListCheckRuns(gctx context.Context, gc *github.Client, owner, repo, sha string, opt *github.ListOptions) (*CheckRuns, *github.Response, error) {
+	u := fmt.Sprintf("repos/%v/%v/commits/%v/check-runs", owner, repo, sha)
+	if opt != nil && (opt.Page > 0 || opt.PerPage > 0) {
+		u += fmt.Sprintf("?page=%d&per_page=%d", opt.Page, opt.PerPage)
+	}
+	req, err := gc.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", checkRunsPreview)
+	runs := &CheckRuns{}
+	resp, err := gc.Do(gctx, req, runs)
+	if err != nil {
+		return nil, resp, err
+	}
+	return runs, resp, nil
+}
+
+// getRecentPRHeads - get list of PR head SHAs for PRs updated after dtFrom
+func getRecentPRHeads(c *sql.DB, ctx *Ctx, dtFrom time.Time) (heads []prHead) {
+	rows := QuerySQLWithErr(
+		c,
+		ctx,
+		fmt.Sprintf(
+			"select distinct head_sha, dup_repo_id, dup_repo_name from gha_pull_requests "+
+				"where updated_at > %s",
+			NValue(1),
+		),
+		dtFrom,
+	)
+	defer func() { FatalOnError(rows.Close()) }()
+	var head prHead
+	for rows.Next() {
+		FatalOnError(rows.Scan(&head.sha, &head.rid, &head.repo))
+		heads = append(heads, head)
+	}
+	FatalOnError(rows.Err())
+	return
+}
+
+// upsertCheck - inserts check run or commit status or updates its state
+// kind is "check_run" or "status", statuses are never updated by GitHub - new state means new status
+func upsertCheck(c *sql.DB, ctx *Ctx, head *prHead, kind string, id int64, name, status, conclusion *string, startedAt, completedAt *time.Time) {
+	if ctx.SkipPDB {
+		if ctx.Debug > 0 {
+			Printf("No DB write: %s %d for %s %s\n", kind, id, head.repo, head.sha)
+		}
+		return
+	}
+	ExecSQLWithErr(
+		c,
+		ctx,
+		"insert into gha_checks(id, kind, sha, name, status, conclusion, started_at, completed_at, "+
+			"dup_repo_id, dup_repo_name) "+NValues(10)+
+			" on conflict(id, kind) do update set status = excluded.status, conclusion = excluded.conclusion, "+
+			"started_at = excluded.started_at, completed_at = excluded.completed_at",
+		AnyArray{
+			id,
+			kind,
+			head.sha,
+			TruncStringOrNil(name, 200),
+			StringOrNil(status),
+			StringOrNil(conclusion),
+			TimeOrNil(startedAt),
+			TimeOrNil(completedAt),
+			head.rid,
+			head.repo,
+		}...,
+	)
+}
+
+// syncHeadChecks - gets all check runs and commit statuses for a single PR head and saves them
+func syncHeadChecks(gctx context.Context, gc *github.Client, ctx *Ctx, c *sql.DB, head *prHead) bool {
+	ary := strings.Split(head.repo, "/")
+	if len(ary) != 2 || ary[0] == "" || ary[1] == "" {
+		return false
+	}
+	cfg := &IssueConfig{Repo: head.repo}
+
+	// Check runs
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		var (
+			runs     *CheckRuns
+			response *github.Response
+		)
+		ok := ghAPICallWithRetry(
+			gctx,
+			gc,
+			ctx,
+			cfg,
+			"Checks.ListCheckRunsForRef",
+			func() (err error) {
+				runs, response, err = ListCheckRuns(gctx, gc, ary[0], ary[1], head.sha, opt)
+				return
+			},
+		)
+		if !ok {
+			return false
+		}
+		for _, run := range runs.CheckRuns {
+			if run.ID == nil || run.Name == nil {
+				continue
+			}
+			upsertCheck(c, ctx, head, "check_run", *run.ID, run.Name, run.Status, run.Conclusion, run.StartedAt, run.CompletedAt)
+		}
+		if response.NextPage == 0 {
+			break
+		}
+		opt.Page = response.NextPage
+	}
+
+	// Commit statuses
+	opt = &github.ListOptions{PerPage: 100}
+	for {
+		var (
+			statuses []*github.RepoStatus
+			response *github.Response
+		)
+		ok := ghAPICallWithRetry(
+			gctx,
+			gc,
+			ctx,
+			cfg,
+			"Repositories.ListStatuses",
+			func() (err error) {
+				statuses, response, err = gc.Repositories.ListStatuses(gctx, ary[0], ary[1], head.sha, opt)
+				return
+			},
+		)
+		if !ok {
+			return false
+		}
+		for _, st := range statuses {
+			if st.ID == nil || st.Context == nil {
+				continue
+			}
+			// Status is completed when it is not pending anymore
+			var completedAt *time.Time
+			if st.GetState() != "pending" {
+				completedAt = st.UpdatedAt
+			}
+			upsertCheck(c, ctx, head, "status", *st.ID, st.Context, nil, st.State, st.CreatedAt, completedAt)
+		}
+		if response.NextPage == 0 {
+			break
+		}
+		opt.Page = response.NextPage
+	}
+	return true
+}
+
+// SyncChecks - gets check runs and commit statuses for recently updated PRs head commits
+// PRs updated in the last ctx.RecentRange are processed
+func SyncChecks(gctx context.Context, gc *github.Client, ctx *Ctx, c *sql.DB) {
+	dtFrom := GetDateAgo(c, ctx, HourStart(time.Now()), ctx.RecentRange)
+	heads := getRecentPRHeads(c, ctx, dtFrom)
+	nHeads := len(heads)
+	Printf("ghapi2db.go: Processing %d PR heads checks - GHAPI part\n", nHeads)
+
+	// GitHub don't like MT quering - use the same threads limit as for issue events
+	thrN := GetThreadsNum(ctx)
+	maxThreads := 16
+	if maxThreads > thrN {
+		maxThreads = thrN
+	}
+	ch := make(chan bool)
+	nThreads := 0
+	dtStart := time.Now()
+	lastTime := dtStart
+	checked := 0
+	for i := range heads {
+		go func(ch chan bool, head prHead) {
+			ch <- syncHeadChecks(gctx, gc, ctx, c, &head)
+		}(ch, heads[i])
+		nThreads++
+		if nThreads >= maxThreads {
+			<-ch
+			nThreads--
+			checked++
+			ProgressInfo(checked, nHeads, dtStart, &lastTime, time.Duration(10)*time.Second, "")
+		}
+	}
+	for nThreads > 0 {
+		<-ch
+		nThreads--
+		checked++
+		ProgressInfo(checked, nHeads, dtStart, &lastTime, time.Duration(10)*time.Second, "")
+	}
+}
@@ -0,0 +1,175 @@
+package devstats
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GHAPIEndpointStats - GitHub API usage statistics for a single endpoint (for example "Issues.Get")
+// Calls - number of API calls, Retries - number of calls that failed and were retried (rate limit, abuse, server errors)
+// Waits - number of waits (API points reset or abuse detection), WaitTime - total time spent waiting
+// Remaining - last seen remaining API points, MinRemaining - minimum seen remaining API points
+type GHAPIEndpointStats struct {
+	Calls        int
+	Retries      int
+	Waits        int
+	WaitTime     time.Duration
+	Remaining    int
+	MinRemaining int
+}
+
+// ghAPIStatsSeries - merged TS series name used to store GitHub API usage statistics
+const ghAPIStatsSeries = "ghapi"
+
+// GitHub API usage statistics collected by all GitHub API clients in this process
+var (
+	ghAPIStats    = make(map[string]*GHAPIEndpointStats)
+	ghAPIStatsMtx = &sync.Mutex{}
+)
+
+// ghAPIEndpoint - returns (creates if needed) statistics for a given endpoint, must be called with ghAPIStatsMtx locked
+func ghAPIEndpoint(endpoint string) *GHAPIEndpointStats {
+	st, ok := ghAPIStats[endpoint]
+	if !ok {
+		st = &GHAPIEndpointStats{Remaining: -1, MinRemaining: -1}
+		ghAPIStats[endpoint] = st
+	}
+	return st
+}
+
+// recordGHAPICall - records a single API call and its result (as returned by HandlePossibleError)
+func recordGHAPICall(endpoint, res string) {
+	ghAPIStatsMtx.Lock()
+	st := ghAPIEndpoint(endpoint)
+	st.Calls++
+	if res != "" && res != NotFound {
+		st.Retries++
+	}
+	ghAPIStatsMtx.Unlock()
+}
+
+// recordGHAPIRemaining - records remaining API points seen before calling endpoint
+func recordGHAPIRemaining(endpoint string, rem int) {
+	if rem < 0 {
+		return
+	}
+	ghAPIStatsMtx.Lock()
+	st := ghAPIEndpoint(endpoint)
+	st.Remaining = rem
+	if st.MinRemaining < 0 || rem < st.MinRemaining {
+		st.MinRemaining = rem
+	}
+	ghAPIStatsMtx.Unlock()
+}
+
+// RecordGHAPIWait - records waiting for a given endpoint (API points reset or abuse detection)
+func RecordGHAPIWait(endpoint string, wait time.Duration) {
+	ghAPIStatsMtx.Lock()
+	st := ghAPIEndpoint(endpoint)
+	st.Waits++
+	st.WaitTime += wait
+	ghAPIStatsMtx.Unlock()
+}
+
+// GetGHAPIStats - returns a copy of GitHub API usage statistics collected so far
+func GetGHAPIStats() map[string]GHAPIEndpointStats {
+	ghAPIStatsMtx.Lock()
+	defer ghAPIStatsMtx.Unlock()
+	stats := make(map[string]GHAPIEndpointStats)
+	for endpoint, st := range ghAPIStats {
+		stats[endpoint] = *st
+	}
+	return stats
+}
+
+// ResetGHAPIStats - clears GitHub API usage statistics collected so far
+func ResetGHAPIStats() {
+	ghAPIStatsMtx.Lock()
+	ghAPIStats = make(map[string]*GHAPIEndpointStats)
+	ghAPIStatsMtx.Unlock()
+}
+
+// GHAPIStatsSeriesName - returns series name for a given tool and endpoint, for example: ghapi2db, "Issues.Get" -> "ghapi2db_issues_get"
+func GHAPIStatsSeriesName(tool, endpoint string) string {
+	return strings.ToLower(tool + "_" + strings.Replace(endpoint, ".", "_", -1))
+}
+
+// previousGHAPIStats - returns GitHub API usage statistics already saved for a given hour and series
+// So multiple tool runs in the same hour are summed instead of overwritten
+func previousGHAPIStats(con *sql.DB, ctx *Ctx, t time.Time, series string) (st GHAPIEndpointStats, found bool) {
+	rows := QuerySQLWithErr(
+		con,
+		ctx,
+		fmt.Sprintf(
+			"select calls, retries, waits, wait_seconds, min_remaining from \"s%s\" "+
+				"where time = %s and series = %s and period = 'h'",
+			ghAPIStatsSeries,
+			NValue(1),
+			NValue(2),
+		),
+		t,
+		series,
+	)
+	defer func() { FatalOnError(rows.Close()) }()
+	var calls, retries, waits, waitSeconds, minRemaining float64
+	for rows.Next() {
+		FatalOnError(rows.Scan(&calls, &retries, &waits, &waitSeconds, &minRemaining))
+		st = GHAPIEndpointStats{
+			Calls:        int(calls),
+			Retries:      int(retries),
+			Waits:        int(waits),
+			WaitTime:     time.Duration(waitSeconds * float64(time.Second)),
+			MinRemaining: int(minRemaining),
+		}
+		found = true
+	}
+	FatalOnError(rows.Err())
+	return
+}
+
+// WriteGHAPIStats - writes GitHub API usage statistics collected so far to "sghapi" TS series
+// Series name is tool_endpoint (see GHAPIStatsSeriesName), period is "h", each tool run adds to the current hour values
+func WriteGHAPIStats(con *sql.DB, ctx *Ctx, tool string) {
+	stats := GetGHAPIStats()
+	if len(stats) == 0 {
+		return
+	}
+	if ctx.SkipTSDB {
+		if ctx.Debug > 0 {
+			Printf("Skipping GitHub API stats write: %+v\n", stats)
+		}
+		return
+	}
+	t := HourStart(time.Now())
+	exists := TableExists(con, ctx, "s"+ghAPIStatsSeries)
+	var pts TSPoints
+	for endpoint, st := range stats {
+		series := GHAPIStatsSeriesName(tool, endpoint)
+		if exists {
+			prev, found := previousGHAPIStats(con, ctx, t, series)
+			if found {
+				st.Calls += prev.Calls
+				st.Retries += prev.Retries
+				st.Waits += prev.Waits
+				st.WaitTime += prev.WaitTime
+				if st.MinRemaining < 0 || (prev.MinRemaining >= 0 && prev.MinRemaining < st.MinRemaining) {
+					st.MinRemaining = prev.MinRemaining
+				}
+			}
+		}
+		fields := map[string]interface{}{
+			"calls":         float64(st.Calls),
+			"retries":       float64(st.Retries),
+			"waits":         float64(st.Waits),
+			"wait_seconds":  st.WaitTime.Seconds(),
+			"remaining":     float64(st.Remaining),
+			"min_remaining": float64(st.MinRemaining),
+		}
+		AddTSPoint(ctx, &pts, NewTSPoint(ctx, series, "h", nil, fields, t))
+	}
+	WriteTSPoints(ctx, con, &pts, ghAPIStatsSeries, nil)
+	Printf("%s: written GitHub API usage stats for %d endpoint(s)\n", tool, len(stats))
+}
@@ -0,0 +1,378 @@
+package devstats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	lib "devstats"
+	testlib "devstats/test"
+
+	"github.com/google/go-github/github"
+)
+
+// fixtureIssueConfigs - gets all fake GitHub API issue events and creates issue configs
+// just like `ghapi2db` does, but without filtering
+func fixtureIssueConfigs(t *testing.T, ctx *lib.Ctx) map[int64]lib.IssueConfigAry {
+	gctx, gc := lib.GHClient(ctx)
+	ary := strings.Split(testlib.FixtureRepo, "/")
+	events, _, err := gc.Issues.ListRepositoryEvents(gctx, ary[0], ary[1], &github.ListOptions{})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return nil
+	}
+	issues := make(map[int64]lib.IssueConfigAry)
+	for _, event := range events {
+		issue := event.Issue
+		cfg := lib.IssueConfig{
+			Repo:      testlib.FixtureRepo,
+			EventID:   *event.ID,
+			IssueID:   *issue.ID,
+			EventType: *event.Event,
+			CreatedAt: *event.CreatedAt,
+			GhIssue:   issue,
+			GhEvent:   event,
+			Number:    *issue.Number,
+			Pr:        issue.IsPullRequest(),
+		}
+		if issue.Milestone != nil {
+			cfg.MilestoneID = issue.Milestone.ID
+		}
+		if issue.Assignee != nil {
+			cfg.AssigneeID = issue.Assignee.ID
+		}
+		cfg.LabelsMap = make(map[int64]string)
+		labels := []string{}
+		for _, label := range issue.Labels {
+			cfg.LabelsMap[*label.ID] = *label.Name
+			labels = append(labels, fmt.Sprintf("%d", *label.ID))
+		}
+		sort.Strings(labels)
+		cfg.Labels = strings.Join(labels, ",")
+		cfg.AssigneesMap = make(map[int64]string)
+		assignees := []string{}
+		for _, assignee := range issue.Assignees {
+			cfg.AssigneesMap[*assignee.ID] = *assignee.Login
+			assignees = append(assignees, fmt.Sprintf("%d", *assignee.ID))
+		}
+		sort.Strings(assignees)
+		cfg.Assignees = strings.Join(assignees, ",")
+		issues[cfg.IssueID] = append(issues[cfg.IssueID], cfg)
+	}
+	return issues
+}
+
+func TestSyncIssuesState(t *testing.T) {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Do not allow to run tests in "gha" database
+	if ctx.PgDB != "dbtest" {
+		t.Errorf("tests can only be run on \"dbtest\" database")
+		return
+	}
+
+	// Fake GitHub API
+	api := testlib.NewGitHubAPI()
+	defer api.Close()
+	testlib.LoadGitHubFixtures(api, testlib.YMDHMS(2018, 3, 1, 12))
+	ctx.GitHubOAuth = "-"
+	ctx.GitHubURL = api.URL()
+
+	// Drop database if exists
+	lib.DropDatabaseIfExists(&ctx)
+
+	// Create database if needed
+	createdDatabase := lib.CreateDatabaseIfNeeded(&ctx)
+	if !createdDatabase {
+		t.Errorf("failed to create database \"%s\"", ctx.PgDB)
+	}
+
+	// Create database structure
+	lib.Structure(&ctx)
+
+	// Connect to Postgres DB
+	c := lib.PgConn(&ctx)
+
+	// Drop database after tests
+	defer func() {
+		lib.FatalOnError(c.Close())
+		// Drop database after tests
+		lib.DropDatabaseIfExists(&ctx)
+	}()
+
+	// Repository must exist, artificial events reference it by name
+	lib.ExecSQLWithErr(
+		c,
+		&ctx,
+		"insert into gha_repos(id, name, org_id) "+lib.NValues(3),
+		lib.AnyArray{501, testlib.FixtureRepo, 1}...,
+	)
+
+	// Sync twice, second sync should not create any new data
+	gctx, gc := lib.GHClient(&ctx)
+	for i := 0; i < 2; i++ {
+		lib.SyncIssuesState(gctx, gc, &ctx, c, fixtureIssueConfigs(t, &ctx), map[int64]github.PullRequest{}, false)
+
+		// Test cases
+		var testCases = []struct {
+			query    string
+			expected int
+		}{
+			{query: "select count(*) from gha_events where id > 281474976710656", expected: 3},
+			{query: "select count(*) from gha_issues where id = 1001", expected: 2},
+			{query: "select count(*) from gha_issues where id = 1002 and is_pull_request", expected: 1},
+			{query: "select count(*) from gha_issues_labels where issue_id = 1001", expected: 4},
+			{query: "select count(*) from gha_issues_assignees where issue_id = 1001", expected: 4},
+			{query: "select count(*) from gha_milestones where id = 101", expected: 2},
+			{
+				query:    fmt.Sprintf("select count(*) from gha_issues where event_id = %d and milestone_id = 101", 281474976710656+3002),
+				expected: 1,
+			},
+		}
+		// Execute test cases
+		for index, test := range testCases {
+			got := 0
+			lib.FatalOnError(lib.QueryRowSQL(c, &ctx, test.query).Scan(&got))
+			if got != test.expected {
+				t.Errorf("sync %d, test number %d, expected %d, got %d (query: %s)", i+1, index+1, test.expected, got, test.query)
+			}
+		}
+	}
+
+	// Each sync gets issue events from the API once
+	if api.NCalls("events") != 2 {
+		t.Errorf("expected 2 events API calls, got %d", api.NCalls("events"))
+	}
+}
+
+func TestSyncReposMetadata(t *testing.T) {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Do not allow to run tests in "gha" database
+	if ctx.PgDB != "dbtest" {
+		t.Errorf("tests can only be run on \"dbtest\" database")
+		return
+	}
+
+	// Fake GitHub API
+	api := testlib.NewGitHubAPI()
+	defer api.Close()
+	testlib.LoadGitHubFixtures(api, testlib.YMDHMS(2018, 3, 1, 12))
+	ctx.GitHubOAuth = "-"
+	ctx.GitHubURL = api.URL()
+
+	// Drop database if exists
+	lib.DropDatabaseIfExists(&ctx)
+
+	// Create database if needed
+	createdDatabase := lib.CreateDatabaseIfNeeded(&ctx)
+	if !createdDatabase {
+		t.Errorf("failed to create database \"%s\"", ctx.PgDB)
+	}
+
+	// Create database structure
+	lib.Structure(&ctx)
+
+	// Connect to Postgres DB
+	c := lib.PgConn(&ctx)
+
+	// Drop database after tests
+	defer func() {
+		lib.FatalOnError(c.Close())
+		// Drop database after tests
+		lib.DropDatabaseIfExists(&ctx)
+	}()
+
+	// Fixture repo and a repo that is missing in the API, both with recent events
+	now := time.Now()
+	for i, repo := range []string{testlib.FixtureRepo, "fixture-org/missing"} {
+		lib.ExecSQLWithErr(
+			c,
+			&ctx,
+			"insert into gha_repos(id, name, org_id) "+lib.NValues(3),
+			lib.AnyArray{501 + i, repo, 1}...,
+		)
+		lib.ExecSQLWithErr(
+			c,
+			&ctx,
+			"insert into gha_events(id, type, actor_id, repo_id, public, created_at, dup_actor_login, dup_repo_name) "+lib.NValues(8),
+			lib.AnyArray{1 + i, "PushEvent", 1, 501 + i, true, now, "fixture-author", repo}...,
+		)
+	}
+
+	// First sync takes a snapshot of the fixture repo and marks missing repo as not found
+	// Second sync calls nothing: the fixture repo was just checked, missing repo is not checked again
+	// Third sync is after the fixture repo check expired: unchanged metadata only updates last checked date
+	gctx, gc := lib.GHClient(&ctx)
+	expectedCalls := []int{2, 2, 3}
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			lib.ExecSQLWithErr(
+				c,
+				&ctx,
+				"update gha_repos_metadata set last_checked = "+lib.NValue(1),
+				now.Add(-48*time.Hour),
+			)
+		}
+		lib.SyncReposMetadata(gctx, gc, &ctx, c)
+		if api.NCalls("repo") != expectedCalls[i] {
+			t.Errorf("sync %d, expected %d repo API calls, got %d", i+1, expectedCalls[i], api.NCalls("repo"))
+		}
+		var (
+			n           int
+			topics      string
+			license     string
+			lastChecked time.Time
+		)
+		lib.FatalOnError(
+			lib.QueryRowSQL(
+				c,
+				&ctx,
+				"select count(*), max(topics), max(license_key), max(last_checked) from gha_repos_metadata where id = 501 and not archived",
+			).Scan(&n, &topics, &license, &lastChecked),
+		)
+		if n != 1 || topics != "devstats,fixture" || license != "apache-2.0" || lastChecked.Before(now.Add(-time.Hour)) {
+			t.Errorf("sync %d, unexpected metadata snapshots: %d, '%s', '%s', %v", i+1, n, topics, license, lastChecked)
+		}
+		lib.FatalOnError(
+			lib.QueryRowSQL(c, &ctx, "select count(*) from gha_repos_metadata where id = 502 and not_found").Scan(&n),
+		)
+		if n != 1 {
+			t.Errorf("sync %d, expected one not found snapshot of the missing repo, got %d", i+1, n)
+		}
+	}
+
+	// Both repos are active
+	repos, _ := lib.GetRecentRepos(c, &ctx, now.Add(-time.Hour))
+	if len(repos) != 2 {
+		t.Errorf("expected 2 recent repos, got %v", repos)
+	}
+
+	// Archived repos are skipped and not checked again
+	archived := *api.Repos[testlib.FixtureRepo]
+	archived.Archived = github.Bool(true)
+	lib.ReposMetadataSnapshot(c, &ctx, 501, now.Add(time.Hour), &archived)
+	repos, _ = lib.GetRecentRepos(c, &ctx, now.Add(-time.Hour))
+	if len(repos) != 1 || repos[0] != "fixture-org/missing" {
+		t.Errorf("expected only non-archived recent repo, got %v", repos)
+	}
+	lib.SyncReposMetadata(gctx, gc, &ctx, c)
+	if api.NCalls("repo") != 3 {
+		t.Errorf("expected no repo API calls for archived repo, got %d calls", api.NCalls("repo"))
+	}
+}
+
+func TestSyncChecks(t *testing.T) {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Do not allow to run tests in "gha" database
+	if ctx.PgDB != "dbtest" {
+		t.Errorf("tests can only be run on \"dbtest\" database")
+		return
+	}
+
+	// Fake GitHub API
+	api := testlib.NewGitHubAPI()
+	defer api.Close()
+	testlib.LoadGitHubFixtures(api, testlib.YMDHMS(2018, 3, 1, 12))
+	ctx.GitHubOAuth = "-"
+	ctx.GitHubURL = api.URL()
+
+	// Drop database if exists
+	lib.DropDatabaseIfExists(&ctx)
+
+	// Create database if needed
+	createdDatabase := lib.CreateDatabaseIfNeeded(&ctx)
+	if !createdDatabase {
+		t.Errorf("failed to create database \"%s\"", ctx.PgDB)
+	}
+
+	// Create database structure
+	lib.Structure(&ctx)
+
+	// Connect to Postgres DB
+	c := lib.PgConn(&ctx)
+
+	// Drop database after tests
+	defer func() {
+		lib.FatalOnError(c.Close())
+		// Drop database after tests
+		lib.DropDatabaseIfExists(&ctx)
+	}()
+
+	// Recently updated PR (2 states with the same head SHA)
+	pr := api.Pulls[testlib.FixtureRepo][2]
+	now := time.Now()
+	for eid := 1; eid <= 2; eid++ {
+		lib.ExecSQLWithErr(
+			c,
+			&ctx,
+			"insert into gha_pull_requests(id, event_id, user_id, base_sha, head_sha, number, state, title, "+
+				"created_at, updated_at, dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, "+
+				"dup_created_at, dup_user_login) "+lib.NValues(17),
+			lib.AnyArray{
+				*pr.ID, eid, 1, *pr.Base.SHA, *pr.Head.SHA, *pr.Number, *pr.State, *pr.Title,
+				now, now, 1, "fixture-author", 501, testlib.FixtureRepo, "PullRequestEvent",
+				now, "fixture-author",
+			}...,
+		)
+	}
+
+	// Second sync gets the same data and only updates it
+	gctx, gc := lib.GHClient(&ctx)
+	for i := 0; i < 2; i++ {
+		lib.SyncChecks(gctx, gc, &ctx, c)
+
+		// Test cases
+		var testCases = []struct {
+			query    string
+			expected int
+		}{
+			{query: "select count(*) from gha_checks", expected: 4},
+			{query: "select count(*) from gha_checks where kind = 'check_run' and completed_at is not null", expected: 1},
+			{query: "select count(*) from gha_checks where kind = 'check_run' and status = 'in_progress'", expected: 1},
+			{query: "select count(*) from gha_checks where kind = 'status' and name = 'ci/lint'", expected: 2},
+			{query: "select count(*) from gha_checks where kind = 'status' and conclusion = 'failure' and completed_at is not null", expected: 1},
+			{query: "select count(*) from gha_checks where sha = '" + *pr.Head.SHA + "' and dup_repo_id = 501", expected: 4},
+		}
+		// Execute test cases
+		for index, test := range testCases {
+			got := 0
+			lib.FatalOnError(lib.QueryRowSQL(c, &ctx, test.query).Scan(&got))
+			if got != test.expected {
+				t.Errorf("sync %d, test number %d, expected %d, got %d (query: %s)", i+1, index+1, test.expected, got, test.query)
+			}
+		}
+	}
+
+	// Check run finished
+	run := api.CheckRuns[testlib.FixtureRepo][*pr.Head.SHA][1]
+	run.Status = github.String("completed")
+	run.Conclusion = github.String("failure")
+	run.CompletedAt = &now
+	lib.SyncChecks(gctx, gc, &ctx, c)
+	got := 0
+	lib.FatalOnError(
+		lib.QueryRowSQL(
+			c,
+			&ctx,
+			"select count(*) from gha_checks where kind = 'check_run' and status = 'completed' and completed_at is not null",
+		).Scan(&got),
+	)
+	if got != 2 {
+		t.Errorf("expected 2 completed check runs after update, got %d", got)
+	}
+
+	// Single PR head was processed 3 times
+	if api.NCalls("check-runs") != 3 || api.NCalls("statuses") != 3 {
+		t.Errorf("unexpected API calls: %+v", api.Calls)
+	}
+}
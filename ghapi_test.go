@@ -0,0 +1,229 @@
+package devstats
+
+import (
+	"testing"
+	"time"
+
+	lib "devstats"
+	testlib "devstats/test"
+
+	"github.com/google/go-github/github"
+)
+
+func TestGHClientBaseURL(t *testing.T) {
+	// Fake GitHub API
+	api := testlib.NewGitHubAPI()
+	defer api.Close()
+	testlib.LoadGitHubFixtures(api, testlib.YMDHMS(2018, 3, 1, 12))
+
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+	ctx.GitHubOAuth = "-"
+	ctx.GitHubURL = api.URL()
+
+	// Connect to fake GitHub API
+	gctx, gc := lib.GHClient(&ctx)
+	if gc.BaseURL.String() != api.URL() {
+		t.Errorf("expected base URL %s, got %s", api.URL(), gc.BaseURL.String())
+	}
+
+	// Get single issue
+	issue, _, err := gc.Issues.Get(gctx, "fixture-org", "fixture-repo", 1)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if *issue.ID != 1001 || len(issue.Labels) != 2 || len(issue.Assignees) != 2 || issue.IsPullRequest() {
+		t.Errorf("unexpected issue data: %+v", issue)
+	}
+
+	// Get single PR
+	pr, _, err := gc.PullRequests.Get(gctx, "fixture-org", "fixture-repo", 2)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if *pr.ID != 2001 || len(pr.RequestedReviewers) != 1 {
+		t.Errorf("unexpected PR data: %+v", pr)
+	}
+
+	// Missing issue must be detected as "not found"
+	_, _, err = gc.Issues.Get(gctx, "fixture-org", "fixture-repo", 3)
+	res := lib.HandlePossibleError(err, &lib.IssueConfig{Repo: testlib.FixtureRepo}, "Issues.Get")
+	if res != lib.NotFound {
+		t.Errorf("expected '%s' status, got '%s'", lib.NotFound, res)
+	}
+
+	// Get all events using 2 per page
+	opt := &github.ListOptions{PerPage: 2}
+	events := []*github.IssueEvent{}
+	nPages := 0
+	for {
+		page, response, err := gc.Issues.ListRepositoryEvents(gctx, "fixture-org", "fixture-repo", opt)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		nPages++
+		events = append(events, page...)
+		if response.NextPage == 0 {
+			break
+		}
+		opt.Page = response.NextPage
+	}
+	if nPages != 2 || len(events) != 3 {
+		t.Errorf("expected 3 events on 2 pages, got %d events on %d pages", len(events), nPages)
+	}
+	if api.NCalls("events") != 2 || api.NCalls("issue") != 2 || api.NCalls("pull") != 1 {
+		t.Errorf("unexpected API calls: %+v", api.Calls)
+	}
+}
+
+func TestWaitForRateLimit(t *testing.T) {
+	// Fake GitHub API
+	api := testlib.NewGitHubAPI()
+	defer api.Close()
+
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+	ctx.GitHubOAuth = "-"
+	ctx.GitHubURL = api.URL()
+	ctx.MinGHAPIPoints = 10
+	ctx.MaxGHAPIWaitSeconds = 5
+
+	// Test cases
+	var testCases = []struct {
+		remaining      int
+		reset          time.Duration
+		expectedStatus string
+		expectedWait   bool
+	}{
+		{remaining: 5000, reset: time.Hour, expectedStatus: "", expectedWait: false},
+		{remaining: 11, reset: time.Hour, expectedStatus: "", expectedWait: false},
+		{remaining: 10, reset: time.Hour, expectedStatus: lib.RateLimit, expectedWait: false},
+		{remaining: 0, reset: time.Hour, expectedStatus: lib.RateLimit, expectedWait: false},
+		{remaining: 0, reset: time.Duration(1) * time.Second, expectedStatus: lib.Retry, expectedWait: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		// New client, because client remembers rate limits and refuses calls until reset
+		api.SetCoreRate(test.remaining, test.reset)
+		gctx, gc := lib.GHClient(&ctx)
+		dtStart := time.Now()
+		rem, _, status := lib.WaitForRateLimit(gctx, gc, &ctx, "testing", 0)
+		waited := time.Now().Sub(dtStart) >= test.reset
+		if rem != test.remaining {
+			t.Errorf("test number %d, expected %d remaining points, got %d", index+1, test.remaining, rem)
+		}
+		if status != test.expectedStatus {
+			t.Errorf("test number %d, expected status '%s', got '%s'", index+1, test.expectedStatus, status)
+		}
+		if waited != test.expectedWait {
+			t.Errorf("test number %d, expected waiting %v, got %v", index+1, test.expectedWait, waited)
+		}
+	}
+}
+
+func TestListCheckRuns(t *testing.T) {
+	// Fake GitHub API
+	api := testlib.NewGitHubAPI()
+	defer api.Close()
+	testlib.LoadGitHubFixtures(api, testlib.YMDHMS(2018, 3, 1, 12))
+
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+	ctx.GitHubOAuth = "-"
+	ctx.GitHubURL = api.URL()
+
+	// Test cases
+	var testCases = []struct {
+		sha         string
+		expectedIDs []int
+	}{
+		{sha: "0000000000000000000000000000000000000002", expectedIDs: []int{4001, 4002}},
+		{sha: "0000000000000000000000000000000000000001", expectedIDs: []int{}},
+	}
+	// Execute test cases
+	gctx, gc := lib.GHClient(&ctx)
+	for index, test := range testCases {
+		runs, _, err := lib.ListCheckRuns(gctx, gc, "fixture-org", "fixture-repo", test.sha, &github.ListOptions{PerPage: 100})
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		got := []int{}
+		for _, run := range runs.CheckRuns {
+			got = append(got, int(*run.ID))
+		}
+		if *runs.TotalCount != len(test.expectedIDs) || !testlib.CompareIntSlices(got, test.expectedIDs) {
+			t.Errorf("test number %d, expected check runs %v, got %v", index+1, test.expectedIDs, got)
+		}
+	}
+}
+
+func TestGHAPIStats(t *testing.T) {
+	// Fake GitHub API
+	api := testlib.NewGitHubAPI()
+	defer api.Close()
+	testlib.LoadGitHubFixtures(api, testlib.YMDHMS(2018, 3, 1, 12))
+
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+	ctx.GitHubOAuth = "-"
+	ctx.GitHubURL = api.URL()
+	ctx.MinGHAPIPoints = 10
+
+	// Start with empty stats
+	lib.ResetGHAPIStats()
+	cfg := &lib.IssueConfig{Repo: testlib.FixtureRepo}
+
+	// Remaining points are recorded before each call, new client, because client remembers rate limits
+	for _, remaining := range []int{100, 50, 80} {
+		api.SetCoreRate(remaining, time.Hour)
+		gctx, gc := lib.GHClient(&ctx)
+		lib.WaitForRateLimit(gctx, gc, &ctx, "Issues.Get", 0)
+	}
+
+	// Existing and missing issue, not found is not retried
+	gctx, gc := lib.GHClient(&ctx)
+	_, _, err := gc.Issues.Get(gctx, "fixture-org", "fixture-repo", 1)
+	lib.HandlePossibleError(err, cfg, "Issues.Get")
+	_, _, err = gc.Issues.Get(gctx, "fixture-org", "fixture-repo", 3)
+	lib.HandlePossibleError(err, cfg, "Issues.Get")
+
+	// Abuse detection is retried after waiting
+	lib.HandlePossibleError(&github.AbuseRateLimitError{}, cfg, "PullRequests.Get")
+	lib.RecordGHAPIWait("PullRequests.Get", time.Duration(8)*time.Second)
+	lib.HandlePossibleError(nil, cfg, "PullRequests.Get")
+
+	// Check collected stats
+	stats := lib.GetGHAPIStats()
+	expected := map[string]lib.GHAPIEndpointStats{
+		"Issues.Get":       {Calls: 2, Retries: 0, Waits: 0, Remaining: 80, MinRemaining: 50},
+		"PullRequests.Get": {Calls: 2, Retries: 1, Waits: 1, WaitTime: time.Duration(8) * time.Second, Remaining: -1, MinRemaining: -1},
+	}
+	if len(stats) != len(expected) {
+		t.Errorf("expected stats for %d endpoints, got %+v", len(expected), stats)
+	}
+	for endpoint, exp := range expected {
+		got, ok := stats[endpoint]
+		if !ok || got != exp {
+			t.Errorf("endpoint %s: expected %+v, got %+v", endpoint, exp, got)
+		}
+	}
+
+	// Series names
+	if name := lib.GHAPIStatsSeriesName("ghapi2db", "Issues.ListRepositoryEvents"); name != "ghapi2db_issues_listrepositoryevents" {
+		t.Errorf("unexpected series name: %s", name)
+	}
+
+	// Reset
+	lib.ResetGHAPIStats()
+	if stats := lib.GetGHAPIStats(); len(stats) != 0 {
+		t.Errorf("expected empty stats after reset, got %+v", stats)
+	}
+}
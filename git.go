@@ -0,0 +1,675 @@
+package devstats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitFile - file changed by a commit
+// Size can be:
+// > 0 - normal file size
+// 0 - file created - no contents
+// -1 - file referenced in the commit but not found in it (means deleted)
+// -2 - special file (submodule)
+// Added and Removed are numbers of lines added and removed by the commit (like `git diff-tree --numstat`)
+// They are nil for binary files and submodules
+type GitFile struct {
+	Path    string
+	Size    int64
+	Added   *int
+	Removed *int
+}
+
+// GitRepo - in-process access to local git repository objects
+// Uses a long running `git cat-file --batch` process per repo
+// It is not thread safe, use one GitRepo per thread
+type GitRepo struct {
+	Path     string
+	contents *gitCatFile
+	shallow  map[string]struct{}
+}
+
+// gitCatFile - single `git cat-file` process
+type gitCatFile struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+// gitTreeEntry - single tree entry, mode is octal string as stored in git ("100644", "40000", "160000")
+type gitTreeEntry struct {
+	mode string
+	name string
+	sha  string
+}
+
+// gitChange - file changed between two trees, empty sha means file not present on a given side
+type gitChange struct {
+	path    string
+	oldSha  string
+	oldMode string
+	newSha  string
+	newMode string
+}
+
+// Git tree entries modes
+const (
+	gitModeTree    = "40000"
+	gitModeGitlink = "160000"
+)
+
+// GitRenameScore - minimum similarity of deleted and added file to be reported as rename (like `git diff-tree -M7`)
+const GitRenameScore = 0.7
+
+// gitRenameLimit - inexact renames are only detected when deleted * added files <= gitRenameLimit^2 (like `diff.renameLimit`)
+const gitRenameLimit = 1000
+
+// gitRenameCacheBytes - max size of deleted files contents cached while detecting inexact renames of a single commit
+const gitRenameCacheBytes = 128 << 20
+
+// gitBinaryCheck - file is binary when there is a NUL byte in its first gitBinaryCheck bytes (like git does)
+const gitBinaryCheck = 8000
+
+// gitDiffMaxWork - max number of steps of exact lines diff, above that changed lines are estimated
+const gitDiffMaxWork = 50000000
+
+func startGitCatFile(path, mode string) (*gitCatFile, error) {
+	cmd := exec.Command("git", "cat-file", mode)
+	cmd.Dir = path
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+	return &gitCatFile{cmd: cmd, in: in, out: bufio.NewReader(out)}, nil
+}
+
+// header - requests object and parses "<sha> <type> <size>" response header
+func (c *gitCatFile) header(sha string) (typ string, size int64, err error) {
+	if sha == "" || strings.ContainsAny(sha, " \t\r\n") {
+		err = fmt.Errorf("invalid object name '%s'", sha)
+		return
+	}
+	_, err = io.WriteString(c.in, sha+"\n")
+	if err != nil {
+		return
+	}
+	line, err := c.out.ReadString('\n')
+	if err != nil {
+		return
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 2 && fields[1] == "missing" {
+		err = fmt.Errorf("object %s not found", sha)
+		return
+	}
+	if len(fields) != 3 {
+		err = fmt.Errorf("invalid git cat-file response for %s: '%s'", sha, strings.TrimSpace(line))
+		return
+	}
+	typ = fields[1]
+	size, err = strconv.ParseInt(fields[2], 10, 64)
+	return
+}
+
+func (c *gitCatFile) close() error {
+	err := c.in.Close()
+	errWait := c.cmd.Wait()
+	if err != nil {
+		return err
+	}
+	return errWait
+}
+
+// NewGitRepo - starts git processes for a repository in a given directory
+func NewGitRepo(path string) (*GitRepo, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("%s: exists, but is not a directory", path)
+	}
+	contents, err := startGitCatFile(path, "--batch")
+	if err != nil {
+		return nil, err
+	}
+	// Commits at the boundary of a shallow clone have parents that are not available, git treats them as root commits
+	shallow := make(map[string]struct{})
+	for _, fn := range []string{"/.git/shallow", "/shallow"} {
+		data, err := ioutil.ReadFile(strings.TrimSuffix(path, "/") + fn)
+		if err != nil {
+			continue
+		}
+		for _, sha := range strings.Fields(string(data)) {
+			shallow[sha] = struct{}{}
+		}
+	}
+	return &GitRepo{Path: path, contents: contents, shallow: shallow}, nil
+}
+
+// Close - stops git process
+func (r *GitRepo) Close() error {
+	return r.contents.close()
+}
+
+// object - returns object contents, fails when object has other type than expected
+func (r *GitRepo) object(sha, expectedType string) ([]byte, error) {
+	typ, size, err := r.contents.header(sha)
+	if err != nil {
+		return nil, err
+	}
+	// Contents is followed by a newline
+	data := make([]byte, size+1)
+	_, err = io.ReadFull(r.contents.out, data)
+	if err != nil {
+		return nil, err
+	}
+	if typ != expectedType {
+		return nil, fmt.Errorf("object %s is %s, expected %s", sha, typ, expectedType)
+	}
+	return data[:size], nil
+}
+
+// commit - returns commit tree, parents and committer date
+func (r *GitRepo) commit(sha string) (tree string, parents []string, dt time.Time, err error) {
+	data, err := r.object(sha, "commit")
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "tree "):
+			tree = line[5:]
+		case strings.HasPrefix(line, "parent "):
+			parents = append(parents, line[7:])
+		case strings.HasPrefix(line, "committer "):
+			// committer Name <email> unix_timestamp timezone
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				err = fmt.Errorf("invalid committer in commit %s: '%s'", sha, line)
+				return
+			}
+			var ts int64
+			ts, err = strconv.ParseInt(fields[len(fields)-2], 10, 64)
+			if err != nil {
+				err = fmt.Errorf("invalid committer time in commit %s: '%s'", sha, line)
+				return
+			}
+			dt = time.Unix(ts, 0)
+		}
+	}
+	if tree == "" || dt.IsZero() {
+		err = fmt.Errorf("invalid commit %s: no tree or committer", sha)
+	}
+	return
+}
+
+// tree - returns tree entries, tree format is a list of "<mode> <name>\0<20 bytes sha>"
+func (r *GitRepo) tree(sha string) (entries []gitTreeEntry, err error) {
+	if sha == "" {
+		return
+	}
+	data, err := r.object(sha, "tree")
+	if err != nil {
+		return
+	}
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if sp < 0 || nul < sp || len(data) < nul+21 {
+			err = fmt.Errorf("invalid tree %s", sha)
+			return
+		}
+		entries = append(
+			entries,
+			gitTreeEntry{
+				mode: string(data[:sp]),
+				name: string(data[sp+1 : nul]),
+				sha:  hex.EncodeToString(data[nul+1 : nul+21]),
+			},
+		)
+		data = data[nul+21:]
+	}
+	return
+}
+
+// diffTrees - returns files changed between two trees recursively (like `git diff-tree -r`)
+// Directory replaced by a file (or the other way round) is reported as all directory files deleted (added) and a file added (deleted)
+func (r *GitRepo) diffTrees(oldTree, newTree, prefix string) (changes []gitChange, err error) {
+	oldEntries, err := r.tree(oldTree)
+	if err != nil {
+		return
+	}
+	newEntries, err := r.tree(newTree)
+	if err != nil {
+		return
+	}
+	olds := make(map[string]gitTreeEntry)
+	names := []string{}
+	for _, entry := range oldEntries {
+		olds[entry.name] = entry
+		names = append(names, entry.name)
+	}
+	news := make(map[string]gitTreeEntry)
+	for _, entry := range newEntries {
+		news[entry.name] = entry
+		if _, ok := olds[entry.name]; !ok {
+			names = append(names, entry.name)
+		}
+	}
+	// Order like git: directories are compared as if their names ended with "/", so "a.go" comes before "a/"
+	sortKey := func(name string) string {
+		if olds[name].mode == gitModeTree || news[name].mode == gitModeTree {
+			return name + "/"
+		}
+		return name
+	}
+	sort.Slice(names, func(i, j int) bool { return sortKey(names[i]) < sortKey(names[j]) })
+	for _, name := range names {
+		o, oldOK := olds[name]
+		n, newOK := news[name]
+		if oldOK && newOK && o.sha == n.sha && o.mode == n.mode {
+			continue
+		}
+		path := prefix + name
+		oldDir := oldOK && o.mode == gitModeTree
+		newDir := newOK && n.mode == gitModeTree
+		if oldDir || newDir {
+			oldSub, newSub := "", ""
+			if oldDir {
+				oldSub = o.sha
+			}
+			if newDir {
+				newSub = n.sha
+			}
+			var sub []gitChange
+			sub, err = r.diffTrees(oldSub, newSub, path+"/")
+			if err != nil {
+				return
+			}
+			changes = append(changes, sub...)
+		}
+		change := gitChange{path: path}
+		if oldOK && !oldDir {
+			change.oldSha, change.oldMode = o.sha, o.mode
+		}
+		if newOK && !newDir {
+			change.newSha, change.newMode = n.sha, n.mode
+		}
+		if change.oldSha != "" || change.newSha != "" {
+			changes = append(changes, change)
+		}
+	}
+	return
+}
+
+// gitChunks - splits data into lines (max 64 bytes each) and returns chunk -> bytes map (similar to git's similarity estimation)
+func gitChunks(data []byte) map[string]int {
+	chunks := make(map[string]int)
+	for len(data) > 0 {
+		n := bytes.IndexByte(data, '\n') + 1
+		if n <= 0 || n > 64 {
+			n = 64
+			if n > len(data) {
+				n = len(data)
+			}
+		}
+		chunks[string(data[:n])] += n
+		data = data[n:]
+	}
+	return chunks
+}
+
+// gitBlob - file contents used to detect renames, its chunks are computed once and then contents is dropped
+type gitBlob struct {
+	size   int
+	data   []byte
+	chunks map[string]int
+}
+
+func newGitBlob(data []byte) *gitBlob {
+	return &gitBlob{size: len(data), data: data}
+}
+
+// getChunks - returns blob chunks, computes them on first use
+func (b *gitBlob) getChunks() map[string]int {
+	if b.chunks == nil {
+		b.chunks = gitChunks(b.data)
+		b.data = nil
+	}
+	return b.chunks
+}
+
+// similarity - returns similarity (0-1) of two blobs: bytes of common chunks / size of the bigger blob
+func (b *gitBlob) similarity(dst *gitBlob) float64 {
+	bigger, smaller := b.size, dst.size
+	if smaller > bigger {
+		bigger, smaller = smaller, bigger
+	}
+	if bigger == 0 {
+		return 1.0
+	}
+	// Files with too different sizes cannot be similar enough, chunks are not even computed then
+	if float64(smaller) < float64(bigger)*GitRenameScore {
+		return 0.0
+	}
+	srcChunks := b.getChunks()
+	copied := 0
+	for chunk, n := range dst.getChunks() {
+		m := srcChunks[chunk]
+		if m < n {
+			n = m
+		}
+		copied += n
+	}
+	return float64(copied) / float64(bigger)
+}
+
+// GitSimilarity - returns similarity (0-1) of two file contents: bytes of common chunks / size of the bigger file
+func GitSimilarity(src, dst []byte) float64 {
+	return newGitBlob(src).similarity(newGitBlob(dst))
+}
+
+// gitLines - splits data into lines, each line includes its terminating newline (if any)
+// Lines are returned as ids, equal lines (also between calls using the same ids map) have equal ids
+func gitLines(data []byte, ids map[string]int) (lines []int) {
+	for len(data) > 0 {
+		n := bytes.IndexByte(data, '\n') + 1
+		if n <= 0 {
+			n = len(data)
+		}
+		line := string(data[:n])
+		id, ok := ids[line]
+		if !ok {
+			id = len(ids)
+			ids[line] = id
+		}
+		lines = append(lines, id)
+		data = data[n:]
+	}
+	return
+}
+
+// gitEditDistance - returns minimal number of lines added and removed to transform a into b (Myers algorithm)
+// Returns -1 when this requires more than gitDiffMaxWork steps
+func gitEditDistance(a, b []int) int {
+	n, m := len(a), len(b)
+	maxD := n + m
+	if maxD > 0 && gitDiffMaxWork/maxD < maxD {
+		maxD = gitDiffMaxWork / maxD
+	}
+	// v[off+k] - furthest x reached on diagonal k = x - y
+	off := maxD + 1
+	v := make([]int, 2*maxD+3)
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return d
+			}
+		}
+	}
+	return -1
+}
+
+// GitLineChanges - returns number of lines added and removed when changing old contents into new (like `git diff --numstat`)
+// It uses minimal diff, git can report slightly bigger numbers for complex changes because it uses heuristics
+// For very big and very different files it falls back to comparing sets of lines, which can underestimate changes
+func GitLineChanges(old, new []byte) (added, removed int) {
+	ids := make(map[string]int)
+	a, b := gitLines(old, ids), gitLines(new, ids)
+	// Skip common prefix and suffix
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return len(b), len(a)
+	}
+	d := gitEditDistance(a, b)
+	if d >= 0 {
+		// d = added + removed, len(b) - len(a) = added - removed
+		added = (d + len(b) - len(a)) / 2
+		removed = d - added
+		return
+	}
+	counts := make(map[int]int)
+	for _, line := range a {
+		counts[line]++
+	}
+	for _, line := range b {
+		if counts[line] > 0 {
+			counts[line]--
+		} else {
+			added++
+		}
+	}
+	for _, count := range counts {
+		removed += count
+	}
+	return
+}
+
+// gitBinary - checks if file contents is binary
+func gitBinary(data []byte) bool {
+	if len(data) > gitBinaryCheck {
+		data = data[:gitBinaryCheck]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// detectRenames - removes deleted files that were renamed to added files (like `git diff-tree -M7 --name-only`, which only lists new names)
+// Added file that is a result of rename gets deleted file's old sha and mode
+func (r *GitRepo) detectRenames(changes []gitChange) ([]gitChange, error) {
+	deleted := []int{}
+	added := []int{}
+	for i, change := range changes {
+		if change.newSha == "" && change.oldMode != gitModeGitlink {
+			deleted = append(deleted, i)
+		}
+		if change.oldSha == "" && change.newMode != gitModeGitlink {
+			added = append(added, i)
+		}
+	}
+	if len(deleted) == 0 || len(added) == 0 {
+		return changes, nil
+	}
+	// renamed: deleted file -> true, sources: added file -> deleted file it was renamed from
+	renamed := make(map[int]bool)
+	sources := make(map[int]int)
+	// Exact renames
+	for _, a := range added {
+		for _, d := range deleted {
+			if !renamed[d] && changes[d].oldSha == changes[a].newSha {
+				renamed[d] = true
+				sources[a] = d
+				break
+			}
+		}
+	}
+	// Inexact renames, best scores first
+	type pair struct {
+		score float64
+		a, d  int
+	}
+	pairs := []pair{}
+	if len(deleted)*len(added) <= gitRenameLimit*gitRenameLimit {
+		// Deleted blobs are cached (with their chunks computed once, like git's spanhash), up to gitRenameCacheBytes
+		blobs := make(map[string]*gitBlob)
+		cached := 0
+		deletedBlob := func(sha string) (*gitBlob, error) {
+			blob, ok := blobs[sha]
+			if ok {
+				return blob, nil
+			}
+			data, err := r.object(sha, "blob")
+			if err != nil {
+				return nil, err
+			}
+			blob = newGitBlob(data)
+			if cached+len(data) <= gitRenameCacheBytes {
+				blobs[sha] = blob
+				cached += len(data)
+			}
+			return blob, nil
+		}
+		for _, a := range added {
+			if _, ok := sources[a]; ok {
+				continue
+			}
+			data, err := r.object(changes[a].newSha, "blob")
+			if err != nil {
+				return nil, err
+			}
+			dst := newGitBlob(data)
+			for _, d := range deleted {
+				if renamed[d] {
+					continue
+				}
+				src, err := deletedBlob(changes[d].oldSha)
+				if err != nil {
+					return nil, err
+				}
+				score := src.similarity(dst)
+				if score >= GitRenameScore {
+					pairs = append(pairs, pair{score: score, a: a, d: d})
+				}
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].score > pairs[j].score })
+	for _, p := range pairs {
+		if _, ok := sources[p.a]; ok || renamed[p.d] {
+			continue
+		}
+		sources[p.a] = p.d
+		renamed[p.d] = true
+	}
+	// Renamed file is compared with its previous version
+	for a, d := range sources {
+		changes[a].oldSha = changes[d].oldSha
+		changes[a].oldMode = changes[d].oldMode
+	}
+	result := []gitChange{}
+	for i, change := range changes {
+		if !renamed[i] {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
+// blob - returns file contents, no contents for missing files and submodules
+func (r *GitRepo) blob(sha, mode string) ([]byte, error) {
+	if sha == "" || mode == gitModeGitlink {
+		return nil, nil
+	}
+	return r.object(sha, "blob")
+}
+
+// lineChanges - sets file's size and numbers of added and removed lines, lines are not set for binary files and submodules
+func (r *GitRepo) lineChanges(change gitChange, file *GitFile) error {
+	if change.newMode == gitModeGitlink || (change.newSha == "" && change.oldMode == gitModeGitlink) {
+		return nil
+	}
+	newData, err := r.blob(change.newSha, change.newMode)
+	if err != nil {
+		return err
+	}
+	if change.newSha != "" {
+		file.Size = int64(len(newData))
+	}
+	oldData := newData
+	if change.oldSha != change.newSha {
+		oldData, err = r.blob(change.oldSha, change.oldMode)
+		if err != nil {
+			return err
+		}
+	}
+	if gitBinary(oldData) || gitBinary(newData) {
+		return nil
+	}
+	added, removed := GitLineChanges(oldData, newData)
+	file.Added, file.Removed = &added, &removed
+	return nil
+}
+
+// CommitFiles - returns commit date and files changed by the commit with their sizes in the commit and changed lines
+// Like `git diff-tree -M7 -r` it returns no files for root commits and merge commits
+// Renamed files lines are counted against their previous version
+// Files with paths matching skip (if not nil) are not returned, they are still used to detect renames
+func (r *GitRepo) CommitFiles(sha string, skip *regexp.Regexp) (dt time.Time, files []GitFile, err error) {
+	tree, parents, dt, err := r.commit(sha)
+	if err != nil || len(parents) != 1 {
+		return
+	}
+	if _, ok := r.shallow[sha]; ok {
+		return
+	}
+	parentTree, _, _, err := r.commit(parents[0])
+	if err != nil {
+		return
+	}
+	changes, err := r.diffTrees(parentTree, tree, "")
+	if err != nil {
+		return
+	}
+	changes, err = r.detectRenames(changes)
+	if err != nil {
+		return
+	}
+	for _, change := range changes {
+		if skip != nil && skip.MatchString(change.path) {
+			continue
+		}
+		file := GitFile{Path: change.path}
+		switch {
+		case change.newSha == "":
+			file.Size = -1
+		case change.newMode == gitModeGitlink:
+			file.Size = -2
+		}
+		err = r.lineChanges(change, &file)
+		if err != nil {
+			return
+		}
+		files = append(files, file)
+	}
+	return
+}
@@ -0,0 +1,222 @@
+package devstats
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	lib "devstats"
+)
+
+// gitTestRepo - creates temporary git repository, returns its path and function running git commands in it
+func gitTestRepo(t *testing.T) (string, func(args ...string) string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir, err := ioutil.TempDir("", "devstats_git")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(
+			os.Environ(),
+			"GIT_AUTHOR_NAME=Author",
+			"GIT_AUTHOR_EMAIL=author@example.com",
+			"GIT_AUTHOR_DATE=2018-01-02T10:00:00Z",
+			"GIT_COMMITTER_NAME=Committer",
+			"GIT_COMMITTER_EMAIL=committer@example.com",
+			"GIT_COMMITTER_DATE=2018-01-03T12:00:00Z",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	return dir, git
+}
+
+func writeTestFile(t *testing.T, dir, name, data string) {
+	path := filepath.Join(dir, name)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(path, []byte(data), 0644)
+	}
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestGitRepoCommitFiles(t *testing.T) {
+	dir, git := gitTestRepo(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// Root commit
+	long := strings.Repeat("line of a file that will be renamed and slightly modified\n", 20)
+	writeTestFile(t, dir, "README.md", "readme\n")
+	writeTestFile(t, dir, "src/main.go", "package main\n")
+	writeTestFile(t, dir, "src/old.go", long)
+	writeTestFile(t, dir, "moved.txt", "moved as is\n")
+	writeTestFile(t, dir, "docs/a b♂♀c.md", "odd name\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "root")
+	root := git("rev-parse", "HEAD")
+
+	// Modify, add, delete, exact and inexact renames
+	writeTestFile(t, dir, "src/main.go", "package main\n\nfunc main() {}\n")
+	writeTestFile(t, dir, "empty.txt", "")
+	writeTestFile(t, dir, "src/new.go", long+"one more line\n")
+	writeTestFile(t, dir, "other/moved.txt", "moved as is\n")
+	writeTestFile(t, dir, "docs/a b♂♀c.md", "odd name changed\n")
+	writeTestFile(t, dir, "bin.dat", "binary\x00data\n")
+	writeTestFile(t, dir, "src.go", "package src\n")
+	git("rm", "-q", "README.md", "src/old.go", "moved.txt")
+	git("add", "-A")
+	git("commit", "-q", "-m", "changes")
+	changes := git("rev-parse", "HEAD")
+
+	// Branch and merge
+	git("checkout", "-q", "-b", "branch", root)
+	writeTestFile(t, dir, "branch.txt", "branch\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "branch")
+	git("checkout", "-q", "-")
+	git("merge", "-q", "--no-edit", "branch")
+	merge := git("rev-parse", "HEAD")
+
+	repo, err := lib.NewGitRepo(dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer func() { _ = repo.Close() }()
+
+	dt, files, err := repo.CommitFiles(changes, nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if dt.UTC().Format("2006-01-02 15:04:05") != "2018-01-03 12:00:00" {
+		t.Errorf("expected commit date 2018-01-03 12:00:00, got %v", dt.UTC())
+	}
+	lines := func(n int) *int { return &n }
+	expected := []lib.GitFile{
+		{Path: "README.md", Size: -1, Added: lines(0), Removed: lines(1)},
+		{Path: "bin.dat", Size: 12},
+		{Path: "docs/a b♂♀c.md", Size: 17, Added: lines(1), Removed: lines(1)},
+		{Path: "empty.txt", Size: 0, Added: lines(0), Removed: lines(0)},
+		{Path: "other/moved.txt", Size: 12, Added: lines(0), Removed: lines(0)},
+		{Path: "src.go", Size: 12, Added: lines(1), Removed: lines(0)},
+		{Path: "src/main.go", Size: 29, Added: lines(2), Removed: lines(0)},
+		{Path: "src/new.go", Size: int64(len(long) + 14), Added: lines(1), Removed: lines(0)},
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected files:\n%+v\ngot:\n%+v", expected, files)
+	}
+
+	// Changed lines must be the same as reported by git, -z format is "added\tremoved\tpath\0" or "added\tremoved\t\0old path\0new path\0"
+	numstat := make(map[string]string)
+	fields := strings.Split(git("diff-tree", "--numstat", "-z", "-M7", "-r", "--no-commit-id", changes), "\x00")
+	for i := 0; i < len(fields); i++ {
+		ary := strings.Split(fields[i], "\t")
+		if len(ary) != 3 {
+			continue
+		}
+		path := ary[2]
+		if path == "" && i+2 < len(fields) {
+			path = fields[i+2]
+			i += 2
+		}
+		numstat[path] = ary[0] + "\t" + ary[1]
+	}
+	if len(numstat) != len(files) {
+		t.Errorf("git numstat reports %d files, got %d", len(numstat), len(files))
+	}
+	for _, file := range files {
+		got := "-\t-"
+		if file.Added != nil {
+			got = fmt.Sprintf("%d\t%d", *file.Added, *file.Removed)
+		}
+		if got != numstat[file.Path] {
+			t.Errorf("%s: git numstat reports '%s', got '%s'", file.Path, numstat[file.Path], got)
+		}
+	}
+
+	// Skipped files are not returned
+	_, files, err = repo.CommitFiles(changes, regexp.MustCompile(`(^|/)src/`))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !reflect.DeepEqual(files, expected[:len(expected)-2]) {
+		t.Errorf("expected files with skip pattern:\n%+v\ngot:\n%+v", expected[:len(expected)-2], files)
+	}
+
+	// Root and merge commits have no files, like `git diff-tree`
+	for _, sha := range []string{root, merge} {
+		_, files, err = repo.CommitFiles(sha, nil)
+		if err != nil || len(files) != 0 {
+			t.Errorf("expected no files for %s, got %+v, %v", sha, files, err)
+		}
+	}
+
+	// Missing commit is an error, but repo can still be used
+	_, _, err = repo.CommitFiles("0123456789012345678901234567890123456789", nil)
+	if err == nil {
+		t.Errorf("expected error for missing commit")
+	}
+	_, files, err = repo.CommitFiles(changes, nil)
+	if err != nil || len(files) != len(expected) {
+		t.Errorf("expected %d files after missing commit, got %+v, %v", len(expected), files, err)
+	}
+}
+
+func TestGitSimilarity(t *testing.T) {
+	var testCases = []struct {
+		src, dst string
+		min, max float64
+	}{
+		{src: "", dst: "", min: 1.0, max: 1.0},
+		{src: "a\nb\nc\n", dst: "a\nb\nc\n", min: 1.0, max: 1.0},
+		{src: "a\nb\nc\nd\n", dst: "a\nb\nc\nx\n", min: 0.75, max: 0.75},
+		{src: "a\nb\nc\nd\n", dst: "x\ny\nz\nd\n", min: 0.25, max: 0.25},
+		{src: strings.Repeat("x", 100), dst: "x", min: 0.0, max: 0.0},
+	}
+	for index, test := range testCases {
+		got := lib.GitSimilarity([]byte(test.src), []byte(test.dst))
+		if got < test.min || got > test.max {
+			t.Errorf("test number %d: expected similarity in [%f, %f], got %f", index+1, test.min, test.max, got)
+		}
+	}
+}
+
+func TestGitLineChanges(t *testing.T) {
+	var testCases = []struct {
+		old, new       string
+		added, removed int
+	}{
+		{old: "", new: "", added: 0, removed: 0},
+		{old: "", new: "a\nb\n", added: 2, removed: 0},
+		{old: "a\nb\n", new: "", added: 0, removed: 2},
+		{old: "a\nb", new: "a\nb\n", added: 1, removed: 1},
+		{old: "a\nb\nc\n", new: "a\nx\nc\n", added: 1, removed: 1},
+		{old: "a\nb\nc\nd\n", new: "b\nc\nd\ne\nf\n", added: 2, removed: 1},
+		{old: "a\nb\nc\n", new: "c\nb\na\n", added: 2, removed: 2},
+		{old: "x\nx\nx\n", new: "x\n", added: 0, removed: 2},
+	}
+	for index, test := range testCases {
+		added, removed := lib.GitLineChanges([]byte(test.old), []byte(test.new))
+		if added != test.added || removed != test.removed {
+			t.Errorf(
+				"test number %d: expected +%d -%d, got +%d -%d",
+				index+1, test.added, test.removed, added, removed,
+			)
+		}
+	}
+}
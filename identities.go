@@ -0,0 +1,304 @@
+package devstats
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+)
+
+// Identity kinds stored in gha_identities.kind
+const (
+	IdentityLogin = "login"
+	IdentityEmail = "email"
+)
+
+// Commit emails are only linked to a pusher login when that login pushed at least IdentityMinCommits commits with
+// that email and they are at least IdentityMinShare of all commits pushed by that login (maintainers also push
+// commits authored by others). Emails linked to more than IdentityMaxLogins logins by commits are ignored.
+const (
+	IdentityMinCommits = 3
+	IdentityMinShare   = 0.25
+	IdentityMaxLogins  = 3
+)
+
+// Identities - groups logins and emails belonging to the same person
+type Identities struct {
+	parent map[string]string
+}
+
+// NewIdentities - returns empty identities
+func NewIdentities() *Identities {
+	return &Identities{parent: make(map[string]string)}
+}
+
+// identityKey - returns key of a given identity, values are case insensitive
+func identityKey(kind, value string) string {
+	return kind + ":" + strings.ToLower(strings.TrimSpace(value))
+}
+
+// find - returns root key of the group that contains given key
+func (ids *Identities) find(key string) string {
+	parent, ok := ids.parent[key]
+	if !ok {
+		ids.parent[key] = key
+		return key
+	}
+	if parent == key {
+		return key
+	}
+	root := ids.find(parent)
+	ids.parent[key] = root
+	return root
+}
+
+// union - merges groups of given keys
+func (ids *Identities) union(key1, key2 string) {
+	root1, root2 := ids.find(key1), ids.find(key2)
+	if root1 != root2 {
+		ids.parent[root2] = root1
+	}
+}
+
+// NoreplyLogin - returns login encoded in GitHub noreply email: "123+login@users.noreply.github.com" or
+// "login@users.noreply.github.com", returns empty string for other emails
+func NoreplyLogin(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	suffix := "@users.noreply.github.com"
+	if !strings.HasSuffix(email, suffix) {
+		return ""
+	}
+	login := strings.TrimSuffix(email, suffix)
+	i := strings.Index(login, "+")
+	if i >= 0 {
+		login = login[i+1:]
+	}
+	return login
+}
+
+// Link - marks login and email as belonging to the same person
+// GitHub noreply emails also link the login they encode
+func (ids *Identities) Link(login, email string) {
+	if strings.TrimSpace(login) == "" || strings.TrimSpace(email) == "" {
+		return
+	}
+	emailKey := identityKey(IdentityEmail, email)
+	ids.union(identityKey(IdentityLogin, login), emailKey)
+	noreply := NoreplyLogin(email)
+	if noreply != "" {
+		ids.union(identityKey(IdentityLogin, noreply), emailKey)
+	}
+}
+
+// LinkLogins - marks two logins as belonging to the same person, for example logins of a renamed GitHub account
+func (ids *Identities) LinkLogins(login1, login2 string) {
+	if strings.TrimSpace(login1) == "" || strings.TrimSpace(login2) == "" {
+		return
+	}
+	ids.union(identityKey(IdentityLogin, login1), identityKey(IdentityLogin, login2))
+}
+
+// Persons - returns person id for all logins and emails: map kind -> value -> person id
+// Person id is the lowest login in a group, groups without logins are skipped
+func (ids *Identities) Persons() map[string]map[string]string {
+	loginPrefix := IdentityLogin + ":"
+	personIDs := make(map[string]string)
+	for key := range ids.parent {
+		if !strings.HasPrefix(key, loginPrefix) {
+			continue
+		}
+		root := ids.find(key)
+		login := key[len(loginPrefix):]
+		personID, ok := personIDs[root]
+		if !ok || login < personID {
+			personIDs[root] = login
+		}
+	}
+	persons := map[string]map[string]string{IdentityLogin: {}, IdentityEmail: {}}
+	for key := range ids.parent {
+		personID, ok := personIDs[ids.find(key)]
+		if !ok {
+			continue
+		}
+		ary := strings.SplitN(key, ":", 2)
+		persons[ary[0]][ary[1]] = personID
+	}
+	return persons
+}
+
+// IdentityCommitLinks - returns [login, email] links that can be trusted from commits pushed by logins
+// loginEmails is login -> commit author email -> number of commits pushed, see IdentityMinCommits
+// Links are sorted by login and email
+func IdentityCommitLinks(loginEmails map[string]map[string]int) (links [][2]string) {
+	emailLogins := make(map[string]int)
+	var candidates [][2]string
+	for login, emails := range loginEmails {
+		all := 0
+		for _, n := range emails {
+			all += n
+		}
+		for email, n := range emails {
+			if !strings.Contains(email, "@") || n < IdentityMinCommits || float64(n) < IdentityMinShare*float64(all) {
+				continue
+			}
+			candidates = append(candidates, [2]string{login, email})
+			emailLogins[strings.ToLower(email)]++
+		}
+	}
+	for _, link := range candidates {
+		if emailLogins[strings.ToLower(link[1])] > IdentityMaxLogins {
+			continue
+		}
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i][0] != links[j][0] {
+			return links[i][0] < links[j][0]
+		}
+		return links[i][1] < links[j][1]
+	})
+	return
+}
+
+// UpdateIdentities - recalculates gha_identities from logins used by the same GitHub actor ID (renamed accounts),
+// emails imported from affiliations files (gha_actors_emails) and commit author emails (gha_commits)
+// Returns number of persons having more than one login
+func UpdateIdentities(con *sql.DB, ctx *Ctx) (merged int) {
+	ids := NewIdentities()
+	var login, email string
+
+	// Logins of the same actor ID, only IDs having more than one login are returned
+	rows := QuerySQLWithErr(
+		con,
+		ctx,
+		"select actor_id, login from (select actor_id, login, count(*) over (partition by actor_id) as n from ("+
+			"select distinct actor_id, lower(dup_actor_login) as login from gha_events "+
+			"union select id, lower(login) from gha_actors) sub) sub where n > 1 order by actor_id",
+	)
+	var (
+		aid, prevAid int64
+		prevLogin    string
+	)
+	for rows.Next() {
+		FatalOnError(rows.Scan(&aid, &login))
+		if aid == prevAid && prevLogin != "" {
+			ids.LinkLogins(prevLogin, login)
+		}
+		prevAid, prevLogin = aid, login
+	}
+	FatalOnError(rows.Err())
+	FatalOnError(rows.Close())
+
+	// Emails from affiliations files
+	rows = QuerySQLWithErr(
+		con,
+		ctx,
+		"select distinct a.login, ae.email from gha_actors a, gha_actors_emails ae where a.id = ae.actor_id",
+	)
+	for rows.Next() {
+		FatalOnError(rows.Scan(&login, &email))
+		ids.Link(login, email)
+	}
+	FatalOnError(rows.Err())
+	FatalOnError(rows.Close())
+
+	// Commits emails
+	rows = QuerySQLWithErr(
+		con,
+		ctx,
+		"select dup_actor_login, author_email, count(*) from gha_commits "+
+			"where author_email != '' group by dup_actor_login, author_email",
+	)
+	loginEmails := make(map[string]map[string]int)
+	var n int
+	for rows.Next() {
+		FatalOnError(rows.Scan(&login, &email, &n))
+		_, ok := loginEmails[login]
+		if !ok {
+			loginEmails[login] = make(map[string]int)
+		}
+		loginEmails[login][email] += n
+	}
+	FatalOnError(rows.Err())
+	FatalOnError(rows.Close())
+	for _, link := range IdentityCommitLinks(loginEmails) {
+		ids.Link(link[0], link[1])
+	}
+
+	// Save, logins without any linked email are not stored: they are single login persons
+	persons := ids.Persons()
+	logins := make(map[string]int)
+	for _, personID := range persons[IdentityLogin] {
+		logins[personID]++
+	}
+	for _, cnt := range logins {
+		if cnt > 1 {
+			merged++
+		}
+	}
+	// Replace in a transaction, so metrics computed meanwhile never see empty identities
+	tc, err := con.Begin()
+	FatalOnError(err)
+	ExecSQLTxWithErr(tc, ctx, "delete from gha_identities")
+	for _, kind := range []string{IdentityLogin, IdentityEmail} {
+		for value, personID := range persons[kind] {
+			ExecSQLTxWithErr(
+				tc,
+				ctx,
+				InsertIgnore("into gha_identities(kind, value, person_id) "+NValues(3)),
+				AnyArray{kind, TruncToBytes(value, 160), TruncToBytes(personID, 160)}...,
+			)
+		}
+	}
+	FatalOnError(tc.Commit())
+	if ctx.Debug > 0 {
+		Printf("Identities: %d logins, %d emails, %d persons\n", len(persons[IdentityLogin]), len(persons[IdentityEmail]), len(logins))
+	}
+	return
+}
+
+// personAlias - returns gha_identities alias used to join person of a given login column: "pr.dup_user_login" -> "idt_pr_dup_user_login"
+func personAlias(col string) string {
+	alias := []rune("idt_")
+	for _, r := range col {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			alias = append(alias, r)
+		} else {
+			alias = append(alias, '_')
+		}
+	}
+	return string(alias)
+}
+
+// PreparePersonQuery - replaces {{person_join:alias.login_column}} with left join of gha_identities for that login
+// and {{person:alias.login_column}} with person id of that login (see gha_identities), login is used when there is no person
+// This allows counting persons instead of logins:
+// from gha_pull_requests pr {{person_join:pr.dup_user_login}} ... count(distinct {{person:pr.dup_user_login}})
+// Join is used instead of a subquery per row, {{person:col}} must be used in a query that has {{person_join:col}}
+func PreparePersonQuery(sql string) string {
+	for _, patt := range []string{"{{person_join:", "{{person:"} {
+		from := 0
+		for {
+			i := strings.Index(sql[from:], patt)
+			if i < 0 {
+				break
+			}
+			i += from
+			j := strings.Index(sql[i:], "}}")
+			if j < 0 {
+				break
+			}
+			col := sql[i+len(patt) : i+j]
+			alias := personAlias(col)
+			var repl string
+			if patt == "{{person_join:" {
+				repl = "left join gha_identities " + alias + " on " + alias + ".kind = 'login' and " +
+					alias + ".value = lower(" + col + ")"
+			} else {
+				repl = "coalesce(" + alias + ".person_id, lower(" + col + "))"
+			}
+			sql = sql[:i] + repl + sql[i+j+2:]
+			from = i + len(repl)
+		}
+	}
+	return sql
+}
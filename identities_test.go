@@ -0,0 +1,164 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestNoreplyLogin(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		email    string
+		expected string
+	}{
+		{email: "12345+John@users.noreply.github.com", expected: "john"},
+		{email: "john@users.noreply.github.com", expected: "john"},
+		{email: "john@gmail.com", expected: ""},
+		{email: "", expected: ""},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.NoreplyLogin(test.email)
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%v', got '%v', test case: %+v", index+1, test.expected, got, test)
+		}
+	}
+}
+
+func TestIdentitiesPersons(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		links      [][2]string
+		loginLinks [][2]string
+		expected   map[string]map[string]string
+	}{
+		{
+			links:    [][2]string{},
+			expected: map[string]map[string]string{"login": {}, "email": {}},
+		},
+		{
+			links: [][2]string{
+				{"John", "john@gmail.com"},
+				{"john-work", "john@corp.com"},
+				{"john-work", "John@Gmail.com"},
+				{"alice", "alice@corp.com"},
+				{"", "nobody@corp.com"},
+			},
+			expected: map[string]map[string]string{
+				"login": {"john": "john", "john-work": "john", "alice": "alice"},
+				"email": {"john@gmail.com": "john", "john@corp.com": "john", "alice@corp.com": "alice"},
+			},
+		},
+		{
+			links: [][2]string{
+				{"bob", "1+bobby@users.noreply.github.com"},
+				{"zed", "zed@corp.com"},
+			},
+			expected: map[string]map[string]string{
+				"login": {"bob": "bob", "bobby": "bob", "zed": "zed"},
+				"email": {"1+bobby@users.noreply.github.com": "bob", "zed@corp.com": "zed"},
+			},
+		},
+		{
+			links: [][2]string{
+				{"zed", "zed@corp.com"},
+			},
+			loginLinks: [][2]string{
+				{"zed", "Zed-Renamed"},
+				{"old", "new"},
+				{"new", ""},
+			},
+			expected: map[string]map[string]string{
+				"login": {"zed": "zed", "zed-renamed": "zed", "old": "new", "new": "new"},
+				"email": {"zed@corp.com": "zed"},
+			},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		ids := lib.NewIdentities()
+		for _, link := range test.links {
+			ids.Link(link[0], link[1])
+		}
+		for _, link := range test.loginLinks {
+			ids.LinkLogins(link[0], link[1])
+		}
+		got := ids.Persons()
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected:\n%+v\ngot:\n%+v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestIdentityCommitLinks(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		loginEmails map[string]map[string]int
+		expected    [][2]string
+	}{
+		{loginEmails: map[string]map[string]int{}},
+		{
+			loginEmails: map[string]map[string]int{
+				"john":       {"john@gmail.com": 10, "alice@corp.com": 2, "no-email": 10},
+				"maintainer": {"maintainer@corp.com": 20, "john@gmail.com": 3, "bob@corp.com": 6},
+				"bob":        {"bob@corp.com": 3},
+			},
+			expected: [][2]string{
+				{"bob", "bob@corp.com"},
+				{"john", "john@gmail.com"},
+				{"maintainer", "maintainer@corp.com"},
+			},
+		},
+		{
+			loginEmails: map[string]map[string]int{
+				"a": {"shared@corp.com": 5},
+				"b": {"shared@corp.com": 5},
+				"c": {"shared@corp.com": 5},
+				"d": {"Shared@corp.com": 5},
+				"e": {"e@corp.com": 5},
+			},
+			expected: [][2]string{{"e", "e@corp.com"}},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.IdentityCommitLinks(test.loginEmails)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected:\n%+v\ngot:\n%+v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestPreparePersonQuery(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		sql      string
+		expected string
+	}{
+		{sql: "select 1", expected: "select 1"},
+		{
+			sql:      "count(distinct {{person:pr.dup_user_login}})",
+			expected: "count(distinct coalesce(idt_pr_dup_user_login.person_id, lower(pr.dup_user_login)))",
+		},
+		{
+			sql: "from gha_pull_requests pr {{person_join:pr.dup_user_login}} where {{person:pr.dup_user_login}} is not null",
+			expected: "from gha_pull_requests pr left join gha_identities idt_pr_dup_user_login on " +
+				"idt_pr_dup_user_login.kind = 'login' and idt_pr_dup_user_login.value = lower(pr.dup_user_login) " +
+				"where coalesce(idt_pr_dup_user_login.person_id, lower(pr.dup_user_login)) is not null",
+		},
+		{
+			sql:      "{{person:a}} {{person:b}}",
+			expected: "coalesce(idt_a.person_id, lower(a)) coalesce(idt_b.person_id, lower(b))",
+		},
+		{sql: "unterminated {{person:a", expected: "unterminated {{person:a"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.PreparePersonQuery(test.sql)
+		if got != test.expected {
+			t.Errorf("test number %d, expected:\n%s\ngot:\n%s", index+1, test.expected, got)
+		}
+	}
+}
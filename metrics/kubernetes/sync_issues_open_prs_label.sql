@@ -0,0 +1,16 @@
+select
+  distinct i.dup_repo_name,
+  i.number
+from
+  gha_issues i
+where
+  i.dup_repo_name = 'kubernetes/kubernetes'
+  and i.is_pull_request = true
+  and i.state = 'open'
+  and i.event_id = (
+    select inn.event_id from gha_issues inn where inn.id = i.id order by inn.updated_at desc, inn.event_id desc limit 1
+  )
+  and i.event_id in (
+    select il.event_id from gha_issues_labels il where il.dup_label_name = '{{label}}'
+  )
+;
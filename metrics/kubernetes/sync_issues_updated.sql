@@ -0,0 +1,9 @@
+select
+  distinct dup_repo_name,
+  number
+from
+  gha_issues
+where
+  dup_repo_name = 'kubernetes/kubernetes'
+  and updated_at > '{{since}}'
+;
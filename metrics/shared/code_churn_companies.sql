@@ -0,0 +1,33 @@
+select
+  'churn_company;' || sub.company || ';added,removed,churn' as name,
+  round(sum(sub.added) / {{n}}, 2) as added,
+  round(sum(sub.removed) / {{n}}, 2) as removed,
+  round(sum(sub.added + sub.removed) / {{n}}, 2) as churn
+from (
+  select distinct affs.company_name as company,
+    cf.sha,
+    cf.path,
+    cf.added,
+    cf.removed
+  from
+    gha_commits c,
+    gha_commits_files cf,
+    gha_actors_affiliations affs
+  where
+    cf.sha = c.sha
+    and cf.added is not null
+    and c.dup_actor_id = affs.actor_id
+    and affs.dt_from <= c.dup_created_at
+    and affs.dt_to > c.dup_created_at
+    and affs.source in ({{affs_sources}})
+    and c.dup_created_at >= '{{from}}'
+    and c.dup_created_at < '{{to}}'
+    and (lower(c.dup_actor_login) {{exclude_bots}})
+    and affs.company_name in (select companies_name from tcompanies)
+  ) sub
+group by
+  sub.company
+order by
+  churn desc,
+  name asc
+;
@@ -0,0 +1,28 @@
+select
+  'churn_ext;' || sub.ext || ';added,removed,churn' as name,
+  round(sum(sub.added) / {{n}}, 2) as added,
+  round(sum(sub.removed) / {{n}}, 2) as removed,
+  round(sum(sub.added + sub.removed) / {{n}}, 2) as churn
+from (
+  select distinct coalesce(lower(substring(cf.path from '\.([^./]+)$')), 'none') as ext,
+    cf.sha,
+    cf.path,
+    cf.added,
+    cf.removed
+  from
+    gha_commits c,
+    gha_commits_files cf
+  where
+    cf.sha = c.sha
+    and cf.added is not null
+    and c.dup_created_at >= '{{from}}'
+    and c.dup_created_at < '{{to}}'
+    and (lower(c.dup_actor_login) {{exclude_bots}})
+  ) sub
+group by
+  sub.ext
+order by
+  churn desc,
+  name asc
+limit 20
+;
@@ -0,0 +1,53 @@
+select
+  'churn;' || sub.repo_group || ';added,removed,churn' as name,
+  round(sum(sub.added) / {{n}}, 2) as added,
+  round(sum(sub.removed) / {{n}}, 2) as removed,
+  round(sum(sub.added + sub.removed) / {{n}}, 2) as churn
+from (
+  select distinct coalesce(ecf.repo_group, r.repo_group) as repo_group,
+    cf.sha,
+    cf.path,
+    cf.added,
+    cf.removed
+  from
+    gha_repos r,
+    gha_commits c
+  join
+    gha_commits_files cf
+  on
+    cf.sha = c.sha
+  left join
+    gha_events_commits_files ecf
+  on
+    ecf.event_id = c.event_id
+    and ecf.sha = cf.sha
+    and ecf.path = c.dup_repo_name || '/' || cf.path
+  where
+    r.name = c.dup_repo_name
+    and cf.added is not null
+    and c.dup_created_at >= '{{from}}'
+    and c.dup_created_at < '{{to}}'
+    and (lower(c.dup_actor_login) {{exclude_bots}})
+  union select distinct 'All' as repo_group,
+    cf.sha,
+    cf.path,
+    cf.added,
+    cf.removed
+  from
+    gha_commits c,
+    gha_commits_files cf
+  where
+    cf.sha = c.sha
+    and cf.added is not null
+    and c.dup_created_at >= '{{from}}'
+    and c.dup_created_at < '{{to}}'
+    and (lower(c.dup_actor_login) {{exclude_bots}})
+  ) sub
+where
+  sub.repo_group is not null
+group by
+  sub.repo_group
+order by
+  churn desc,
+  name asc
+;
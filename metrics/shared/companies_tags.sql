@@ -11,6 +11,7 @@ select
   where
     aa.company_name = c.name
     and e.actor_id = aa.actor_id
+    and aa.source in ({{affs_sources}})
     and c.name not in (
       '(Unknown)'
     )
@@ -30,6 +30,7 @@ from (
     ev.actor_id = affs.actor_id
     and affs.dt_from <= ev.created_at
     and affs.dt_to > ev.created_at
+    and affs.source in ({{affs_sources}})
     and ev.created_at >= '{{from}}'
     and ev.created_at < '{{to}}'
     and ev.type in (
@@ -65,6 +66,7 @@ from (
     and ev.actor_id = affs.actor_id
     and affs.dt_from <= ev.created_at
     and affs.dt_to > ev.created_at
+    and affs.source in ({{affs_sources}})
     and ev.created_at >= '{{from}}'
     and ev.created_at < '{{to}}'
     and ev.type in (
@@ -1,44 +1,48 @@
 with prev as (
-  select distinct user_id
+  select distinct {{person:dup_user_login}} as person
   from
     gha_pull_requests
+  {{person_join:dup_user_login}}
   where
     created_at >= date '{{from}}' - '3 months'::interval
     and created_at < '{{from}}'
 ), prev_cnt as (
-  select user_id, count(distinct id) as cnt
+  select {{person:dup_user_login}} as person, count(distinct id) as cnt
   from
     gha_pull_requests
+  {{person_join:dup_user_login}}
   where
     created_at < '{{from}}'
   group by
-    user_id
+    person
 )
 select
   'epis_contrib;All;contrib,prs' as name,
-  round(count(distinct pr.user_id) / {{n}}, 2) as contributors,
+  round(count(distinct {{person:pr.dup_user_login}}) / {{n}}, 2) as contributors,
   round(count(distinct pr.id) / {{n}}, 2) as prs
 from
   gha_pull_requests pr
+{{person_join:pr.dup_user_login}}
 left join
   prev_cnt pc
 on
-  pc.user_id = pr.user_id
+  pc.person = {{person:pr.dup_user_login}}
 where
   pr.created_at >= '{{from}}'
   and pr.created_at < '{{to}}'
-  and pr.user_id not in (select user_id from prev)
-  and (pc.user_id is null or pc.cnt <= 12)
+  and {{person:pr.dup_user_login}} not in (select person from prev)
+  and (pc.person is null or pc.cnt <= 12)
 union select sub.name,
-  round(count(distinct sub.user_id) / {{n}}, 2) as contributors,
+  round(count(distinct sub.person) / {{n}}, 2) as contributors,
   round(count(distinct sub.id) / {{n}}, 2) as prs
 from (
     select 'epis_contrib;' || coalesce(ecf.repo_group, r.repo_group) || ';contrib,prs' as name,
-    pr.user_id,
+    {{person:pr.dup_user_login}} as person,
     pr.id
   from
     gha_repos r,
     gha_pull_requests pr
+  {{person_join:pr.dup_user_login}}
   left join
     gha_events_commits_files ecf
   on
@@ -46,13 +50,13 @@ from (
   left join
     prev_cnt pc
   on
-    pc.user_id = pr.user_id
+    pc.person = {{person:pr.dup_user_login}}
   where
     pr.dup_repo_id = r.id
     and pr.created_at >= '{{from}}'
     and pr.created_at < '{{to}}'
-    and pr.user_id not in (select user_id from prev)
-    and (pc.user_id is null or pc.cnt <= 12)
+    and {{person:pr.dup_user_login}} not in (select person from prev)
+    and (pc.person is null or pc.cnt <= 12)
   ) sub
 where
   sub.name is not null
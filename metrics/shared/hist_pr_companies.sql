@@ -18,6 +18,7 @@ from (
     pr.dup_actor_id = a.actor_id
     and a.dt_from <= pr.created_at
     and a.dt_to > pr.created_at
+    and a.source in ({{affs_sources}})
     and {{period:pr.created_at}}
     and pr.dup_repo_id = r.id
     and (lower(pr.dup_actor_login) {{exclude_bots}})
@@ -39,6 +40,7 @@ where
   pr.dup_actor_id = a.actor_id
   and a.dt_from <= pr.created_at
   and a.dt_to > pr.created_at
+  and a.source in ({{affs_sources}})
   and {{period:pr.created_at}}
   and (lower(pr.dup_actor_login) {{exclude_bots}})
 group by
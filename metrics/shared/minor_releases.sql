@@ -0,0 +1,14 @@
+select
+  'minor_release_days,minor_release_patches' as name,
+  round(avg(minor_days)::numeric, 2) as days_between,
+  round(avg(patches), 2) as patches
+from
+  gha_release_versions
+where
+  kind in ('major', 'minor')
+  and minor_days is not null
+  and release_date >= '{{from}}'
+  and release_date < '{{to}}'
+having
+  count(*) > 0
+;
@@ -1,30 +1,33 @@
 with prev as (
-  select distinct user_id
+  select distinct {{person:dup_user_login}} as person
   from
     gha_pull_requests
+  {{person_join:dup_user_login}}
   where
     created_at < '{{from}}'
 )
 select
   'new_contrib;All;contrib,prs' as name,
-  round(count(distinct user_id) / {{n}}, 2) as contributors,
+  round(count(distinct {{person:dup_user_login}}) / {{n}}, 2) as contributors,
   round(count(distinct id) / {{n}}, 2) as prs
 from
   gha_pull_requests
+{{person_join:dup_user_login}}
 where
   created_at >= '{{from}}'
   and created_at < '{{to}}'
-  and user_id not in (select user_id from prev)
+  and {{person:dup_user_login}} not in (select person from prev)
 union select sub.name,
-  round(count(distinct sub.user_id) / {{n}}, 2) as contributors,
+  round(count(distinct sub.person) / {{n}}, 2) as contributors,
   round(count(distinct sub.id) / {{n}}, 2) as prs
 from (
     select 'new_contrib;' || coalesce(ecf.repo_group, r.repo_group) || ';contrib,prs' as name,
-    pr.user_id,
+    {{person:pr.dup_user_login}} as person,
     pr.id
   from
     gha_repos r,
     gha_pull_requests pr
+  {{person_join:pr.dup_user_login}}
   left join
     gha_events_commits_files ecf
   on
@@ -33,7 +36,7 @@ from (
     pr.dup_repo_id = r.id
     and pr.created_at >= '{{from}}'
     and pr.created_at < '{{to}}'
-    and pr.user_id not in (select user_id from prev)
+    and {{person:pr.dup_user_login}} not in (select person from prev)
   ) sub
 where
   sub.name is not null
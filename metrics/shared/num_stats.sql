@@ -11,6 +11,7 @@ on
   ev.actor_id = affs.actor_id
   and affs.dt_from <= ev.created_at
   and affs.dt_to > ev.created_at
+  and affs.source in ({{affs_sources}})
 where
   ev.created_at >= '{{from}}'
   and ev.created_at < '{{to}}'
@@ -31,6 +32,7 @@ on
   ev.actor_id = affs.actor_id
   and affs.dt_from <= ev.created_at
   and affs.dt_to > ev.created_at
+  and affs.source in ({{affs_sources}})
 where
   r.name = ev.dup_repo_name
   and r.repo_group is not null
@@ -13,6 +13,7 @@ from (
     c.dup_actor_id = af.actor_id
     and af.dt_from <= c.dup_created_at
     and af.dt_to > c.dup_created_at
+    and af.source in ({{affs_sources}})
     and {{period:c.dup_created_at}}
     and (lower(c.dup_actor_login) {{exclude_bots}})
   group by
@@ -36,6 +37,7 @@ from (
     e.actor_id = af.actor_id
     and af.dt_from <= e.created_at
     and af.dt_to > e.created_at
+    and af.source in ({{affs_sources}})
     and e.type in (
       'IssuesEvent', 'PullRequestEvent', 'PushEvent',
       'PullRequestReviewCommentEvent', 'IssueCommentEvent',
@@ -56,6 +58,7 @@ from (
     e.actor_id = af.actor_id
     and af.dt_from <= e.created_at
     and af.dt_to > e.created_at
+    and af.source in ({{affs_sources}})
     and e.type in (
       'PushEvent', 'PullRequestEvent', 'IssuesEvent',
       'CommitCommentEvent', 'IssueCommentEvent', 'PullRequestReviewCommentEvent'
@@ -74,6 +77,7 @@ from (
     e.actor_id = af.actor_id
     and af.dt_from <= e.created_at
     and af.dt_to > e.created_at
+    and af.source in ({{affs_sources}})
     and e.type in (
       'PushEvent', 'PullRequestEvent', 'IssuesEvent',
       'CommitCommentEvent', 'IssueCommentEvent', 'PullRequestReviewCommentEvent'
@@ -92,6 +96,7 @@ from (
     e.actor_id = af.actor_id
     and af.dt_from <= e.created_at
     and af.dt_to > e.created_at
+    and af.source in ({{affs_sources}})
     and {{period:e.created_at}}
     and (lower(e.dup_actor_login) {{exclude_bots}})
   group by
@@ -106,6 +111,7 @@ from (
     c.user_id = af.actor_id
     and af.dt_from <= c.created_at
     and af.dt_to > c.created_at
+    and af.source in ({{affs_sources}})
     and {{period:c.created_at}}
     and (lower(c.dup_user_login) {{exclude_bots}})
   group by
@@ -120,6 +126,7 @@ from (
     c.user_id = af.actor_id
     and af.dt_from <= c.created_at
     and af.dt_to > c.created_at
+    and af.source in ({{affs_sources}})
     and {{period:c.created_at}}
     and (lower(c.dup_user_login) {{exclude_bots}})
   group by
@@ -134,6 +141,7 @@ from (
     i.user_id = af.actor_id
     and af.dt_from <= i.created_at
     and af.dt_to > i.created_at
+    and af.source in ({{affs_sources}})
     and {{period:i.created_at}}
     and i.is_pull_request = false
     and (lower(i.dup_user_login) {{exclude_bots}})
@@ -149,6 +157,7 @@ from (
     i.user_id = af.actor_id
     and af.dt_from <= i.created_at
     and af.dt_to > i.created_at
+    and af.source in ({{affs_sources}})
     and {{period:i.created_at}}
     and i.is_pull_request = true
     and (lower(i.dup_user_login) {{exclude_bots}})
@@ -164,6 +173,7 @@ from (
     e.actor_id = af.actor_id
     and af.dt_from <= e.created_at
     and af.dt_to > e.created_at
+    and af.source in ({{affs_sources}})
     and {{period:e.created_at}}
     and (lower(e.dup_actor_login) {{exclude_bots}})
   group by
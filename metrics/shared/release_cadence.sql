@@ -0,0 +1,21 @@
+select
+  'releases,' || kind as kind,
+  count(*) as releases
+from
+  gha_release_versions
+where
+  release_date >= '{{from}}'
+  and release_date < '{{to}}'
+group by
+  kind
+union select 'releases,All' as kind,
+  count(*) as releases
+from
+  gha_release_versions
+where
+  release_date >= '{{from}}'
+  and release_date < '{{to}}'
+order by
+  releases desc,
+  kind asc
+;
@@ -309,6 +309,15 @@ func dataForMetricTestCase(con *sql.DB, ctx *lib.Ctx, testMetric *metricTestCase
 				}
 			}
 		}
+		cfs, ok := data["commits_files"]
+		if ok {
+			for _, cf := range cfs {
+				err = addCommitFile(con, ctx, cf...)
+				if err != nil {
+					return
+				}
+			}
+		}
 		milestones, ok := data["milestones"]
 		if ok {
 			for _, milestone := range milestones {
@@ -318,6 +327,15 @@ func dataForMetricTestCase(con *sql.DB, ctx *lib.Ctx, testMetric *metricTestCase
 				}
 			}
 		}
+		bots, ok := data["bots"]
+		if ok {
+			for _, bot := range bots {
+				err = addBot(con, ctx, bot...)
+				if err != nil {
+					return
+				}
+			}
+		}
 	}
 	return
 }
@@ -413,13 +431,9 @@ func executeMetric(c *sql.DB, ctx *lib.Ctx, metric, msql string, from, to time.T
 	}
 	sqlQuery = strings.Replace(sqlQuery, "{{period}}", period, -1)
 	sqlQuery = strings.Replace(sqlQuery, "{{n}}", strconv.Itoa(n)+".0", -1)
-	sqlQuery = strings.Replace(
-		sqlQuery,
-		"{{exclude_bots}}",
-		"not like all(array['googlebot', 'rktbot', 'coveralls', 'k8s-%', '%-bot', '%-robot', "+
-			"'bot-%', 'robot-%', '%[bot]%', '%-jenkins', '%-ci%bot', '%-testing', 'codecov-%'])",
-		-1,
-	)
+	sqlQuery = strings.Replace(sqlQuery, "{{exclude_bots}}", lib.ExcludeBotsSQL(c, ctx, "./"), -1)
+	sqlQuery = strings.Replace(sqlQuery, "{{affs_sources}}", lib.AffsSourcesSQL(false), -1)
+	sqlQuery = lib.PreparePersonQuery(sqlQuery)
 	for _, replace := range replaces {
 		if len(replace) != 2 {
 			err = fmt.Errorf("replace(s) should have length 2, invalid: %+v", replace)
@@ -607,6 +621,22 @@ func addActor(con *sql.DB, ctx *lib.Ctx, args ...interface{}) (err error) {
 	return
 }
 
+// Add bot
+// login, reason, detail
+func addBot(con *sql.DB, ctx *lib.Ctx, args ...interface{}) (err error) {
+	if len(args) != 3 {
+		err = fmt.Errorf("addBot: expects 3 variadic parameters")
+		return
+	}
+	_, err = lib.ExecSQL(
+		con,
+		ctx,
+		"insert into gha_bots(login, reason, detail) "+lib.NValues(3),
+		args...,
+	)
+	return
+}
+
 // Add actor affiliation
 // actor_id, company_name, dt_from, dt_to
 func addActorAffiliation(con *sql.DB, ctx *lib.Ctx, args ...interface{}) (err error) {
@@ -662,6 +692,22 @@ func addEventCommitFile(con *sql.DB, ctx *lib.Ctx, args ...interface{}) (err err
 	return
 }
 
+// Add commit file
+// sha, dt, path, size, added, removed
+func addCommitFile(con *sql.DB, ctx *lib.Ctx, args ...interface{}) (err error) {
+	if len(args) != 6 {
+		err = fmt.Errorf("addCommitFile: expects 6 variadic parameters, got %v", len(args))
+		return
+	}
+	_, err = lib.ExecSQL(
+		con,
+		ctx,
+		"insert into gha_commits_files(sha, dt, path, size, added, removed) "+lib.NValues(6),
+		args...,
+	)
+	return
+}
+
 // Add issue label
 // iid, eid, lid, actor_id, actor_login, repo_id, repo_name,
 // ev_type, ev_created_at, issue_number, label_name
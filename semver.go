@@ -0,0 +1,170 @@
+package devstats
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// SemVer - semantic version parsed from a tag or release name
+// Pre is a pre-release part ("rc.1" in "v1.10.0-rc.1"), build metadata is ignored
+type SemVer struct {
+	Major int
+	Minor int
+	Patch int
+	Pre   string
+}
+
+// semVerRe - optional non-digit prefix ("v", "release-"), major.minor, optional patch, pre-release and build metadata
+var semVerRe = regexp.MustCompile(`^\D*?(\d+)\.(\d+)(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// ParseSemVer - parses semantic version from names like "v1.10.0", "1.2", "release-1.9.3" or "v2.0.0-rc.1"
+// Returns false for names that are not semantic versions
+func ParseSemVer(name string) (SemVer, bool) {
+	m := semVerRe.FindStringSubmatch(name)
+	if m == nil {
+		return SemVer{}, false
+	}
+	var (
+		v   SemVer
+		err error
+	)
+	v.Major, err = strconv.Atoi(m[1])
+	if err != nil {
+		return SemVer{}, false
+	}
+	v.Minor, err = strconv.Atoi(m[2])
+	if err != nil {
+		return SemVer{}, false
+	}
+	if m[3] != "" {
+		v.Patch, err = strconv.Atoi(m[3])
+		if err != nil {
+			return SemVer{}, false
+		}
+	}
+	v.Pre = m[4]
+	return v, true
+}
+
+// String - returns version as "major.minor.patch[-pre]"
+func (v SemVer) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Less - returns true if version precedes other version, pre-releases precede releases
+func (v SemVer) Less(other SemVer) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	if v.Patch != other.Patch {
+		return v.Patch < other.Patch
+	}
+	if v.Pre == "" || other.Pre == "" {
+		return v.Pre != "" && other.Pre == ""
+	}
+	return v.Pre < other.Pre
+}
+
+// Release kinds stored in gha_release_versions.kind: first release of a major version, first release of a minor version
+// and other releases of a minor version
+const (
+	ReleaseMajor = "major"
+	ReleaseMinor = "minor"
+	ReleasePatch = "patch"
+)
+
+// VersionRelease - semantic version release
+// MinorDays - days since previous minor (or major) release, Patches - number of other releases of the same minor version,
+// both are only set for minor and major releases, MinorDays is not set when the release is older than the previous one
+// (for example a minor release of an older major version, released after the new major version)
+type VersionRelease struct {
+	Name      string
+	Version   SemVer
+	Date      time.Time
+	Kind      string
+	MinorDays *float64
+	Patches   int
+}
+
+// GetVersionReleases - returns releases model built from annotations: semantic versions (pre-releases are skipped) sorted by version
+// When the same version has more names ("v1.0.0" and "1.0.0"), the earliest one is used
+func GetVersionReleases(annotations []Annotation) (releases []VersionRelease) {
+	byVersion := make(map[SemVer]int)
+	for _, annotation := range annotations {
+		v, ok := ParseSemVer(annotation.Name)
+		if !ok || v.Pre != "" {
+			continue
+		}
+		i, ok := byVersion[v]
+		if ok {
+			if annotation.Date.Before(releases[i].Date) {
+				releases[i].Name = annotation.Name
+				releases[i].Date = annotation.Date
+			}
+			continue
+		}
+		byVersion[v] = len(releases)
+		releases = append(releases, VersionRelease{Name: annotation.Name, Version: v, Date: annotation.Date})
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].Version.Less(releases[j].Version) })
+	prev := -1
+	for i := range releases {
+		r := &releases[i]
+		switch {
+		case prev < 0 || r.Version.Major != releases[prev].Version.Major:
+			r.Kind = ReleaseMajor
+		case r.Version.Minor != releases[prev].Version.Minor:
+			r.Kind = ReleaseMinor
+		default:
+			r.Kind = ReleasePatch
+			releases[prev].Patches++
+			continue
+		}
+		if prev >= 0 {
+			days := r.Date.Sub(releases[prev].Date).Hours() / 24.0
+			if days >= 0 {
+				r.MinorDays = &days
+			}
+		}
+		prev = i
+	}
+	return
+}
+
+// SaveVersionReleases - saves releases model in gha_release_versions table, used by release cadence metrics
+func SaveVersionReleases(con *sql.DB, ctx *Ctx, releases []VersionRelease) {
+	ExecSQLWithErr(con, ctx, "delete from gha_release_versions")
+	for _, r := range releases {
+		ExecSQLWithErr(
+			con,
+			ctx,
+			InsertIgnore(
+				"into gha_release_versions(name, major, minor, patch, kind, release_date, minor_days, patches) "+NValues(8),
+			),
+			AnyArray{
+				TruncToBytes(r.Name, 200),
+				r.Version.Major,
+				r.Version.Minor,
+				r.Version.Patch,
+				r.Kind,
+				r.Date,
+				r.MinorDays,
+				r.Patches,
+			}...,
+		)
+	}
+	if ctx.Debug > 0 {
+		Printf("Saved %d releases\n", len(releases))
+	}
+}
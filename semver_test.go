@@ -0,0 +1,84 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestParseSemVer(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		expected lib.SemVer
+		ok       bool
+	}{
+		{name: "v1.10.0", expected: lib.SemVer{Major: 1, Minor: 10}, ok: true},
+		{name: "1.2", expected: lib.SemVer{Major: 1, Minor: 2}, ok: true},
+		{name: "release-1.9.3", expected: lib.SemVer{Major: 1, Minor: 9, Patch: 3}, ok: true},
+		{name: "v2.0.0-rc.1", expected: lib.SemVer{Major: 2, Pre: "rc.1"}, ok: true},
+		{name: "v2.0.1+build.5", expected: lib.SemVer{Major: 2, Patch: 1}, ok: true},
+		{name: "Graduation"},
+		{name: "v1"},
+		{name: "v1.2.3.4"},
+	}
+	for index, test := range testCases {
+		got, ok := lib.ParseSemVer(test.name)
+		if ok != test.ok || got != test.expected {
+			t.Errorf("test number %d, '%s': expected %+v, %v, got %+v, %v", index+1, test.name, test.expected, test.ok, got, ok)
+		}
+	}
+}
+
+func TestSemVerLess(t *testing.T) {
+	ordered := []string{"v0.9.0", "v1.9.0", "v1.9.3", "v1.10.0-alpha.1", "v1.10.0-rc.1", "v1.10.0", "v2.0.0"}
+	for i := 0; i < len(ordered); i++ {
+		vi, _ := lib.ParseSemVer(ordered[i])
+		for j := 0; j < len(ordered); j++ {
+			vj, _ := lib.ParseSemVer(ordered[j])
+			if vi.Less(vj) != (i < j) {
+				t.Errorf("%s < %s: expected %v, got %v", ordered[i], ordered[j], i < j, vi.Less(vj))
+			}
+		}
+	}
+	v, _ := lib.ParseSemVer("v2.0.0-rc.1")
+	if v.String() != "2.0.0-rc.1" {
+		t.Errorf("expected '2.0.0-rc.1', got '%s'", v.String())
+	}
+}
+
+func TestGetVersionReleases(t *testing.T) {
+	ft := testlib.YMDHMS
+	annotations := []lib.Annotation{
+		{Name: "v1.9.0", Date: ft(2017, 12, 15)},
+		{Name: "v1.8.0", Date: ft(2017, 9, 28)},
+		{Name: "v1.8.1", Date: ft(2017, 10, 12)},
+		{Name: "1.8.1", Date: ft(2017, 10, 11)},
+		{Name: "v1.10.0-rc.1", Date: ft(2018, 3, 15)},
+		{Name: "Graduation", Date: ft(2018, 3, 6)},
+		{Name: "v1.8.2", Date: ft(2018, 1, 4)},
+		{Name: "v1.10.0", Date: ft(2018, 3, 26)},
+		{Name: "v2.0.0", Date: ft(2018, 6, 1)},
+		{Name: "v2.1.0", Date: ft(2018, 5, 20)},
+		{Name: "v2.2.0", Date: ft(2018, 6, 10)},
+	}
+	days := func(d float64) *float64 { return &d }
+	expected := []lib.VersionRelease{
+		{Name: "v1.8.0", Version: lib.SemVer{Major: 1, Minor: 8}, Date: ft(2017, 9, 28), Kind: lib.ReleaseMajor, Patches: 2},
+		{Name: "1.8.1", Version: lib.SemVer{Major: 1, Minor: 8, Patch: 1}, Date: ft(2017, 10, 11), Kind: lib.ReleasePatch},
+		{Name: "v1.8.2", Version: lib.SemVer{Major: 1, Minor: 8, Patch: 2}, Date: ft(2018, 1, 4), Kind: lib.ReleasePatch},
+		{Name: "v1.9.0", Version: lib.SemVer{Major: 1, Minor: 9}, Date: ft(2017, 12, 15), Kind: lib.ReleaseMinor, MinorDays: days(78)},
+		{Name: "v1.10.0", Version: lib.SemVer{Major: 1, Minor: 10}, Date: ft(2018, 3, 26), Kind: lib.ReleaseMinor, MinorDays: days(101)},
+		{Name: "v2.0.0", Version: lib.SemVer{Major: 2}, Date: ft(2018, 6, 1), Kind: lib.ReleaseMajor, MinorDays: days(67)},
+		{Name: "v2.1.0", Version: lib.SemVer{Major: 2, Minor: 1}, Date: ft(2018, 5, 20), Kind: lib.ReleaseMinor},
+		{Name: "v2.2.0", Version: lib.SemVer{Major: 2, Minor: 2}, Date: ft(2018, 6, 10), Kind: lib.ReleaseMinor, MinorDays: days(21)},
+	}
+	got := lib.GetVersionReleases(annotations)
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", expected, got)
+	}
+	if len(lib.GetVersionReleases([]lib.Annotation{{Name: "Graduation", Date: ft(2018)}})) != 0 {
+		t.Errorf("expected no releases")
+	}
+}
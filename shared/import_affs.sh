@@ -13,6 +13,6 @@ then
   trap finish EXIT
   export TRAP=1
 fi
-GHA2DB_LOCAL=1 ./runq scripts/clean_affiliations.sql || exit 1
 GHA2DB_LOCAL=1 ./import_affs github_users.json || exit 2
+GHA2DB_LOCAL=1 ./bots || exit 3
 GHA2DB_TAGS_YAML=metrics/$GHA2DB_PROJECT/tags_affs.yaml GHA2DB_LOCAL=1 ./tags
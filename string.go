@@ -1,10 +1,14 @@
 package devstats
 
 import (
+	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/csv"
 	"encoding/hex"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"regexp"
 	"strings"
@@ -62,26 +66,93 @@ func Slugify(arg string) string {
 	return strings.ToLower(arg)
 }
 
-// GetHidden - return list of shas to replace
+// GetHiddenShas - returns SHA1 hashes of values to hide from the hide config file
+func GetHiddenShas(configFile string) (shas []string) {
+	f, err := os.Open(configFile)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	reader := csv.NewReader(f)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			FatalOnError(err)
+		}
+		sha := row[0]
+		if sha == "sha1" {
+			continue
+		}
+		shas = append(shas, sha)
+	}
+	return
+}
+
+// GetHideKey - returns secret key used to pseudonymize hidden values, it must not be stored in the repository
+// GHA2DB_HIDE_KEY is either the key or "file:" followed by the key file name, default is HideKeyFile
+// Keys can contain any characters (base64 keys contain "/"), so only the explicit prefix selects a file
+func GetHideKey(envName string) ([]byte, error) {
+	key := os.Getenv(envName)
+	if key == "" || strings.HasPrefix(key, HideKeyFilePrefix) {
+		fileName := strings.TrimPrefix(key, HideKeyFilePrefix)
+		if fileName == "" {
+			fileName = HideKeyFile
+		}
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read hide key (set %s): %v", envName, err)
+		}
+		key = string(data)
+	}
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, fmt.Errorf("empty hide key (set %s)", envName)
+	}
+	return []byte(key), nil
+}
+
+// HidePseudonym - returns keyed pseudonym of a hidden value given by its SHA1: "anon-" + HMAC-SHA256(key, sha1) (first 40 hex digits)
+// Without the key pseudonyms cannot be matched with candidate values
+func HidePseudonym(key []byte, sha string) string {
+	mac := hmac.New(sha256.New, key)
+	_, err := mac.Write([]byte(sha))
+	FatalOnError(err)
+	return "anon-" + hex.EncodeToString(mac.Sum(nil))[:40]
+}
+
+// LegacyHidePseudonym - returns unkeyed pseudonym used before keyed pseudonymization: "anon-" + sha1
+func LegacyHidePseudonym(sha string) string {
+	return "anon-" + sha
+}
+
+// GetHidden - return map of shas to replace to their keyed pseudonyms, see HidePseudonym
+// Key is only needed when there are values to hide
+// When no key is configured legacy pseudonyms are used (with a warning), so tools keep working
+// until the key is provisioned and already hidden data is rekeyed (see HIDE_DATA.md)
 func GetHidden(configFile string) map[string]string {
 	shaMap := make(map[string]string)
-	f, err := os.Open(configFile)
-	if err == nil {
-		defer func() { _ = f.Close() }()
-		reader := csv.NewReader(f)
-		for {
-			row, err := reader.Read()
-			if err == io.EOF {
-				break
-			} else if err != nil {
-				FatalOnError(err)
-			}
-			sha := row[0]
-			if sha == "sha1" {
-				continue
-			}
-			shaMap[sha] = "anon-" + sha
+	shas := GetHiddenShas(configFile)
+	if len(shas) == 0 {
+		return shaMap
+	}
+	key, err := GetHideKey(HideKeyEnv)
+	if err != nil {
+		msg := fmt.Sprintf(
+			"WARNING: %v, using legacy unkeyed pseudonyms for %d hidden values, "+
+				"provision the key and run 'hide_data --rekey' (see HIDE_DATA.md)\n",
+			err, len(shas),
+		)
+		Printf("%s", msg)
+		fmt.Fprintf(os.Stderr, "%s", msg)
+		for _, sha := range shas {
+			shaMap[sha] = LegacyHidePseudonym(sha)
 		}
+		return shaMap
+	}
+	for _, sha := range shas {
+		shaMap[sha] = HidePseudonym(key, sha)
 	}
 	return shaMap
 }
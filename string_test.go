@@ -1,6 +1,9 @@
 package devstats
 
 import (
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
 	lib "devstats"
@@ -104,6 +107,117 @@ func TestMaybeHideFunc(t *testing.T) {
 	}
 }
 
+func TestHidePseudonym(t *testing.T) {
+	sha := "f6f9480eb4f34372a4860c829cc5bc5fc1549a1c"
+	key1 := []byte("key1")
+	key2 := []byte("key2")
+	anon := lib.HidePseudonym(key1, sha)
+	if !strings.HasPrefix(anon, "anon-") || len(anon) != len(lib.LegacyHidePseudonym(sha)) {
+		t.Errorf("expected 'anon-' followed by 40 hex digits, got '%s'", anon)
+	}
+	if anon != lib.HidePseudonym(key1, sha) {
+		t.Errorf("expected the same pseudonym for the same key and SHA1, got '%s' and '%s'", anon, lib.HidePseudonym(key1, sha))
+	}
+	if anon == lib.HidePseudonym(key2, sha) {
+		t.Errorf("expected different pseudonyms for different keys, got '%s'", anon)
+	}
+	if anon == lib.LegacyHidePseudonym(sha) {
+		t.Errorf("expected keyed pseudonym different than legacy one, got '%s'", anon)
+	}
+}
+
+func TestGetHideKey(t *testing.T) {
+	envName := "GHA2DB_HIDE_KEY_TEST"
+	defer func() { _ = os.Unsetenv(envName) }()
+
+	// Key given directly
+	err := os.Setenv(envName, " secret ")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	key, err := lib.GetHideKey(envName)
+	if err != nil || string(key) != "secret" {
+		t.Errorf("expected key 'secret', got '%s', error: %v", string(key), err)
+	}
+
+	// Key given in a file
+	f, err := ioutil.TempFile("", "hide_key")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	_, err = f.WriteString("file secret\n")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	_ = f.Close()
+	err = os.Setenv(envName, "file:"+f.Name())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	key, err = lib.GetHideKey(envName)
+	if err != nil || string(key) != "file secret" {
+		t.Errorf("expected key 'file secret', got '%s', error: %v", string(key), err)
+	}
+
+	// Base64 key containing "/" is a key, not a file name
+	err = os.Setenv(envName, "q3/Zk+8aJ1/xYw==")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	key, err = lib.GetHideKey(envName)
+	if err != nil || string(key) != "q3/Zk+8aJ1/xYw==" {
+		t.Errorf("expected key 'q3/Zk+8aJ1/xYw==', got '%s', error: %v", string(key), err)
+	}
+
+	// Missing key file
+	err = os.Setenv(envName, "file:"+f.Name()+"/missing")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	_, err = lib.GetHideKey(envName)
+	if err == nil {
+		t.Errorf("expected error for missing key file")
+	}
+}
+
+func TestGetHidden(t *testing.T) {
+	defer func() { _ = os.Unsetenv(lib.HideKeyEnv) }()
+	sha := "f6f9480eb4f34372a4860c829cc5bc5fc1549a1c"
+	f, err := ioutil.TempFile("", "hide_csv")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	_, err = f.WriteString("sha1\n" + sha + "\n")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	_ = f.Close()
+
+	// Keyed pseudonyms
+	err = os.Setenv(lib.HideKeyEnv, "secret")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	shaMap := lib.GetHidden(f.Name())
+	expected := lib.HidePseudonym([]byte("secret"), sha)
+	if len(shaMap) != 1 || shaMap[sha] != expected {
+		t.Errorf("expected %s -> %s, got %+v", sha, expected, shaMap)
+	}
+
+	// No key configured, legacy pseudonyms
+	err = os.Setenv(lib.HideKeyEnv, "file:"+f.Name()+"/missing")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	shaMap = lib.GetHidden(f.Name())
+	expected = lib.LegacyHidePseudonym(sha)
+	if len(shaMap) != 1 || shaMap[sha] != expected {
+		t.Errorf("expected %s -> %s, got %+v", sha, expected, shaMap)
+	}
+}
+
 func TestPrepareQuickRangeQuery(t *testing.T) {
 	// Test cases
 	var testCases = []struct {
@@ -4,6 +4,88 @@ import (
 	"time"
 )
 
+// Kinds of personal data in PersonalColumns
+const (
+	PersonalLogin    = "login"
+	PersonalName     = "name"
+	PersonalEmail    = "email"
+	PersonalCompany  = "company"
+	PersonalIdentity = "identity"
+)
+
+// PersonalColumn - table column holding personal data
+type PersonalColumn struct {
+	Table  string
+	Column string
+	Kind   string
+}
+
+// PersonalColumns - all table columns holding personal data (GDPR), used by `hide_data` and `merge_dbs` tools
+// Declare every new column holding logins, names, emails or companies here when adding it to Structure,
+// TestPersonalColumns fails for login and email columns that are neither here nor in NotPersonalColumns
+var PersonalColumns = []PersonalColumn{
+	{Table: "gha_actors", Column: "login", Kind: PersonalLogin},
+	{Table: "gha_actors", Column: "name", Kind: PersonalName},
+	{Table: "gha_actors_emails", Column: "email", Kind: PersonalEmail},
+	{Table: "gha_actors_affiliations", Column: "company_name", Kind: PersonalCompany},
+	{Table: "gha_companies", Column: "name", Kind: PersonalCompany},
+	{Table: "gha_company_aliases", Column: "alias", Kind: PersonalCompany},
+	{Table: "gha_company_aliases", Column: "company_name", Kind: PersonalCompany},
+	{Table: "gha_affiliations_history", Column: "login", Kind: PersonalLogin},
+	{Table: "gha_affiliations_history", Column: "company_name", Kind: PersonalCompany},
+	{Table: "gha_identities", Column: "value", Kind: PersonalIdentity},
+	{Table: "gha_identities", Column: "person_id", Kind: PersonalIdentity},
+	{Table: "gha_bots", Column: "login", Kind: PersonalLogin},
+	{Table: "gha_events", Column: "dup_actor_login", Kind: PersonalLogin},
+	{Table: "gha_payloads", Column: "dup_actor_login", Kind: PersonalLogin},
+	{Table: "gha_commits", Column: "dup_actor_login", Kind: PersonalLogin},
+	{Table: "gha_commits", Column: "author_name", Kind: PersonalName},
+	{Table: "gha_commits", Column: "author_email", Kind: PersonalEmail},
+	{Table: "gha_pages", Column: "dup_actor_login", Kind: PersonalLogin},
+	{Table: "gha_comments", Column: "dup_actor_login", Kind: PersonalLogin},
+	{Table: "gha_comments", Column: "dup_user_login", Kind: PersonalLogin},
+	{Table: "gha_issues", Column: "dup_actor_login", Kind: PersonalLogin},
+	{Table: "gha_issues", Column: "dup_user_login", Kind: PersonalLogin},
+	{Table: "gha_issues", Column: "dupn_assignee_login", Kind: PersonalLogin},
+	{Table: "gha_milestones", Column: "dup_actor_login", Kind: PersonalLogin},
+	{Table: "gha_milestones", Column: "dupn_creator_login", Kind: PersonalLogin},
+	{Table: "gha_issues_labels", Column: "dup_actor_login", Kind: PersonalLogin},
+	{Table: "gha_forkees", Column: "dup_actor_login", Kind: PersonalLogin},
+	{Table: "gha_forkees", Column: "dup_owner_login", Kind: PersonalLogin},
+	{Table: "gha_releases", Column: "dup_actor_login", Kind: PersonalLogin},
+	{Table: "gha_releases", Column: "dup_author_login", Kind: PersonalLogin},
+	{Table: "gha_assets", Column: "dup_actor_login", Kind: PersonalLogin},
+	{Table: "gha_assets", Column: "dup_uploader_login", Kind: PersonalLogin},
+	{Table: "gha_pull_requests", Column: "dup_actor_login", Kind: PersonalLogin},
+	{Table: "gha_pull_requests", Column: "dup_user_login", Kind: PersonalLogin},
+	{Table: "gha_pull_requests", Column: "dupn_assignee_login", Kind: PersonalLogin},
+	{Table: "gha_pull_requests", Column: "dupn_merged_by_login", Kind: PersonalLogin},
+	{Table: "gha_branches", Column: "dupn_forkee_name", Kind: PersonalName},
+	{Table: "gha_branches", Column: "dupn_user_login", Kind: PersonalLogin},
+	{Table: "gha_teams", Column: "dup_actor_login", Kind: PersonalLogin},
+	{Table: "gha_texts", Column: "actor_login", Kind: PersonalLogin},
+	{Table: "gha_issues_events_labels", Column: "actor_login", Kind: PersonalLogin},
+}
+
+// NotPersonalColumns - login like columns that don't hold personal data (organizations)
+var NotPersonalColumns = []PersonalColumn{
+	{Table: "gha_repos", Column: "org_login"},
+	{Table: "gha_orgs", Column: "login"},
+}
+
+// PersonalColumnsMap - returns personal columns as a map: table -> column -> kind
+func PersonalColumnsMap() map[string]map[string]string {
+	cols := make(map[string]map[string]string)
+	for _, col := range PersonalColumns {
+		_, ok := cols[col.Table]
+		if !ok {
+			cols[col.Table] = make(map[string]string)
+		}
+		cols[col.Table][col.Column] = col.Kind
+	}
+	return cols
+}
+
 // Structure creates full database structure, indexes, views/summary tables etc
 func Structure(ctx *Ctx) {
 	// Connect to Postgres DB
@@ -109,7 +191,73 @@ func Structure(ctx *Ctx) {
 		)
 	}
 
+	// gha_company_aliases: this is filled by `import_affs` and `company_aliases` tools from company_aliases.yaml
+	// Maps company name or alias to canonical company name
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_company_aliases")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_company_aliases("+
+					"alias varchar(160) not null, "+
+					"company_name varchar(160) not null, "+
+					"primary key(alias)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index company_aliases_company_name_idx on gha_company_aliases(company_name)")
+	}
+
+	// gha_bots: this is filled by `bots` tool using bots.yaml patterns and heuristics
+	// Logins are lowercase, {{exclude_bots}} in metrics SQLs excludes them
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_bots")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_bots("+
+					"login varchar(120) not null, "+
+					"reason varchar(20) not null, "+
+					"detail varchar(160) not null, "+
+					"primary key(login)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index bots_reason_idx on gha_bots(reason)")
+	}
+
+	// gha_identities: this is filled by `import_affs` tool from affiliations files emails and commits emails
+	// Maps logins and emails (kind 'login' or 'email', lowercase) to person id: the lowest login used by that person
+	// Logins not present in this table are persons with a single login (person id is a lowercase login)
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_identities")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_identities("+
+					"kind varchar(5) not null, "+
+					"value varchar(160) not null, "+
+					"person_id varchar(160) not null, "+
+					"primary key(kind, value)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index identities_person_id_idx on gha_identities(person_id)")
+		ExecSQLWithErr(c, ctx, "create index identities_value_idx on gha_identities(value)")
+	}
+
 	// gha_actors_affiliations: this is filled by `import_affs` tool, that uses cncf/gitdm:github_users.json
+	// source is 'file' for affiliations from the affiliations file(s) and 'email_domain' for inferred ones
+	// confidence is 1.0 for affiliations from files and the fraction of actor's emails in company's domain(s) for inferred ones
 	if ctx.Table {
 		ExecSQLWithErr(c, ctx, "drop table if exists gha_actors_affiliations")
 		ExecSQLWithErr(
@@ -121,6 +269,8 @@ func Structure(ctx *Ctx) {
 					"company_name varchar(160) not null, "+
 					"dt_from {{ts}} not null, "+
 					"dt_to {{ts}} not null, "+
+					"source varchar(20) not null default 'file', "+
+					"confidence double precision not null default 1.0, "+
 					"primary key(actor_id, company_name, dt_from, dt_to)"+
 					")",
 			),
@@ -131,6 +281,34 @@ func Structure(ctx *Ctx) {
 		ExecSQLWithErr(c, ctx, "create index actors_affiliations_company_name_idx on gha_actors_affiliations(company_name)")
 		ExecSQLWithErr(c, ctx, "create index actors_affiliations_dt_from_idx on gha_actors_affiliations(dt_from)")
 		ExecSQLWithErr(c, ctx, "create index actors_affiliations_dt_to_idx on gha_actors_affiliations(dt_to)")
+		ExecSQLWithErr(c, ctx, "create index actors_affiliations_source_idx on gha_actors_affiliations(source)")
+	}
+
+	// gha_affiliations_history: this is filled by `import_affs` tool, each affiliation added or removed by import is recorded here
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_affiliations_history")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_affiliations_history("+
+					"dt {{ts}} not null, "+
+					"action varchar(12) not null, "+
+					"login varchar(120) not null, "+
+					"company_name varchar(160) not null, "+
+					"dt_from {{ts}} not null, "+
+					"dt_to {{ts}} not null, "+
+					"source_hash varchar(40) not null, "+
+					"primary key(dt, action, login, company_name, dt_from, dt_to)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index affiliations_history_dt_idx on gha_affiliations_history(dt)")
+		ExecSQLWithErr(c, ctx, "create index affiliations_history_login_idx on gha_affiliations_history(login)")
+		ExecSQLWithErr(c, ctx, "create index affiliations_history_company_name_idx on gha_affiliations_history(company_name)")
+		ExecSQLWithErr(c, ctx, "create index affiliations_history_source_hash_idx on gha_affiliations_history(source_hash)")
 	}
 
 	// gha_repos
@@ -270,6 +448,7 @@ func Structure(ctx *Ctx) {
 					"sha varchar(40) not null, "+
 					"event_id bigint not null, "+
 					"author_name varchar(160) not null, "+
+					"author_email varchar(160) not null default '', "+
 					"message text not null, "+
 					"is_distinct boolean not null, "+
 					"dup_actor_id bigint not null, "+
@@ -693,6 +872,35 @@ func Structure(ctx *Ctx) {
 		ExecSQLWithErr(c, ctx, "create index releases_dup_author_login_idx on gha_releases(dup_author_login)")
 	}
 
+	// gha_release_versions: this is filled by `annotations` tool from all project's annotation sources
+	// Semantic versions releases (no pre-releases): kind is 'major' or 'minor' for the first release of a major or minor version
+	// and 'patch' for other releases, minor_days is days since previous minor release and patches is the number of patch releases
+	// (both only for major and minor releases), used by release cadence metrics
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_release_versions")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_release_versions("+
+					"name varchar(200) not null, "+
+					"major int not null, "+
+					"minor int not null, "+
+					"patch int not null, "+
+					"kind varchar(5) not null, "+
+					"release_date {{ts}} not null, "+
+					"minor_days double precision, "+
+					"patches int not null default 0, "+
+					"primary key(name)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index release_versions_kind_idx on gha_release_versions(kind)")
+		ExecSQLWithErr(c, ctx, "create index release_versions_release_date_idx on gha_release_versions(release_date)")
+	}
+
 	// gha_assets
 	// Table details and analysis in `analysis/analysis.txt` and `analysis/asset_*.json`
 	// Key: uploader_id
@@ -977,6 +1185,8 @@ func Structure(ctx *Ctx) {
 					"path text not null, "+
 					"size bigint not null, "+
 					"dt {{ts}} not null, "+
+					"added int, "+
+					"removed int, "+
 					"primary key(sha, path)"+
 					")",
 			),
@@ -992,6 +1202,8 @@ func Structure(ctx *Ctx) {
 					"path text not null, "+
 					"size bigint not null, "+
 					"dt {{ts}} not null, "+
+					"added int, "+
+					"removed int, "+
 					"repo_group varchar(80), "+
 					"dup_repo_id bigint not null, "+
 					"dup_repo_name varchar(160) not null, "+
@@ -1203,6 +1415,32 @@ func Structure(ctx *Ctx) {
 		ExecSQLWithErr(c, ctx, "create index computed_metric_idx on gha_computed(metric)")
 		ExecSQLWithErr(c, ctx, "create index computed_dt_idx on gha_computed(dt)")
 	}
+	// gha_archived_columns: values of series columns pruned by `columns` tool (GHA2DB_COLUMNS_PRUNE=archive)
+	// Columns no longer present in their tags (for example company or repo group that disappeared), only non-zero values are saved
+	// series is only set for merged series tables, other series tables use ''
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_archived_columns")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_archived_columns("+
+					"table_name varchar(63) not null, "+
+					"column_name varchar(63) not null, "+
+					"time {{ts}} not null, "+
+					"period text not null default '', "+
+					"series text not null default '', "+
+					"value double precision not null, "+
+					"archived_at {{ts}} not null, "+
+					"primary key(table_name, column_name, time, period, series)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index archived_columns_table_name_idx on gha_archived_columns(table_name)")
+		ExecSQLWithErr(c, ctx, "create index archived_columns_column_name_idx on gha_archived_columns(column_name)")
+	}
 	if ctx.Table {
 		ExecSQLWithErr(c, ctx, "drop table if exists gha_parsed")
 		ExecSQLWithErr(
@@ -1219,6 +1457,70 @@ func Structure(ctx *Ctx) {
 	if ctx.Index {
 		ExecSQLWithErr(c, ctx, "create index parsed_dt_idx on gha_parsed(dt)")
 	}
+	// This table keeps history of repositories metadata snapshots taken by ghapi2db using GitHub API
+	// Repository can be renamed, archived, can change license or default branch and we want to see when it happened
+	// New snapshot is only added on change, last_checked is the last time the API returned the same metadata
+	// not_found: repository was not found in the API, archived and not found repositories are not checked again
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_repos_metadata")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_repos_metadata("+
+					"id bigint not null, "+
+					"dt {{ts}} not null, "+
+					"last_checked {{ts}} not null, "+
+					"name varchar(160) not null, "+
+					"default_branch varchar(200), "+
+					"license_key varchar(100), "+
+					"license_name varchar(200), "+
+					"topics text not null, "+
+					"archived boolean not null, "+
+					"not_found boolean not null default false, "+
+					"primary key(id, dt)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index repos_metadata_id_idx on gha_repos_metadata(id)")
+		ExecSQLWithErr(c, ctx, "create index repos_metadata_dt_idx on gha_repos_metadata(dt)")
+		ExecSQLWithErr(c, ctx, "create index repos_metadata_name_idx on gha_repos_metadata(name)")
+		ExecSQLWithErr(c, ctx, "create index repos_metadata_archived_idx on gha_repos_metadata(archived)")
+	}
+	// This table keeps CI check runs and commit statuses of PRs head commits, taken by ghapi2db using GitHub API
+	// kind: "check_run" or "status", for statuses conclusion is a status state and status is null
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_checks")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_checks("+
+					"id bigint not null, "+
+					"kind varchar(20) not null, "+
+					"sha varchar(40) not null, "+
+					"name varchar(200) not null, "+
+					"status varchar(20), "+
+					"conclusion varchar(20), "+
+					"started_at {{ts}}, "+
+					"completed_at {{ts}}, "+
+					"dup_repo_id bigint not null, "+
+					"dup_repo_name varchar(160) not null, "+
+					"primary key(id, kind)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index checks_sha_idx on gha_checks(sha)")
+		ExecSQLWithErr(c, ctx, "create index checks_name_idx on gha_checks(name)")
+		ExecSQLWithErr(c, ctx, "create index checks_conclusion_idx on gha_checks(conclusion)")
+		ExecSQLWithErr(c, ctx, "create index checks_started_at_idx on gha_checks(started_at)")
+		ExecSQLWithErr(c, ctx, "create index checks_dup_repo_id_idx on gha_checks(dup_repo_id)")
+		ExecSQLWithErr(c, ctx, "create index checks_dup_repo_name_idx on gha_checks(dup_repo_name)")
+	}
 	// Foreign keys are not needed - they slow down processing a lot
 
 	// Tools (like views and functions needed for generating metrics)
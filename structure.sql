@@ -55,7 +55,9 @@ CREATE TABLE gha_actors_affiliations (
     actor_id bigint NOT NULL,
     company_name character varying(160) NOT NULL,
     dt_from timestamp without time zone NOT NULL,
-    dt_to timestamp without time zone NOT NULL
+    dt_to timestamp without time zone NOT NULL,
+    source character varying(20) DEFAULT 'file'::character varying NOT NULL,
+    confidence double precision DEFAULT 1.0 NOT NULL
 );
 
 
@@ -73,6 +75,40 @@ CREATE TABLE gha_actors_emails (
 
 ALTER TABLE gha_actors_emails OWNER TO gha_admin;
 
+--
+-- Name: gha_affiliations_history; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_affiliations_history (
+    dt timestamp without time zone NOT NULL,
+    action character varying(12) NOT NULL,
+    login character varying(120) NOT NULL,
+    company_name character varying(160) NOT NULL,
+    dt_from timestamp without time zone NOT NULL,
+    dt_to timestamp without time zone NOT NULL,
+    source_hash character varying(40) NOT NULL
+);
+
+
+ALTER TABLE gha_affiliations_history OWNER TO gha_admin;
+
+--
+-- Name: gha_archived_columns; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_archived_columns (
+    table_name character varying(63) NOT NULL,
+    column_name character varying(63) NOT NULL,
+    "time" timestamp without time zone NOT NULL,
+    period text DEFAULT ''::text NOT NULL,
+    series text DEFAULT ''::text NOT NULL,
+    value double precision NOT NULL,
+    archived_at timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE gha_archived_columns OWNER TO gha_admin;
+
 --
 -- Name: gha_assets; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -101,6 +137,19 @@ CREATE TABLE gha_assets (
 
 ALTER TABLE gha_assets OWNER TO gha_admin;
 
+--
+-- Name: gha_bots; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_bots (
+    login character varying(120) NOT NULL,
+    reason character varying(20) NOT NULL,
+    detail character varying(160) NOT NULL
+);
+
+
+ALTER TABLE gha_bots OWNER TO gha_admin;
+
 --
 -- Name: gha_branches; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -121,6 +170,26 @@ CREATE TABLE gha_branches (
 
 ALTER TABLE gha_branches OWNER TO gha_admin;
 
+--
+-- Name: gha_checks; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_checks (
+    id bigint NOT NULL,
+    kind character varying(20) NOT NULL,
+    sha character varying(40) NOT NULL,
+    name character varying(200) NOT NULL,
+    status character varying(20),
+    conclusion character varying(20),
+    started_at timestamp without time zone,
+    completed_at timestamp without time zone,
+    dup_repo_id bigint NOT NULL,
+    dup_repo_name character varying(160) NOT NULL
+);
+
+
+ALTER TABLE gha_checks OWNER TO gha_admin;
+
 --
 -- Name: gha_comments; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -160,6 +229,7 @@ CREATE TABLE gha_commits (
     sha character varying(40) NOT NULL,
     event_id bigint NOT NULL,
     author_name character varying(160) NOT NULL,
+    author_email character varying(160) DEFAULT ''::character varying NOT NULL,
     message text NOT NULL,
     is_distinct boolean NOT NULL,
     dup_actor_id bigint NOT NULL,
@@ -181,7 +251,9 @@ CREATE TABLE gha_commits_files (
     sha character varying(40) NOT NULL,
     path text NOT NULL,
     size bigint NOT NULL,
-    dt timestamp without time zone NOT NULL
+    dt timestamp without time zone NOT NULL,
+    added integer,
+    removed integer
 );
 
 
@@ -198,6 +270,18 @@ CREATE TABLE gha_companies (
 
 ALTER TABLE gha_companies OWNER TO gha_admin;
 
+--
+-- Name: gha_company_aliases; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_company_aliases (
+    alias character varying(160) NOT NULL,
+    company_name character varying(160) NOT NULL
+);
+
+
+ALTER TABLE gha_company_aliases OWNER TO gha_admin;
+
 --
 -- Name: gha_computed; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -240,6 +324,8 @@ CREATE TABLE gha_events_commits_files (
     path text NOT NULL,
     size bigint NOT NULL,
     dt timestamp without time zone NOT NULL,
+    added integer,
+    removed integer,
     repo_group character varying(80),
     dup_repo_id bigint NOT NULL,
     dup_repo_name character varying(160) NOT NULL,
@@ -292,6 +378,19 @@ CREATE TABLE gha_forkees (
 
 ALTER TABLE gha_forkees OWNER TO gha_admin;
 
+--
+-- Name: gha_identities; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_identities (
+    kind character varying(5) NOT NULL,
+    value character varying(160) NOT NULL,
+    person_id character varying(160) NOT NULL
+);
+
+
+ALTER TABLE gha_identities OWNER TO gha_admin;
+
 --
 -- Name: gha_issues; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -640,6 +739,24 @@ CREATE TABLE gha_pull_requests_requested_reviewers (
 
 ALTER TABLE gha_pull_requests_requested_reviewers OWNER TO gha_admin;
 
+--
+-- Name: gha_release_versions; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_release_versions (
+    name character varying(200) NOT NULL,
+    major integer NOT NULL,
+    minor integer NOT NULL,
+    patch integer NOT NULL,
+    kind character varying(5) NOT NULL,
+    release_date timestamp without time zone NOT NULL,
+    minor_days double precision,
+    patches integer DEFAULT 0 NOT NULL
+);
+
+
+ALTER TABLE gha_release_versions OWNER TO gha_admin;
+
 --
 -- Name: gha_releases; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -697,6 +814,26 @@ CREATE TABLE gha_repos (
 
 ALTER TABLE gha_repos OWNER TO gha_admin;
 
+--
+-- Name: gha_repos_metadata; Type: TABLE; Schema: public; Owner: gha_admin
+--
+
+CREATE TABLE gha_repos_metadata (
+    id bigint NOT NULL,
+    dt timestamp without time zone NOT NULL,
+    last_checked timestamp without time zone NOT NULL,
+    name character varying(160) NOT NULL,
+    default_branch character varying(200),
+    license_key character varying(100),
+    license_name character varying(200),
+    topics text NOT NULL,
+    archived boolean NOT NULL,
+    not_found boolean DEFAULT false NOT NULL
+);
+
+
+ALTER TABLE gha_repos_metadata OWNER TO gha_admin;
+
 --
 -- Name: gha_skip_commits; Type: TABLE; Schema: public; Owner: gha_admin
 --
@@ -807,6 +944,22 @@ ALTER TABLE ONLY gha_actors
     ADD CONSTRAINT gha_actors_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: gha_affiliations_history gha_affiliations_history_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_affiliations_history
+    ADD CONSTRAINT gha_affiliations_history_pkey PRIMARY KEY (dt, action, login, company_name, dt_from, dt_to);
+
+
+--
+-- Name: gha_archived_columns gha_archived_columns_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_archived_columns
+    ADD CONSTRAINT gha_archived_columns_pkey PRIMARY KEY (table_name, column_name, "time", period, series);
+
+
 --
 -- Name: gha_assets gha_assets_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -815,6 +968,14 @@ ALTER TABLE ONLY gha_assets
     ADD CONSTRAINT gha_assets_pkey PRIMARY KEY (id, event_id);
 
 
+--
+-- Name: gha_bots gha_bots_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_bots
+    ADD CONSTRAINT gha_bots_pkey PRIMARY KEY (login);
+
+
 --
 -- Name: gha_branches gha_branches_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -823,6 +984,14 @@ ALTER TABLE ONLY gha_branches
     ADD CONSTRAINT gha_branches_pkey PRIMARY KEY (sha, event_id);
 
 
+--
+-- Name: gha_checks gha_checks_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_checks
+    ADD CONSTRAINT gha_checks_pkey PRIMARY KEY (id, kind);
+
+
 --
 -- Name: gha_comments gha_comments_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -855,6 +1024,14 @@ ALTER TABLE ONLY gha_companies
     ADD CONSTRAINT gha_companies_pkey PRIMARY KEY (name);
 
 
+--
+-- Name: gha_company_aliases gha_company_aliases_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_company_aliases
+    ADD CONSTRAINT gha_company_aliases_pkey PRIMARY KEY (alias);
+
+
 --
 -- Name: gha_computed gha_computed_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -887,6 +1064,14 @@ ALTER TABLE ONLY gha_forkees
     ADD CONSTRAINT gha_forkees_pkey PRIMARY KEY (id, event_id);
 
 
+--
+-- Name: gha_identities gha_identities_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_identities
+    ADD CONSTRAINT gha_identities_pkey PRIMARY KEY (kind, value);
+
+
 --
 -- Name: gha_issues_assignees gha_issues_assignees_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -999,6 +1184,14 @@ ALTER TABLE ONLY gha_pull_requests_requested_reviewers
     ADD CONSTRAINT gha_pull_requests_requested_reviewers_pkey PRIMARY KEY (pull_request_id, event_id, requested_reviewer_id);
 
 
+--
+-- Name: gha_release_versions gha_release_versions_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_release_versions
+    ADD CONSTRAINT gha_release_versions_pkey PRIMARY KEY (name);
+
+
 --
 -- Name: gha_releases_assets gha_releases_assets_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -1023,6 +1216,14 @@ ALTER TABLE ONLY gha_repos
     ADD CONSTRAINT gha_repos_pkey PRIMARY KEY (id, name);
 
 
+--
+-- Name: gha_repos_metadata gha_repos_metadata_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
+--
+
+ALTER TABLE ONLY gha_repos_metadata
+    ADD CONSTRAINT gha_repos_metadata_pkey PRIMARY KEY (id, dt);
+
+
 --
 -- Name: gha_skip_commits gha_skip_commits_pkey; Type: CONSTRAINT; Schema: public; Owner: gha_admin
 --
@@ -1083,6 +1284,13 @@ CREATE INDEX actors_affiliations_dt_from_idx ON gha_actors_affiliations USING bt
 CREATE INDEX actors_affiliations_dt_to_idx ON gha_actors_affiliations USING btree (dt_to);
 
 
+--
+-- Name: actors_affiliations_source_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX actors_affiliations_source_idx ON gha_actors_affiliations USING btree (source);
+
+
 --
 -- Name: actors_emails_actor_id_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -1111,6 +1319,48 @@ CREATE INDEX actors_login_idx ON gha_actors USING btree (login);
 CREATE INDEX actors_name_idx ON gha_actors USING btree (name);
 
 
+--
+-- Name: affiliations_history_company_name_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX affiliations_history_company_name_idx ON gha_affiliations_history USING btree (company_name);
+
+
+--
+-- Name: affiliations_history_dt_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX affiliations_history_dt_idx ON gha_affiliations_history USING btree (dt);
+
+
+--
+-- Name: affiliations_history_login_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX affiliations_history_login_idx ON gha_affiliations_history USING btree (login);
+
+
+--
+-- Name: affiliations_history_source_hash_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX affiliations_history_source_hash_idx ON gha_affiliations_history USING btree (source_hash);
+
+
+--
+-- Name: archived_columns_column_name_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX archived_columns_column_name_idx ON gha_archived_columns USING btree (column_name);
+
+
+--
+-- Name: archived_columns_table_name_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX archived_columns_table_name_idx ON gha_archived_columns USING btree (table_name);
+
+
 --
 -- Name: assets_content_type_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -1202,6 +1452,13 @@ CREATE INDEX assets_updated_at_idx ON gha_assets USING btree (updated_at);
 CREATE INDEX assets_uploader_id_idx ON gha_assets USING btree (uploader_id);
 
 
+--
+-- Name: bots_reason_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX bots_reason_idx ON gha_bots USING btree (reason);
+
+
 --
 -- Name: branches_dup_created_at_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -1251,6 +1508,48 @@ CREATE INDEX branches_repo_id_idx ON gha_branches USING btree (repo_id);
 CREATE INDEX branches_user_id_idx ON gha_branches USING btree (user_id);
 
 
+--
+-- Name: checks_conclusion_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX checks_conclusion_idx ON gha_checks USING btree (conclusion);
+
+
+--
+-- Name: checks_dup_repo_id_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX checks_dup_repo_id_idx ON gha_checks USING btree (dup_repo_id);
+
+
+--
+-- Name: checks_dup_repo_name_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX checks_dup_repo_name_idx ON gha_checks USING btree (dup_repo_name);
+
+
+--
+-- Name: checks_name_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX checks_name_idx ON gha_checks USING btree (name);
+
+
+--
+-- Name: checks_sha_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX checks_sha_idx ON gha_checks USING btree (sha);
+
+
+--
+-- Name: checks_started_at_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX checks_started_at_idx ON gha_checks USING btree (started_at);
+
+
 --
 -- Name: comments_commit_id_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -1419,6 +1718,13 @@ CREATE INDEX commits_files_sha_idx ON gha_commits_files USING btree (sha);
 CREATE INDEX commits_files_size_idx ON gha_commits_files USING btree (size);
 
 
+--
+-- Name: company_aliases_company_name_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX company_aliases_company_name_idx ON gha_company_aliases USING btree (company_name);
+
+
 --
 -- Name: computed_dt_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -1650,6 +1956,20 @@ CREATE INDEX forkees_owner_id_idx ON gha_forkees USING btree (owner_id);
 CREATE INDEX forkees_updated_at_idx ON gha_forkees USING btree (updated_at);
 
 
+--
+-- Name: identities_person_id_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX identities_person_id_idx ON gha_identities USING btree (person_id);
+
+
+--
+-- Name: identities_value_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX identities_value_idx ON gha_identities USING btree (value);
+
+
 --
 -- Name: issues_assignee_id_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -2392,6 +2712,20 @@ CREATE INDEX pull_requests_updated_at_idx ON gha_pull_requests USING btree (upda
 CREATE INDEX pull_requests_user_id_idx ON gha_pull_requests USING btree (user_id);
 
 
+--
+-- Name: release_versions_kind_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX release_versions_kind_idx ON gha_release_versions USING btree (kind);
+
+
+--
+-- Name: release_versions_release_date_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX release_versions_release_date_idx ON gha_release_versions USING btree (release_date);
+
+
 --
 -- Name: releases_author_id_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -2469,6 +2803,34 @@ CREATE INDEX releases_event_id_idx ON gha_releases USING btree (event_id);
 CREATE INDEX repos_alias_idx ON gha_repos USING btree (alias);
 
 
+--
+-- Name: repos_metadata_archived_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_metadata_archived_idx ON gha_repos_metadata USING btree (archived);
+
+
+--
+-- Name: repos_metadata_dt_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_metadata_dt_idx ON gha_repos_metadata USING btree (dt);
+
+
+--
+-- Name: repos_metadata_id_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_metadata_id_idx ON gha_repos_metadata USING btree (id);
+
+
+--
+-- Name: repos_metadata_name_idx; Type: INDEX; Schema: public; Owner: gha_admin
+--
+
+CREATE INDEX repos_metadata_name_idx ON gha_repos_metadata USING btree (name);
+
+
 --
 -- Name: repos_name_idx; Type: INDEX; Schema: public; Owner: gha_admin
 --
@@ -2654,6 +3016,22 @@ GRANT SELECT ON TABLE gha_actors_emails TO ro_user;
 GRANT SELECT ON TABLE gha_actors_emails TO devstats_team;
 
 
+--
+-- Name: gha_affiliations_history; Type: ACL; Schema: public; Owner: gha_admin
+--
+
+GRANT SELECT ON TABLE gha_affiliations_history TO ro_user;
+GRANT SELECT ON TABLE gha_affiliations_history TO devstats_team;
+
+
+--
+-- Name: gha_archived_columns; Type: ACL; Schema: public; Owner: gha_admin
+--
+
+GRANT SELECT ON TABLE gha_archived_columns TO ro_user;
+GRANT SELECT ON TABLE gha_archived_columns TO devstats_team;
+
+
 --
 -- Name: gha_assets; Type: ACL; Schema: public; Owner: gha_admin
 --
@@ -2662,6 +3040,14 @@ GRANT SELECT ON TABLE gha_assets TO ro_user;
 GRANT SELECT ON TABLE gha_assets TO devstats_team;
 
 
+--
+-- Name: gha_bots; Type: ACL; Schema: public; Owner: gha_admin
+--
+
+GRANT SELECT ON TABLE gha_bots TO ro_user;
+GRANT SELECT ON TABLE gha_bots TO devstats_team;
+
+
 --
 -- Name: gha_branches; Type: ACL; Schema: public; Owner: gha_admin
 --
@@ -2670,6 +3056,14 @@ GRANT SELECT ON TABLE gha_branches TO ro_user;
 GRANT SELECT ON TABLE gha_branches TO devstats_team;
 
 
+--
+-- Name: gha_checks; Type: ACL; Schema: public; Owner: gha_admin
+--
+
+GRANT SELECT ON TABLE gha_checks TO ro_user;
+GRANT SELECT ON TABLE gha_checks TO devstats_team;
+
+
 --
 -- Name: gha_comments; Type: ACL; Schema: public; Owner: gha_admin
 --
@@ -2702,6 +3096,14 @@ GRANT SELECT ON TABLE gha_companies TO ro_user;
 GRANT SELECT ON TABLE gha_companies TO devstats_team;
 
 
+--
+-- Name: gha_company_aliases; Type: ACL; Schema: public; Owner: gha_admin
+--
+
+GRANT SELECT ON TABLE gha_company_aliases TO ro_user;
+GRANT SELECT ON TABLE gha_company_aliases TO devstats_team;
+
+
 --
 -- Name: gha_events; Type: ACL; Schema: public; Owner: gha_admin
 --
@@ -2726,6 +3128,14 @@ GRANT SELECT ON TABLE gha_forkees TO ro_user;
 GRANT SELECT ON TABLE gha_forkees TO devstats_team;
 
 
+--
+-- Name: gha_identities; Type: ACL; Schema: public; Owner: gha_admin
+--
+
+GRANT SELECT ON TABLE gha_identities TO ro_user;
+GRANT SELECT ON TABLE gha_identities TO devstats_team;
+
+
 --
 -- Name: gha_issues; Type: ACL; Schema: public; Owner: gha_admin
 --
@@ -2846,6 +3256,14 @@ GRANT SELECT ON TABLE gha_pull_requests_requested_reviewers TO ro_user;
 GRANT SELECT ON TABLE gha_pull_requests_requested_reviewers TO devstats_team;
 
 
+--
+-- Name: gha_release_versions; Type: ACL; Schema: public; Owner: gha_admin
+--
+
+GRANT SELECT ON TABLE gha_release_versions TO ro_user;
+GRANT SELECT ON TABLE gha_release_versions TO devstats_team;
+
+
 --
 -- Name: gha_releases; Type: ACL; Schema: public; Owner: gha_admin
 --
@@ -2870,6 +3288,14 @@ GRANT SELECT ON TABLE gha_repos TO ro_user;
 GRANT SELECT ON TABLE gha_repos TO devstats_team;
 
 
+--
+-- Name: gha_repos_metadata; Type: ACL; Schema: public; Owner: gha_admin
+--
+
+GRANT SELECT ON TABLE gha_repos_metadata TO ro_user;
+GRANT SELECT ON TABLE gha_repos_metadata TO devstats_team;
+
+
 --
 -- Name: gha_skip_commits; Type: ACL; Schema: public; Owner: gha_admin
 --
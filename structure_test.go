@@ -0,0 +1,85 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+
+	lib "devstats"
+)
+
+// structureColumns - returns table -> columns map parsed from tables definitions in structure.go
+func structureColumns(t *testing.T) map[string]map[string]struct{} {
+	data, err := ioutil.ReadFile("structure.go")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	reTable := regexp.MustCompile(`^\s*"(\w+)\("\+$`)
+	reColumn := regexp.MustCompile(`^\s*"(\w+) \w+`)
+	tables := make(map[string]map[string]struct{})
+	table := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		m := reTable.FindStringSubmatch(line)
+		if m != nil {
+			table = m[1]
+			tables[table] = make(map[string]struct{})
+			continue
+		}
+		if table == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), `")"`) {
+			table = ""
+			continue
+		}
+		m = reColumn.FindStringSubmatch(line)
+		if m != nil && m[1] != "primary" {
+			tables[table][m[1]] = struct{}{}
+		}
+	}
+	return tables
+}
+
+func TestPersonalColumns(t *testing.T) {
+	tables := structureColumns(t)
+	if len(tables["gha_events"]) == 0 {
+		t.Fatalf("cannot parse tables from structure.go")
+	}
+
+	// All registered columns must exist
+	classified := make(map[string]struct{})
+	for _, cols := range [][]lib.PersonalColumn{lib.PersonalColumns, lib.NotPersonalColumns} {
+		for _, col := range cols {
+			name := col.Table + "." + col.Column
+			_, ok := tables[col.Table][col.Column]
+			if !ok {
+				t.Errorf("registered column %s is not defined in structure.go", name)
+			}
+			_, ok = classified[name]
+			if ok {
+				t.Errorf("column %s registered more than once", name)
+			}
+			classified[name] = struct{}{}
+		}
+	}
+
+	// All login and email columns must be classified
+	for table, cols := range tables {
+		for col := range cols {
+			if !strings.Contains(col, "login") && !strings.Contains(col, "email") {
+				continue
+			}
+			_, ok := classified[table+"."+col]
+			if !ok {
+				t.Errorf("column %s.%s looks like personal data, add it to PersonalColumns or NotPersonalColumns", table, col)
+			}
+		}
+	}
+
+	// Map
+	cols := lib.PersonalColumnsMap()
+	if cols["gha_commits"]["author_email"] != lib.PersonalEmail || cols["gha_actors"]["login"] != lib.PersonalLogin {
+		t.Errorf("unexpected personal columns map: %+v", cols)
+	}
+}
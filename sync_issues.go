@@ -0,0 +1,109 @@
+package devstats
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SyncIssuesSelectors contain list of named issue selectors used by `sync_issues` tool
+type SyncIssuesSelectors struct {
+	Selectors []SyncIssuesSelector `yaml:"selectors"`
+}
+
+// SyncIssuesSelector contain each issue selector data
+// SQL file must return full repo name and issue number, for example: kubernetes/kubernetes, 60172
+// SQL can use {{since}} - a watermark: when this selector was last successfully synced
+// Since is used as a watermark for the first run - now() - since, default '1 week'
+// Replaces is a list of [from, to] replacements applied to the SQL
+type SyncIssuesSelector struct {
+	Name     string     `yaml:"name"`
+	SQLFile  string     `yaml:"sql"`
+	Since    string     `yaml:"since"`
+	Replaces [][]string `yaml:"replaces"`
+}
+
+// GetSyncIssuesSelectors - returns selectors with given names or all selectors when no names given
+// Returns error when any of given names is not defined
+func GetSyncIssuesSelectors(all *SyncIssuesSelectors, names []string) ([]SyncIssuesSelector, error) {
+	if len(names) == 0 {
+		return all.Selectors, nil
+	}
+	byName := make(map[string]SyncIssuesSelector)
+	for _, sel := range all.Selectors {
+		byName[sel.Name] = sel
+	}
+	selectors := []SyncIssuesSelector{}
+	for _, name := range names {
+		sel, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("sync issues selector '%s' not found", name)
+		}
+		selectors = append(selectors, sel)
+	}
+	return selectors, nil
+}
+
+// PrepareSyncIssuesSQL - applies selector replaces and {{since}} watermark to the selector SQL
+func PrepareSyncIssuesSQL(sqlQuery string, sel *SyncIssuesSelector, since time.Time) (string, error) {
+	for _, replace := range sel.Replaces {
+		if len(replace) != 2 {
+			return "", fmt.Errorf("selector '%s': replace(s) should have length 2, invalid: %+v", sel.Name, replace)
+		}
+		sqlQuery = strings.Replace(sqlQuery, replace[0], replace[1], -1)
+	}
+	return strings.Replace(sqlQuery, "{{since}}", ToYMDHMSDate(since), -1), nil
+}
+
+// syncIssuesWatermarkName - gha_vars name used to store selector's watermark
+func syncIssuesWatermarkName(name string) string {
+	return "sync_issues_" + name
+}
+
+// GetSyncIssuesWatermark - returns selector's watermark (last successful sync time)
+// When selector was never synced, returns now() - sel.Since
+func GetSyncIssuesWatermark(con *sql.DB, ctx *Ctx, sel *SyncIssuesSelector) time.Time {
+	rows := QuerySQLWithErr(
+		con,
+		ctx,
+		fmt.Sprintf("select value_dt from gha_vars where name = %s and value_dt is not null", NValue(1)),
+		syncIssuesWatermarkName(sel.Name),
+	)
+	defer func() { FatalOnError(rows.Close()) }()
+	var (
+		dt  time.Time
+		got bool
+	)
+	for rows.Next() {
+		FatalOnError(rows.Scan(&dt))
+		got = true
+	}
+	FatalOnError(rows.Err())
+	if got {
+		return dt
+	}
+	since := sel.Since
+	if since == "" {
+		since = "1 week"
+	}
+	return GetDateAgo(con, ctx, time.Now(), since)
+}
+
+// SetSyncIssuesWatermark - saves selector's watermark (last successful sync time)
+func SetSyncIssuesWatermark(con *sql.DB, ctx *Ctx, sel *SyncIssuesSelector, dt time.Time) {
+	if ctx.SkipPDB {
+		if ctx.Debug > 0 {
+			Printf("No DB write: sync issues selector '%s' watermark %v\n", sel.Name, dt)
+		}
+		return
+	}
+	ExecSQLWithErr(
+		con,
+		ctx,
+		"insert into gha_vars(name, value_dt) "+NValues(2)+
+			" on conflict(name) do update set value_dt = excluded.value_dt",
+		syncIssuesWatermarkName(sel.Name),
+		dt,
+	)
+}
@@ -0,0 +1,107 @@
+package devstats
+
+import (
+	"testing"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestGetSyncIssuesSelectors(t *testing.T) {
+	// All selectors
+	all := lib.SyncIssuesSelectors{
+		Selectors: []lib.SyncIssuesSelector{
+			{Name: "updated", SQLFile: "sync_issues_updated"},
+			{Name: "open_prs", SQLFile: "sync_issues_open_prs_label"},
+			{Name: "milestones", SQLFile: "sync_issues_milestones"},
+		},
+	}
+
+	// Test cases
+	var testCases = []struct {
+		names    []string
+		expected []string
+		err      bool
+	}{
+		{names: []string{}, expected: []string{"updated", "open_prs", "milestones"}},
+		{names: []string{"milestones"}, expected: []string{"milestones"}},
+		{names: []string{"milestones", "updated"}, expected: []string{"milestones", "updated"}},
+		{names: []string{"updated", "unknown"}, err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		selectors, err := lib.GetSyncIssuesSelectors(&all, test.names)
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got %+v", index+1, selectors)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		got := []string{}
+		for _, sel := range selectors {
+			got = append(got, sel.Name)
+		}
+		if !testlib.CompareStringSlices(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestPrepareSyncIssuesSQL(t *testing.T) {
+	// Test cases
+	ft := testlib.YMDHMS
+	var testCases = []struct {
+		sql      string
+		selector lib.SyncIssuesSelector
+		expected string
+		err      bool
+	}{
+		{
+			sql:      "select 1",
+			selector: lib.SyncIssuesSelector{Name: "s"},
+			expected: "select 1",
+		},
+		{
+			sql:      "updated_at > '{{since}}' and updated_at > '{{since}}'",
+			selector: lib.SyncIssuesSelector{Name: "s"},
+			expected: "updated_at > '2018-03-01 12:30:00' and updated_at > '2018-03-01 12:30:00'",
+		},
+		{
+			sql: "label = '{{label}}' and updated_at <= now() - '{{to}}'::interval and updated_at > '{{since}}'",
+			selector: lib.SyncIssuesSelector{
+				Name:     "s",
+				Replaces: [][]string{{"{{label}}", "lgtm"}, {"{{to}}", "1 week"}},
+			},
+			expected: "label = 'lgtm' and updated_at <= now() - '1 week'::interval and updated_at > '2018-03-01 12:30:00'",
+		},
+		{
+			sql: "select 1",
+			selector: lib.SyncIssuesSelector{
+				Name:     "s",
+				Replaces: [][]string{{"{{label}}"}},
+			},
+			err: true,
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.PrepareSyncIssuesSQL(test.sql, &test.selector, ft(2018, 3, 1, 12, 30))
+		if test.err {
+			if err == nil {
+				t.Errorf("test number %d, expected error, got '%s'", index+1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%s', got '%s'", index+1, test.expected, got)
+		}
+	}
+}
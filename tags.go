@@ -3,26 +3,35 @@ package devstats
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// DefaultTagLimit - number of tag values returned by tags SQLs using {{lim}} when tag has no limit set
+const DefaultTagLimit = 69
+
 // Tags contain list of TSDB tags
 type Tags struct {
 	Tags []Tag `yaml:"tags"`
 }
 
 // Tag contain each TSDB tag data
+// Limit replaces {{lim}} in tag SQL, 0 means DefaultTagLimit
 type Tag struct {
-	Name       string            `yaml:"name"`
-	SQLFile    string            `yaml:"sql"`
-	SeriesName string            `yaml:"series_name"`
-	NameTag    string            `yaml:"name_tag"`
-	ValueTag   string            `yaml:"value_tag"`
-	OtherTags  map[string]string `yaml:"other_tags"`
+	Name         string            `yaml:"name"`
+	SQLFile      string            `yaml:"sql"`
+	SeriesName   string            `yaml:"series_name"`
+	NameTag      string            `yaml:"name_tag"`
+	ValueTag     string            `yaml:"value_tag"`
+	OtherTags    map[string]string `yaml:"other_tags"`
+	InferredAffs bool              `yaml:"inferred_affs"`
+	Limit        int               `yaml:"limit"`
 }
 
 // ProcessTag - insert given Tag into Postgres TSDB
+// Tag values are upserted and then values past the new last one are deleted,
+// so tag table is never empty while it is being updated
 func ProcessTag(con *sql.DB, ctx *Ctx, tg *Tag, replaces [][]string) {
 	// Batch TS points
 	var pts TSPoints
@@ -45,13 +54,17 @@ func ProcessTag(con *sql.DB, ctx *Ctx, tg *Tag, replaces [][]string) {
 	sqlQuery := string(bytes)
 
 	// Handle excluding bots
-	bytes, err = ReadFile(ctx, dataPrefix+"util_sql/exclude_bots.sql")
-	FatalOnError(err)
-	excludeBots := string(bytes)
+	excludeBots := ExcludeBotsSQL(con, ctx, dataPrefix)
 
 	// Transform SQL
-	sqlQuery = strings.Replace(sqlQuery, "{{lim}}", "69", -1)
+	limit := tg.Limit
+	if limit <= 0 {
+		limit = DefaultTagLimit
+	}
+	sqlQuery = strings.Replace(sqlQuery, "{{lim}}", strconv.Itoa(limit), -1)
 	sqlQuery = strings.Replace(sqlQuery, "{{exclude_bots}}", excludeBots, -1)
+	sqlQuery = strings.Replace(sqlQuery, "{{affs_sources}}", AffsSourcesSQL(tg.InferredAffs), -1)
+	sqlQuery = PreparePersonQuery(sqlQuery)
 
 	// Replaces
 	for _, replace := range replaces {
@@ -65,11 +78,7 @@ func ProcessTag(con *sql.DB, ctx *Ctx, tg *Tag, replaces [][]string) {
 	rows := QuerySQLWithErr(con, ctx, sqlQuery)
 	defer func() { FatalOnError(rows.Close()) }()
 
-	// Drop current tags
-	table := "t" + tg.SeriesName
-	if TableExists(con, ctx, table) {
-		ExecSQLWithErr(con, ctx, "truncate "+table)
-	}
+	// Tag values are stored as consecutive hours starting from 2014-01-01
 	tm := TimeParseAny("2014-01-01")
 
 	// Columns
@@ -128,9 +137,13 @@ func ProcessTag(con *sql.DB, ctx *Ctx, tg *Tag, replaces [][]string) {
 		Printf("Warning: Tag '%+v' have no values\n", &tg)
 	}
 
-	// Write the batch
+	// Write the batch and drop stale tags
 	if !ctx.SkipTSDB {
 		WriteTSPoints(ctx, con, &pts, "", nil)
+		table := makePsqlName("t"+tg.SeriesName, true)
+		if TableExists(con, ctx, table) {
+			ExecSQLWithErr(con, ctx, "delete from \""+table+"\" where time >= "+NValue(1), tm)
+		}
 	} else if ctx.Debug > 0 {
 		Printf("Skipping tags series write\n")
 	}
@@ -0,0 +1,281 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lib "devstats"
+
+	"github.com/google/go-github/github"
+)
+
+// GitHubAPI - in-process fake GitHub REST API server
+// Serves: rate_limit, repos/{org}/{repo}, issues/events, issues/{number}, pulls/{number},
+// commits/{sha}/statuses and commits/{sha}/check-runs
+// All maps are keyed by "org/repo" full repository name (and then by commit SHA)
+type GitHubAPI struct {
+	Server    *httptest.Server
+	Repos     map[string]*github.Repository
+	Issues    map[string]map[int]*github.Issue
+	Events    map[string][]*github.IssueEvent
+	Pulls     map[string]map[int]*github.PullRequest
+	Statuses  map[string]map[string][]*github.RepoStatus
+	CheckRuns map[string]map[string][]*lib.CheckRun
+	Core      github.Rate
+	Search    github.Rate
+	Calls     map[string]int
+	mtx       *sync.Mutex
+}
+
+// NewGitHubAPI - starts a new fake GitHub API server without any data
+// Rate limits are set to 5000/5000 resetting in 1 hour
+// Use `ctx.GitHubURL = api.URL()` to make lib.GHClient use it
+func NewGitHubAPI() *GitHubAPI {
+	reset := github.Timestamp{Time: time.Now().Add(time.Hour)}
+	api := &GitHubAPI{
+		Repos:     make(map[string]*github.Repository),
+		Issues:    make(map[string]map[int]*github.Issue),
+		Events:    make(map[string][]*github.IssueEvent),
+		Pulls:     make(map[string]map[int]*github.PullRequest),
+		Statuses:  make(map[string]map[string][]*github.RepoStatus),
+		CheckRuns: make(map[string]map[string][]*lib.CheckRun),
+		Core:      github.Rate{Limit: 5000, Remaining: 5000, Reset: reset},
+		Search:    github.Rate{Limit: 30, Remaining: 30, Reset: reset},
+		Calls:     make(map[string]int),
+		mtx:       &sync.Mutex{},
+	}
+	api.Server = httptest.NewServer(http.HandlerFunc(api.handle))
+	return api
+}
+
+// URL - returns fake GitHub API base URL
+func (api *GitHubAPI) URL() string {
+	return api.Server.URL + "/"
+}
+
+// Close - stops fake GitHub API server
+func (api *GitHubAPI) Close() {
+	api.Server.Close()
+}
+
+// SetCoreRate - sets remaining Core API points and time to reset
+func (api *GitHubAPI) SetCoreRate(remaining int, reset time.Duration) {
+	api.mtx.Lock()
+	api.Core.Remaining = remaining
+	api.Core.Reset = github.Timestamp{Time: time.Now().Add(reset)}
+	api.mtx.Unlock()
+}
+
+// NCalls - returns number of API calls for a given endpoint kind:
+// "rate_limit", "repo", "events", "issue", "pull", "statuses", "check-runs"
+func (api *GitHubAPI) NCalls(kind string) int {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+	return api.Calls[kind]
+}
+
+// AddRepo - adds repository to fake data
+func (api *GitHubAPI) AddRepo(repo string, r *github.Repository) {
+	api.mtx.Lock()
+	api.Repos[repo] = r
+	api.mtx.Unlock()
+}
+
+// AddIssue - adds issue (or PR's issue) to fake data
+func (api *GitHubAPI) AddIssue(repo string, issue *github.Issue) {
+	api.mtx.Lock()
+	_, ok := api.Issues[repo]
+	if !ok {
+		api.Issues[repo] = make(map[int]*github.Issue)
+	}
+	api.Issues[repo][*issue.Number] = issue
+	api.mtx.Unlock()
+}
+
+// AddEvent - adds issue event to fake data
+func (api *GitHubAPI) AddEvent(repo string, event *github.IssueEvent) {
+	api.mtx.Lock()
+	api.Events[repo] = append(api.Events[repo], event)
+	api.mtx.Unlock()
+}
+
+// AddPull - adds pull request to fake data
+func (api *GitHubAPI) AddPull(repo string, pr *github.PullRequest) {
+	api.mtx.Lock()
+	_, ok := api.Pulls[repo]
+	if !ok {
+		api.Pulls[repo] = make(map[int]*github.PullRequest)
+	}
+	api.Pulls[repo][*pr.Number] = pr
+	api.mtx.Unlock()
+}
+
+// AddStatus - adds commit status to fake data
+func (api *GitHubAPI) AddStatus(repo, sha string, status *github.RepoStatus) {
+	api.mtx.Lock()
+	_, ok := api.Statuses[repo]
+	if !ok {
+		api.Statuses[repo] = make(map[string][]*github.RepoStatus)
+	}
+	api.Statuses[repo][sha] = append(api.Statuses[repo][sha], status)
+	api.mtx.Unlock()
+}
+
+// AddCheckRun - adds commit check run to fake data
+func (api *GitHubAPI) AddCheckRun(repo, sha string, run *lib.CheckRun) {
+	api.mtx.Lock()
+	_, ok := api.CheckRuns[repo]
+	if !ok {
+		api.CheckRuns[repo] = make(map[string][]*lib.CheckRun)
+	}
+	api.CheckRuns[repo][sha] = append(api.CheckRuns[repo][sha], run)
+	api.mtx.Unlock()
+}
+
+// handle - fake API router
+func (api *GitHubAPI) handle(w http.ResponseWriter, r *http.Request) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+	// GitHub sends current Core rate limits with every response
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(api.Core.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(api.Core.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(api.Core.Reset.Unix(), 10))
+	path := strings.Trim(r.URL.Path, "/")
+	ary := strings.Split(path, "/")
+	if path == "rate_limit" {
+		api.Calls["rate_limit"]++
+		api.writeJSON(
+			w,
+			map[string]interface{}{
+				"resources": map[string]github.Rate{"core": api.Core, "search": api.Search},
+			},
+		)
+		return
+	}
+	// repos/org/repo, repos/org/repo/issues/events, repos/org/repo/issues/N, repos/org/repo/pulls/N
+	// repos/org/repo/commits/sha/statuses, repos/org/repo/commits/sha/check-runs
+	if (len(ary) < 3 || len(ary) > 6 || len(ary) == 4) || ary[0] != "repos" {
+		api.notFound(w)
+		return
+	}
+	repo := ary[1] + "/" + ary[2]
+	if len(ary) == 3 {
+		api.Calls["repo"]++
+		r, ok := api.Repos[repo]
+		if !ok {
+			api.notFound(w)
+			return
+		}
+		api.writeJSON(w, r)
+		return
+	}
+	if len(ary) == 6 {
+		if ary[3] != "commits" {
+			api.notFound(w)
+			return
+		}
+		api.Calls[ary[5]]++
+		sha := ary[4]
+		switch ary[5] {
+		case "statuses":
+			statuses := api.Statuses[repo][sha]
+			if statuses == nil {
+				statuses = []*github.RepoStatus{}
+			}
+			api.writeJSON(w, statuses)
+		case "check-runs":
+			runs := api.CheckRuns[repo][sha]
+			if runs == nil {
+				runs = []*lib.CheckRun{}
+			}
+			n := len(runs)
+			api.writeJSON(w, lib.CheckRuns{TotalCount: &n, CheckRuns: runs})
+		default:
+			api.notFound(w)
+		}
+		return
+	}
+	if ary[3] == "issues" && ary[4] == "events" {
+		api.Calls["events"]++
+		api.writeEvents(w, r, repo)
+		return
+	}
+	number, err := strconv.Atoi(ary[4])
+	if err != nil {
+		api.notFound(w)
+		return
+	}
+	switch ary[3] {
+	case "issues":
+		api.Calls["issue"]++
+		issue, ok := api.Issues[repo][number]
+		if !ok {
+			api.notFound(w)
+			return
+		}
+		api.writeJSON(w, issue)
+	case "pulls":
+		api.Calls["pull"]++
+		pr, ok := api.Pulls[repo][number]
+		if !ok {
+			api.notFound(w)
+			return
+		}
+		api.writeJSON(w, pr)
+	default:
+		api.notFound(w)
+	}
+}
+
+// writeEvents - writes a single page of repository issue events, uses "page" and "per_page" params
+// and sets "Link" header when there are more pages, just like GitHub does
+func (api *GitHubAPI) writeEvents(w http.ResponseWriter, r *http.Request, repo string) {
+	events := api.Events[repo]
+	page, perPage := 1, 30
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	if pp, err := strconv.Atoi(r.URL.Query().Get("per_page")); err == nil && pp > 0 {
+		perPage = pp
+	}
+	from := (page - 1) * perPage
+	to := from + perPage
+	if from > len(events) {
+		from = len(events)
+	}
+	if to > len(events) {
+		to = len(events)
+	}
+	if to < len(events) {
+		next := *r.URL
+		q := next.Query()
+		q.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = q.Encode()
+		w.Header().Set("Link", fmt.Sprintf("<%s%s>; rel=\"next\"", api.Server.URL, next.String()))
+	}
+	api.writeJSON(w, events[from:to])
+}
+
+// writeJSON - writes object as JSON response
+func (api *GitHubAPI) writeJSON(w http.ResponseWriter, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(data)
+}
+
+// notFound - writes GitHub style 404 response
+func (api *GitHubAPI) notFound(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	_, _ = w.Write([]byte("{\"message\":\"Not Found\",\"documentation_url\":\"https://developer.github.com/v3\"}"))
+}
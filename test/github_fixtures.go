@@ -0,0 +1,213 @@
+package test
+
+import (
+	"time"
+
+	lib "devstats"
+
+	"github.com/google/go-github/github"
+)
+
+// FixtureRepo - repository used by fake GitHub API fixture data
+const FixtureRepo = "fixture-org/fixture-repo"
+
+// LoadGitHubFixtures - populates fake GitHub API with fixture data:
+// repository with 2 topics, Apache-2.0 license and "master" default branch
+// issue #1 (open, 2 labels, milestone, 2 assignees) with "labeled" and "milestoned" events
+// issue #2 which is a PR (open, 1 label, requested reviewer) with "review_requested" event
+// PR head commit has 2 check runs (completed success, in progress) and 2 statuses (pending then failure of the same context)
+// All events happen at `dt` and `dt + 1 minute`
+func LoadGitHubFixtures(api *GitHubAPI, dt time.Time) {
+	var (
+		open     = "open"
+		falseVal = false
+	)
+	author := fixtureUser(1, "fixture-author")
+	reviewer := fixtureUser(2, "fixture-reviewer")
+	assignee1 := fixtureUser(3, "fixture-assignee1")
+	assignee2 := fixtureUser(4, "fixture-assignee2")
+	milestone := &github.Milestone{
+		ID:        github.Int64(101),
+		Number:    github.Int(1),
+		Title:     github.String("v1.0"),
+		State:     &open,
+		Creator:   author,
+		CreatedAt: &dt,
+		UpdatedAt: &dt,
+	}
+	kindBug := &github.Label{ID: github.Int64(201), Name: github.String("kind/bug")}
+	kindFeature := &github.Label{ID: github.Int64(202), Name: github.String("kind/feature")}
+	sizeXS := &github.Label{ID: github.Int64(203), Name: github.String("size/XS")}
+
+	// Issue #1
+	issue := &github.Issue{
+		ID:        github.Int64(1001),
+		Number:    github.Int(1),
+		State:     &open,
+		Locked:    &falseVal,
+		Title:     github.String("Fixture issue"),
+		Body:      github.String("Fixture issue body"),
+		User:      author,
+		Labels:    []github.Label{*kindBug, *kindFeature},
+		Assignee:  assignee1,
+		Assignees: []*github.User{assignee1, assignee2},
+		Comments:  github.Int(0),
+		Milestone: milestone,
+		CreatedAt: &dt,
+		UpdatedAt: &dt,
+	}
+	api.AddIssue(FixtureRepo, issue)
+
+	// Issue #2 - a PR
+	prURL := api.URL() + "repos/" + FixtureRepo + "/pulls/2"
+	prIssue := &github.Issue{
+		ID:               github.Int64(1002),
+		Number:           github.Int(2),
+		State:            &open,
+		Locked:           &falseVal,
+		Title:            github.String("Fixture PR"),
+		Body:             github.String("Fixture PR body"),
+		User:             author,
+		Labels:           []github.Label{*sizeXS},
+		Comments:         github.Int(0),
+		CreatedAt:        &dt,
+		UpdatedAt:        &dt,
+		PullRequestLinks: &github.PullRequestLinks{URL: &prURL},
+	}
+	api.AddIssue(FixtureRepo, prIssue)
+	repo := &github.Repository{
+		ID:       github.Int64(501),
+		Name:     github.String("fixture-repo"),
+		FullName: github.String(FixtureRepo),
+	}
+	api.AddRepo(
+		FixtureRepo,
+		&github.Repository{
+			ID:            repo.ID,
+			Name:          repo.Name,
+			FullName:      repo.FullName,
+			DefaultBranch: github.String("master"),
+			Topics:        []string{"fixture", "devstats"},
+			License:       &github.License{Key: github.String("apache-2.0"), Name: github.String("Apache License 2.0")},
+			Archived:      &falseVal,
+		},
+	)
+	api.AddPull(
+		FixtureRepo,
+		&github.PullRequest{
+			ID:                 github.Int64(2001),
+			Number:             github.Int(2),
+			State:              &open,
+			Title:              github.String("Fixture PR"),
+			Body:               github.String("Fixture PR body"),
+			User:               author,
+			Merged:             &falseVal,
+			CreatedAt:          &dt,
+			UpdatedAt:          &dt,
+			RequestedReviewers: []*github.User{reviewer},
+			Base: &github.PullRequestBranch{
+				Label: github.String("fixture-org:master"),
+				Ref:   github.String("master"),
+				SHA:   github.String("0000000000000000000000000000000000000001"),
+				Repo:  repo,
+				User:  author,
+			},
+			Head: &github.PullRequestBranch{
+				Label: github.String("fixture-author:feature"),
+				Ref:   github.String("feature"),
+				SHA:   github.String("0000000000000000000000000000000000000002"),
+				Repo:  repo,
+				User:  author,
+			},
+		},
+	)
+
+	// PR head CI checks
+	dt2 := dt.Add(time.Minute)
+	headSHA := "0000000000000000000000000000000000000002"
+	api.AddCheckRun(
+		FixtureRepo,
+		headSHA,
+		&lib.CheckRun{
+			ID:          github.Int64(4001),
+			HeadSHA:     &headSHA,
+			Name:        github.String("unit-tests"),
+			Status:      github.String("completed"),
+			Conclusion:  github.String("success"),
+			StartedAt:   &dt,
+			CompletedAt: &dt2,
+		},
+	)
+	api.AddCheckRun(
+		FixtureRepo,
+		headSHA,
+		&lib.CheckRun{
+			ID:        github.Int64(4002),
+			HeadSHA:   &headSHA,
+			Name:      github.String("e2e-tests"),
+			Status:    github.String("in_progress"),
+			StartedAt: &dt,
+		},
+	)
+	api.AddStatus(
+		FixtureRepo,
+		headSHA,
+		&github.RepoStatus{
+			ID:        github.Int64(5001),
+			State:     github.String("pending"),
+			Context:   github.String("ci/lint"),
+			CreatedAt: &dt,
+			UpdatedAt: &dt,
+		},
+	)
+	api.AddStatus(
+		FixtureRepo,
+		headSHA,
+		&github.RepoStatus{
+			ID:        github.Int64(5002),
+			State:     github.String("failure"),
+			Context:   github.String("ci/lint"),
+			CreatedAt: &dt2,
+			UpdatedAt: &dt2,
+		},
+	)
+
+	// Events
+	api.AddEvent(
+		FixtureRepo,
+		&github.IssueEvent{
+			ID:        github.Int64(3001),
+			Event:     github.String("labeled"),
+			Actor:     author,
+			Label:     kindFeature,
+			Issue:     issue,
+			CreatedAt: &dt,
+		},
+	)
+	api.AddEvent(
+		FixtureRepo,
+		&github.IssueEvent{
+			ID:        github.Int64(3002),
+			Event:     github.String("milestoned"),
+			Actor:     author,
+			Milestone: milestone,
+			Issue:     issue,
+			CreatedAt: &dt2,
+		},
+	)
+	api.AddEvent(
+		FixtureRepo,
+		&github.IssueEvent{
+			ID:        github.Int64(3003),
+			Event:     github.String("review_requested"),
+			Actor:     author,
+			Issue:     prIssue,
+			CreatedAt: &dt2,
+		},
+	)
+}
+
+// fixtureUser - returns fixture GitHub user
+func fixtureUser(id int64, login string) *github.User {
+	return &github.User{ID: &id, Login: &login}
+}
@@ -1,2 +1,2 @@
 #!/bin/bash
-GHA2DB_LOCAL=1 GHA2DB_SKIPPDB=1 PG_DB=gha ./sync_issues
+GHA2DB_LOCAL=1 GHA2DB_SKIPPDB=1 GHA2DB_PROJECT=kubernetes PG_DB=gha ./sync_issues "$@"
@@ -0,0 +1,34 @@
+alter table gha_repos_metadata add last_checked timestamp without time zone;
+alter table gha_repos_metadata add not_found boolean default false not null;
+with changes as (
+  select id,
+    dt,
+    case when lower(name) is not distinct from lower(lag(name) over w)
+      and default_branch is not distinct from lag(default_branch) over w
+      and license_key is not distinct from lag(license_key) over w
+      and license_name is not distinct from lag(license_name) over w
+      and topics is not distinct from lag(topics) over w
+      and archived is not distinct from lag(archived) over w
+    then 0 else 1 end as changed
+  from
+    gha_repos_metadata
+  window w as (partition by id order by dt)
+), runs as (
+  select id,
+    dt,
+    sum(changed) over (partition by id order by dt) as run
+  from
+    changes
+), firsts as (
+  select id,
+    min(dt) as dt,
+    max(dt) as last_checked
+  from
+    runs
+  group by
+    id,
+    run
+)
+update gha_repos_metadata m set last_checked = f.last_checked from firsts f where m.id = f.id and m.dt = f.dt;
+delete from gha_repos_metadata where last_checked is null;
+alter table gha_repos_metadata alter column last_checked set not null;
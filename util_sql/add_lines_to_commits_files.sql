@@ -0,0 +1,4 @@
+alter table gha_commits_files add added int;
+alter table gha_commits_files add removed int;
+alter table gha_events_commits_files add added int;
+alter table gha_events_commits_files add removed int;
@@ -0,0 +1,19 @@
+select
+  h.dt,
+  h.action,
+  h.login,
+  h.company_name,
+  h.dt_from,
+  h.dt_to,
+  h.source_hash
+from
+  gha_affiliations_history h
+where
+  h.company_name = '{{company}}'
+  and h.dt >= '{{from}}'
+  and h.dt < '{{to}}'
+order by
+  h.dt desc,
+  h.login asc,
+  h.action asc
+;
@@ -0,0 +1,17 @@
+CREATE TABLE gha_affiliations_history (
+    dt timestamp without time zone NOT NULL,
+    action character varying(12) NOT NULL,
+    login character varying(120) NOT NULL,
+    company_name character varying(160) NOT NULL,
+    dt_from timestamp without time zone NOT NULL,
+    dt_to timestamp without time zone NOT NULL,
+    source_hash character varying(40) NOT NULL
+);
+ALTER TABLE gha_affiliations_history OWNER TO gha_admin;
+ALTER TABLE ONLY gha_affiliations_history ADD CONSTRAINT gha_affiliations_history_pkey PRIMARY KEY (dt, action, login, company_name, dt_from, dt_to);
+CREATE INDEX affiliations_history_company_name_idx ON gha_affiliations_history USING btree (company_name);
+CREATE INDEX affiliations_history_dt_idx ON gha_affiliations_history USING btree (dt);
+CREATE INDEX affiliations_history_login_idx ON gha_affiliations_history USING btree (login);
+CREATE INDEX affiliations_history_source_hash_idx ON gha_affiliations_history USING btree (source_hash);
+GRANT SELECT ON TABLE gha_affiliations_history TO ro_user;
+GRANT SELECT ON TABLE gha_affiliations_history TO devstats_team;
@@ -0,0 +1,4 @@
+alter table gha_actors_affiliations add source character varying(20) DEFAULT 'file'::character varying NOT NULL;
+alter table gha_actors_affiliations add confidence double precision DEFAULT 1.0 NOT NULL;
+create index actors_affiliations_source_idx on gha_actors_affiliations(source);
+alter table gha_commits add author_email character varying(160) DEFAULT ''::character varying NOT NULL;
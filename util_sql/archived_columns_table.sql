@@ -0,0 +1,15 @@
+CREATE TABLE gha_archived_columns (
+    table_name character varying(63) NOT NULL,
+    column_name character varying(63) NOT NULL,
+    "time" timestamp without time zone NOT NULL,
+    period text DEFAULT ''::text NOT NULL,
+    series text DEFAULT ''::text NOT NULL,
+    value double precision NOT NULL,
+    archived_at timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_archived_columns OWNER TO gha_admin;
+ALTER TABLE ONLY gha_archived_columns ADD CONSTRAINT gha_archived_columns_pkey PRIMARY KEY (table_name, column_name, "time", period, series);
+CREATE INDEX archived_columns_table_name_idx ON gha_archived_columns USING btree (table_name);
+CREATE INDEX archived_columns_column_name_idx ON gha_archived_columns USING btree (column_name);
+GRANT SELECT ON TABLE gha_archived_columns TO ro_user;
+GRANT SELECT ON TABLE gha_archived_columns TO devstats_team;
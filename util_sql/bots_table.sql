@@ -0,0 +1,10 @@
+CREATE TABLE gha_bots (
+    login character varying(120) NOT NULL,
+    reason character varying(20) NOT NULL,
+    detail character varying(160) NOT NULL
+);
+ALTER TABLE gha_bots OWNER TO gha_admin;
+ALTER TABLE ONLY gha_bots ADD CONSTRAINT gha_bots_pkey PRIMARY KEY (login);
+CREATE INDEX bots_reason_idx ON gha_bots USING btree (reason);
+GRANT SELECT ON TABLE gha_bots TO ro_user;
+GRANT SELECT ON TABLE gha_bots TO devstats_team;
@@ -0,0 +1,22 @@
+CREATE TABLE gha_checks (
+    id bigint NOT NULL,
+    kind character varying(20) NOT NULL,
+    sha character varying(40) NOT NULL,
+    name character varying(200) NOT NULL,
+    status character varying(20),
+    conclusion character varying(20),
+    started_at timestamp without time zone,
+    completed_at timestamp without time zone,
+    dup_repo_id bigint NOT NULL,
+    dup_repo_name character varying(160) NOT NULL
+);
+ALTER TABLE gha_checks OWNER TO gha_admin;
+ALTER TABLE ONLY gha_checks ADD CONSTRAINT gha_checks_pkey PRIMARY KEY (id, kind);
+CREATE INDEX checks_conclusion_idx ON gha_checks USING btree (conclusion);
+CREATE INDEX checks_dup_repo_id_idx ON gha_checks USING btree (dup_repo_id);
+CREATE INDEX checks_dup_repo_name_idx ON gha_checks USING btree (dup_repo_name);
+CREATE INDEX checks_name_idx ON gha_checks USING btree (name);
+CREATE INDEX checks_sha_idx ON gha_checks USING btree (sha);
+CREATE INDEX checks_started_at_idx ON gha_checks USING btree (started_at);
+GRANT SELECT ON TABLE gha_checks TO ro_user;
+GRANT SELECT ON TABLE gha_checks TO devstats_team;
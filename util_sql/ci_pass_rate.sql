@@ -0,0 +1,32 @@
+create temp table heads as
+select
+  c.sha,
+  (select max(r.repo_group) from gha_repos r where r.id = c.dup_repo_id) as repo_group,
+  bool_and(c.conclusion in ('success', 'neutral', 'skipped')) as green,
+  max(c.completed_at) - min(c.started_at) as wait_for_ci
+from
+  gha_checks c
+where
+  c.started_at >= '{{from}}'
+  and c.started_at < '{{to}}'
+  and c.completed_at is not null
+  and (c.kind = 'check_run' or c.id = (
+    select s.id from gha_checks s where s.kind = 'status' and s.sha = c.sha and s.name = c.name order by s.started_at desc, s.id desc limit 1
+  ))
+group by
+  c.sha,
+  c.dup_repo_id;
+
+select
+  coalesce(h.repo_group, 'Other') as repo_group,
+  count(*) as heads,
+  round(100.0 * count(*) filter (where h.green) / count(*), 2) as ci_pass_rate,
+  percentile_disc(0.5) within group (order by h.wait_for_ci) filter (where h.green) as median_wait_for_green
+from
+  heads h
+group by
+  coalesce(h.repo_group, 'Other')
+order by
+  heads desc;
+
+drop table heads;
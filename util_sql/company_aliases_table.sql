@@ -0,0 +1,9 @@
+CREATE TABLE gha_company_aliases (
+    alias character varying(160) NOT NULL,
+    company_name character varying(160) NOT NULL
+);
+ALTER TABLE gha_company_aliases OWNER TO gha_admin;
+ALTER TABLE ONLY gha_company_aliases ADD CONSTRAINT gha_company_aliases_pkey PRIMARY KEY (alias);
+CREATE INDEX company_aliases_company_name_idx ON gha_company_aliases USING btree (company_name);
+GRANT SELECT ON TABLE gha_company_aliases TO ro_user;
+GRANT SELECT ON TABLE gha_company_aliases TO devstats_team;
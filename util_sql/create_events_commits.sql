@@ -4,6 +4,8 @@ insert into gha_events_commits_files(
   path,
   dt,
   size,
+  added,
+  removed,
   dup_repo_id,
   dup_repo_name,
   dup_type,
@@ -15,6 +17,8 @@ select
   sub.path,
   sub.dt,
   sub.size,
+  sub.added,
+  sub.removed,
   sub.dup_repo_id,
   sub.dup_repo_name,
   sub.dup_type,
@@ -25,6 +29,8 @@ from (
     c.dup_repo_name || '/' || cf.path as path,
     cf.dt,
     cf.size,
+    cf.added,
+    cf.removed,
     c.dup_repo_id,
     c.dup_repo_name,
     c.dup_type,
@@ -40,6 +46,8 @@ from (
     c.dup_repo_name || '/' || cf.path as path,
     cf.dt,
     cf.size,
+    cf.added,
+    cf.removed,
     c.dup_repo_id,
     c.dup_repo_name,
     c.dup_type,
@@ -54,6 +62,8 @@ from (
     p.dup_repo_name || '/' || cf.path as path,
     cf.dt,
     cf.size,
+    cf.added,
+    cf.removed,
     p.dup_repo_id,
     p.dup_repo_name,
     p.dup_type,
@@ -68,6 +78,8 @@ from (
     pl.dup_repo_name || '/' || cf.path as path,
     cf.dt,
     cf.size,
+    cf.added,
+    cf.removed,
     pl.dup_repo_id,
     pl.dup_repo_name,
     pl.dup_type,
@@ -86,6 +98,8 @@ from (
     pr.dup_repo_name || '/' || cf.path as path,
     cf.dt,
     cf.size,
+    cf.added,
+    cf.removed,
     pr.dup_repo_id,
     pr.dup_repo_name,
     pr.dup_type,
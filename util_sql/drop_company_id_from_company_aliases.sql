@@ -0,0 +1 @@
+alter table gha_company_aliases drop column if exists company_id;
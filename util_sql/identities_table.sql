@@ -0,0 +1,11 @@
+CREATE TABLE gha_identities (
+    kind character varying(5) NOT NULL,
+    value character varying(160) NOT NULL,
+    person_id character varying(160) NOT NULL
+);
+ALTER TABLE gha_identities OWNER TO gha_admin;
+ALTER TABLE ONLY gha_identities ADD CONSTRAINT gha_identities_pkey PRIMARY KEY (kind, value);
+CREATE INDEX identities_person_id_idx ON gha_identities USING btree (person_id);
+CREATE INDEX identities_value_idx ON gha_identities USING btree (value);
+GRANT SELECT ON TABLE gha_identities TO ro_user;
+GRANT SELECT ON TABLE gha_identities TO devstats_team;
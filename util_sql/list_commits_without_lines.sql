@@ -0,0 +1,21 @@
+select
+  distinct sub.sha, sub.repo
+from (
+  select distinct commit_id as sha, dup_repo_name as repo from gha_comments
+  union select distinct original_commit_id as sha, dup_repo_name as repo from gha_comments where original_commit_id is not null
+  union select distinct sha, dup_repo_name as repo from gha_commits
+  union select distinct sha, dup_repo_name as repo from gha_pages
+  union select distinct head as sha, dup_repo_name as repo from gha_payloads
+  union select distinct befor as sha, dup_repo_name as repo from gha_payloads
+  union select distinct commit as sha, dup_repo_name as repo from gha_payloads where commit is not null
+  union select distinct base_sha as sha, dup_repo_name as repo from gha_pull_requests
+  union select distinct head_sha as sha, dup_repo_name as repo from gha_pull_requests
+  union select distinct merge_commit_sha as sha, dup_repo_name as repo from gha_pull_requests where merge_commit_sha is not null
+  ) sub
+where
+  sub.sha in (
+    -- commits with files stored before added/removed lines were recorded, commits with only binary files are also listed
+    select sha from gha_commits_files group by sha having count(added) = 0
+  )
+  and sub.repo like '%/%'
+;
@@ -0,0 +1,16 @@
+CREATE TABLE gha_release_versions (
+    name character varying(200) NOT NULL,
+    major integer NOT NULL,
+    minor integer NOT NULL,
+    patch integer NOT NULL,
+    kind character varying(5) NOT NULL,
+    release_date timestamp without time zone NOT NULL,
+    minor_days double precision,
+    patches integer DEFAULT 0 NOT NULL
+);
+ALTER TABLE gha_release_versions OWNER TO gha_admin;
+ALTER TABLE ONLY gha_release_versions ADD CONSTRAINT gha_release_versions_pkey PRIMARY KEY (name);
+CREATE INDEX release_versions_kind_idx ON gha_release_versions USING btree (kind);
+CREATE INDEX release_versions_release_date_idx ON gha_release_versions USING btree (release_date);
+GRANT SELECT ON TABLE gha_release_versions TO ro_user;
+GRANT SELECT ON TABLE gha_release_versions TO devstats_team;
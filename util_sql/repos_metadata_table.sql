@@ -0,0 +1,20 @@
+CREATE TABLE gha_repos_metadata (
+    id bigint NOT NULL,
+    dt timestamp without time zone NOT NULL,
+    last_checked timestamp without time zone NOT NULL,
+    name character varying(160) NOT NULL,
+    default_branch character varying(200),
+    license_key character varying(100),
+    license_name character varying(200),
+    topics text NOT NULL,
+    archived boolean NOT NULL,
+    not_found boolean DEFAULT false NOT NULL
+);
+ALTER TABLE gha_repos_metadata OWNER TO gha_admin;
+ALTER TABLE ONLY gha_repos_metadata ADD CONSTRAINT gha_repos_metadata_pkey PRIMARY KEY (id, dt);
+CREATE INDEX repos_metadata_archived_idx ON gha_repos_metadata USING btree (archived);
+CREATE INDEX repos_metadata_dt_idx ON gha_repos_metadata USING btree (dt);
+CREATE INDEX repos_metadata_id_idx ON gha_repos_metadata USING btree (id);
+CREATE INDEX repos_metadata_name_idx ON gha_repos_metadata USING btree (name);
+GRANT SELECT ON TABLE gha_repos_metadata TO ro_user;
+GRANT SELECT ON TABLE gha_repos_metadata TO devstats_team;
@@ -7,7 +7,9 @@ CREATE TABLE gha_commits_files (
     sha character varying(40) NOT NULL,
     path text NOT NULL,
     size bigint NOT NULL,
-    dt timestamp without time zone NOT NULL
+    dt timestamp without time zone NOT NULL,
+    added integer,
+    removed integer
 );
 ALTER TABLE gha_commits_files OWNER TO gha_admin;
 ALTER TABLE ONLY gha_commits_files ADD CONSTRAINT gha_commits_files_pkey PRIMARY KEY (sha, path);
@@ -22,6 +24,8 @@ CREATE TABLE gha_events_commits_files (
     path text NOT NULL,
     size bigint NOT NULL,
     dt timestamp without time zone NOT NULL,
+    added integer,
+    removed integer,
     repo_group character varying(80),
     dup_repo_id bigint NOT NULL,
     dup_repo_name character varying(160) NOT NULL,
@@ -0,0 +1,13 @@
+update
+  gha_events_commits_files ecf
+set
+  added = cf.added,
+  removed = cf.removed
+from
+  gha_commits_files cf
+where
+  ecf.sha = cf.sha
+  and ecf.path = ecf.dup_repo_name || '/' || cf.path
+  and ecf.added is null
+  and cf.added is not null
+;
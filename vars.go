@@ -0,0 +1,171 @@
+package devstats
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Vars - list of Postgres variables to set, read from vars.yaml
+type Vars struct {
+	Vars []Var `yaml:"vars"`
+}
+
+// Var - single Postgres variable
+// Type is one of s, i, f, dt and selects gha_vars value_{{type}} column
+// Value can be overwritten by a non-empty File contents (path relative to data directory) or Command output
+// DependsOn - explicit list of variables that must be set before this one, variables used in Replaces are added automatically
+type Var struct {
+	Name      string     `yaml:"name"`
+	Type      string     `yaml:"type"`
+	Value     string     `yaml:"value"`
+	File      string     `yaml:"file"`
+	Command   []string   `yaml:"command"`
+	Replaces  [][]string `yaml:"replaces"`
+	DependsOn []string   `yaml:"depends_on"`
+	Disabled  bool       `yaml:"disabled"`
+}
+
+// Dependencies - returns names this variable depends on: explicit DependsOn and variables used as replacement values
+// Direct (":value") and environment ("$NAME") replacement values are skipped
+func (v *Var) Dependencies() (deps []string) {
+	deps = append(deps, v.DependsOn...)
+	for _, repl := range v.Replaces {
+		if len(repl) != 2 || repl[1] == "" || repl[1][0:1] == ":" || repl[1][0:1] == "$" {
+			continue
+		}
+		deps = append(deps, repl[1])
+	}
+	return
+}
+
+// provides - returns names defined by this variable: its name and "from" parts of its template replacements
+func (v *Var) provides() (names []string) {
+	names = append(names, v.Name)
+	for _, repl := range v.Replaces {
+		if len(repl) != 2 || (len(repl[0]) > 1 && repl[0][0:1] == ":") || repl[0] == repl[1] {
+			continue
+		}
+		names = append(names, repl[0])
+	}
+	return
+}
+
+// OrderVars - returns enabled variables ordered so that every variable comes after all variables it depends on
+// Independent variables keep their file order, and a redefined variable comes after its previous definitions
+// A dependency resolves to the last definition before the dependent variable, or the first one after it when there is none
+// Returns error for unknown dependencies and dependency cycles
+func OrderVars(vars []Var) ([]Var, error) {
+	enabled := []Var{}
+	for _, v := range vars {
+		if !v.Disabled {
+			enabled = append(enabled, v)
+		}
+	}
+	n := len(enabled)
+	providers := make(map[string][]int)
+	for i := range enabled {
+		for _, name := range enabled[i].provides() {
+			p := providers[name]
+			if len(p) == 0 || p[len(p)-1] != i {
+				providers[name] = append(p, i)
+			}
+		}
+	}
+	deps := make([]map[int]struct{}, n)
+	for i := range enabled {
+		deps[i] = make(map[int]struct{})
+		for _, p := range providers[enabled[i].Name] {
+			if p < i {
+				deps[i][p] = struct{}{}
+			}
+		}
+		for _, dep := range enabled[i].Dependencies() {
+			p, ok := providers[dep]
+			if !ok {
+				return nil, fmt.Errorf("variable '%s' depends on '%s', but no such variable is defined", enabled[i].Name, dep)
+			}
+			provider := -1
+			for _, j := range p {
+				if j > i {
+					if provider < 0 {
+						provider = j
+					}
+					break
+				}
+				provider = j
+				if j == i {
+					// Defined by a replacement of the same variable
+					break
+				}
+			}
+			if provider != i {
+				deps[i][provider] = struct{}{}
+			}
+		}
+	}
+	ordered := []Var{}
+	done := make([]bool, n)
+	for len(ordered) < n {
+		next := -1
+		for i := 0; i < n && next < 0; i++ {
+			if done[i] {
+				continue
+			}
+			ready := true
+			for j := range deps[i] {
+				if !done[j] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+			}
+		}
+		if next < 0 {
+			cycle := []string{}
+			for i := range enabled {
+				if !done[i] {
+					cycle = append(cycle, enabled[i].Name)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle between variables: %s", strings.Join(cycle, ", "))
+		}
+		done[next] = true
+		ordered = append(ordered, enabled[next])
+	}
+	return ordered, nil
+}
+
+// ValidateVarValue - checks if value can be stored in gha_vars value_{{typ}} column
+func ValidateVarValue(typ, value string) error {
+	var err error
+	switch typ {
+	case "s":
+	case "i":
+		_, err = strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	case "f":
+		_, err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+	case "dt":
+		_, err = TimeParseAnyWithErr(strings.TrimSpace(value))
+	default:
+		return fmt.Errorf("unknown variable type '%s', allowed types are: s, i, f, dt", typ)
+	}
+	if err != nil {
+		return fmt.Errorf("value '%s' is not a valid '%s' type value: %v", value, typ, err)
+	}
+	return nil
+}
+
+// ReadVarFile - reads variable file, path is relative to the data directory and cannot point outside of it
+// Leading "{{datadir}}" is allowed for compatibility with command definitions
+func ReadVarFile(ctx *Ctx, dataPrefix, path string) ([]byte, error) {
+	path = strings.TrimPrefix(path, "{{datadir}}")
+	clean := filepath.Clean(path)
+	if path == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return nil, fmt.Errorf("variable file '%s' must be a relative path inside the data directory", path)
+	}
+	return ReadFile(ctx, dataPrefix+clean)
+}
@@ -0,0 +1,162 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	lib "devstats"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func varNames(vars []lib.Var) string {
+	names := []string{}
+	for _, v := range vars {
+		names = append(names, v.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestOrderVars(t *testing.T) {
+	var testCases = []struct {
+		vars     []lib.Var
+		expected string
+		err      bool
+	}{
+		{
+			vars:     []lib.Var{{Name: "a"}, {Name: "b"}, {Name: "c", Disabled: true}},
+			expected: "a,b",
+		},
+		{
+			vars: []lib.Var{
+				{Name: "docs", Replaces: [][]string{{"hostname", "os_hostname"}, {"full_name", "full_name"}}},
+				{Name: "full_name"},
+				{Name: "os_hostname"},
+			},
+			expected: "full_name,os_hostname,docs",
+		},
+		{
+			vars: []lib.Var{
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "a"},
+				{Name: "c"},
+			},
+			expected: "a,b,c",
+		},
+		{
+			vars: []lib.Var{
+				{Name: "docs", Replaces: [][]string{{"proj_name", "$GHA2DB_PROJECT"}, {"url_prefix", "proj_name"}, {":x", ":y"}}},
+			},
+			expected: "docs",
+		},
+		{
+			vars: []lib.Var{
+				{Name: "host", Replaces: [][]string{{"hostname", "os_hostname"}}},
+				{Name: "other", Replaces: [][]string{{"h", "hostname"}}},
+				{Name: "os_hostname"},
+			},
+			expected: "os_hostname,host,other",
+		},
+		{
+			vars: []lib.Var{
+				{Name: "x", Value: "1"},
+				{Name: "y", Replaces: [][]string{{"x", "x"}}},
+				{Name: "x", Value: "2"},
+			},
+			expected: "x,y,x",
+		},
+		{
+			vars: []lib.Var{{Name: "a", DependsOn: []string{"missing"}}},
+			err:  true,
+		},
+		{
+			vars: []lib.Var{{Name: "a", DependsOn: []string{"c"}}, {Name: "b", DependsOn: []string{"a"}}, {Name: "c", DependsOn: []string{"b"}}},
+			err:  true,
+		},
+	}
+	for index, test := range testCases {
+		got, err := lib.OrderVars(test.vars)
+		if (err != nil) != test.err {
+			t.Errorf("test number %d: expected error: %v, got: %v", index+1, test.err, err)
+			continue
+		}
+		if !test.err && varNames(got) != test.expected {
+			t.Errorf("test number %d: expected order %s, got %s", index+1, test.expected, varNames(got))
+		}
+	}
+}
+
+func TestValidateVarValue(t *testing.T) {
+	var testCases = []struct {
+		typ   string
+		value string
+		ok    bool
+	}{
+		{typ: "s", value: "anything", ok: true},
+		{typ: "i", value: " 42\n", ok: true},
+		{typ: "i", value: "4.2"},
+		{typ: "f", value: "4.2", ok: true},
+		{typ: "f", value: "abc"},
+		{typ: "dt", value: "2018-01-02 10:00:00", ok: true},
+		{typ: "dt", value: "2018-01", ok: true},
+		{typ: "dt", value: "yesterday"},
+		{typ: "x", value: "1"},
+	}
+	for index, test := range testCases {
+		err := lib.ValidateVarValue(test.typ, test.value)
+		if (err == nil) != test.ok {
+			t.Errorf("test number %d, type '%s', value '%s': expected valid: %v, got error: %v", index+1, test.typ, test.value, test.ok, err)
+		}
+	}
+}
+
+func TestReadVarFile(t *testing.T) {
+	ctx := lib.Ctx{}
+	for _, path := range []string{"/etc/passwd", "../README.md", "docs/../../README.md", ""} {
+		_, err := lib.ReadVarFile(&ctx, "./", path)
+		if err == nil {
+			t.Errorf("expected error reading '%s'", path)
+		}
+	}
+	for _, path := range []string{"README.md", "{{datadir}}README.md", "docs/../README.md"} {
+		_, err := lib.ReadVarFile(&ctx, "./", path)
+		if err != nil {
+			t.Errorf("error reading '%s': %v", path, err)
+		}
+	}
+}
+
+func TestVarsYamls(t *testing.T) {
+	files, err := filepath.Glob("metrics/*/vars.yaml")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		var vars lib.Vars
+		err = yaml.Unmarshal(data, &vars)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		_, err = lib.OrderVars(vars.Vars)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+		}
+		ctx := lib.Ctx{Project: filepath.Base(filepath.Dir(file))}
+		for _, v := range vars.Vars {
+			if v.File == "" {
+				continue
+			}
+			_, err = lib.ReadVarFile(&ctx, "./", v.File)
+			if err != nil {
+				t.Errorf("%s: variable %s: %v", file, v.Name, err)
+			}
+		}
+	}
+}
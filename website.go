@@ -0,0 +1,75 @@
+package devstats
+
+import "time"
+
+// WebsiteDataVersion - version of website data JSON schema, increase it on every incompatible change
+// 1 - initial schema, 2 - added schemaVersion fields and combined data (all projects and their stats in a single JSON)
+const WebsiteDataVersion = 2
+
+// Website data outputs (GHA2DB_WEBSITE_OUTPUT), any other value is a HTTP(S) URL where combined data is POSTed
+const (
+	WebsiteOutputFiles    = "files"
+	WebsiteOutputCombined = "combined"
+)
+
+// WebsiteProjects - list of projects, saved as projects.json
+type WebsiteProjects struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Projects      []WebsiteProject `json:"projects"`
+	Summary       string           `json:"summary"`
+	Timestamp     time.Time        `json:"timestamp"`
+}
+
+// WebsiteProject - single project
+type WebsiteProject struct {
+	Name         string `json:"name"`
+	Title        string `json:"title"`
+	Status       string `json:"status"`
+	Repo         string `json:"repo"`
+	DashboardURL string `json:"dashboardUrl"`
+	DBDumpURL    string `json:"dbDumpUrl"`
+}
+
+// WebsiteProjectStats - project stats, saved as project_name.json
+// LatestVersion is the latest main repo tag ("-" when unknown), RecentDiscussion is Totals.Month.Discussion
+// Stars is the sum of stars of all project repos (from the last 3 months events), OpenIssues is the number of open issues
+type WebsiteProjectStats struct {
+	SchemaVersion    int                   `json:"schemaVersion"`
+	Totals           WebsiteActivityTotals `json:"activityTotals"`
+	LatestVersion    string                `json:"latestVersion"`
+	OpenIssues       int                   `json:"openIssues"`
+	RecentDiscussion int                   `json:"recentDiscussion"`
+	Stars            int                   `json:"stars"`
+	CommitGraph      WebsiteCommitGraph    `json:"commitGraph"`
+	Timestamp        time.Time             `json:"timestamp"`
+}
+
+// WebsiteCommitGraph - number of commits: [index, commits] pairs, the last index is the most recent bucket
+// Day - last 24 hours (hourly), Week - last 7 days (daily), Month - last 4 weeks (weekly)
+type WebsiteCommitGraph struct {
+	Day   [24][2]int `json:"day"`
+	Week  [7][2]int  `json:"week"`
+	Month [4][2]int  `json:"month"`
+}
+
+// WebsiteActivityTotals - activity in the last day, week and month
+type WebsiteActivityTotals struct {
+	Day   WebsiteActivityTotal `json:"day"`
+	Week  WebsiteActivityTotal `json:"week"`
+	Month WebsiteActivityTotal `json:"month"`
+}
+
+// WebsiteActivityTotal - number of commits, discussion events (issues, PRs, comments, reviews texts) and new stars
+type WebsiteActivityTotal struct {
+	Commits    int `json:"commits"`
+	Discussion int `json:"discussion"`
+	Stars      int `json:"stars"`
+}
+
+// WebsiteData - combined website data: projects list and all projects stats (by project name)
+type WebsiteData struct {
+	SchemaVersion int                            `json:"schemaVersion"`
+	Projects      WebsiteProjects                `json:"projects"`
+	Stats         map[string]WebsiteProjectStats `json:"stats"`
+	Timestamp     time.Time                      `json:"timestamp"`
+}